@@ -12,7 +12,6 @@ import (
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/model"
-	"google.golang.org/adk/model/gemini"
 	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
 	"google.golang.org/genai"
@@ -21,13 +20,22 @@ import (
 var (
 	ErrContentConversionFailed = errors.New("failed to convert prompt to gemini content")
 	ErrEmbedContentFailed      = errors.New("gemini api call failed to embed content")
+	ErrEmbedClientMissing      = errors.New("agent has no genai client configured for embeddings")
+	// ErrBlocked matches the BlockedError returned when the safety filters withheld a response
+	ErrBlocked = adapter.ErrBlocked
 )
 
+// BlockedError carries the block reason and, when reported, the category ratings
+type BlockedError = adapter.BlockedError
+
 type GenAIAgentInterface interface {
 	NewInMemorySession(ctx context.Context, userID string) GenAISessionInterface
 	NewVertexSession(ctx context.Context, userID string) (GenAISessionInterface, error)
 	NewRedisSession(ctx context.Context, userID string, sessionID string, rdb *redis.Client) (GenAISessionInterface, error)
 	Embed(ctx context.Context, text string, options ...*EmbedOptions) ([][]float32, error)
+	EmbedDetailed(ctx context.Context, text string, options ...*EmbedOptions) (*EmbedResult, error)
+	EmbedBatch(ctx context.Context, texts []string, options ...*EmbedBatchOptions) ([]*EmbedBatchResult, error)
+	Agent() agent.Agent
 }
 type GenAIAgent struct {
 	model                *model.LLM
@@ -40,6 +48,8 @@ type GenAIAgent struct {
 	beforeModelCallbacks []llmagent.BeforeModelCallback
 	afterModelCallbacks  []llmagent.AfterModelCallback
 	tracerEnabled        bool
+	// fileOptions sends large local files of SendPrompt through the Files API
+	fileOptions *adapter.FileOptions
 }
 
 func EnableTracer() (llmagent.BeforeModelCallback, llmagent.AfterModelCallback) {
@@ -97,15 +107,13 @@ func NewGeminiAgent(appName string,
 		beforeModelCallbacks = append(beforeModelCallbacks, b)
 		afterModelCallbacks = append(afterModelCallbacks, a)
 	}
+	beforeModelCallbacks = withGenerationOverrides(beforeModelCallbacks)
 	ctx := context.Background()
 	genaiClient, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey})
 	if err != nil {
 		return nil, err
 	}
-	model, err := gemini.NewModel(ctx, modelName, &genai.ClientConfig{APIKey: apiKey})
-	if err != nil {
-		return nil, fmt.Errorf("Failed to create model: %w", err)
-	}
+	model := newGeminiModel(modelName, genaiClient)
 	var finalCfg llmagent.Config
 	if len(overridConfig) > 0 {
 		finalCfg = overridConfig[0]
@@ -129,6 +137,7 @@ func NewGeminiAgent(appName string,
 		beforeModelCallbacks: beforeModelCallbacks,
 		genaiClient:          genaiClient,
 		afterModelCallbacks:  afterModelCallbacks,
+		fileOptions:          &adapter.FileOptions{Uploader: genaiClient.Files},
 	}, nil
 }
 func NewGenAIAgentFromConfig(appName string, cfg llmagent.Config, enableTracer bool) (GenAIAgentInterface, error) {
@@ -137,6 +146,7 @@ func NewGenAIAgentFromConfig(appName string, cfg llmagent.Config, enableTracer b
 		cfg.BeforeModelCallbacks = append(cfg.BeforeModelCallbacks, before)
 		cfg.AfterModelCallbacks = append(cfg.AfterModelCallbacks, after)
 	}
+	cfg.BeforeModelCallbacks = withGenerationOverrides(cfg.BeforeModelCallbacks)
 	agent, err := llmagent.New(cfg)
 	if err != nil {
 		return nil, err
@@ -146,6 +156,11 @@ func NewGenAIAgentFromConfig(appName string, cfg llmagent.Config, enableTracer b
 		agent:   agent,
 	}, nil
 }
+
+// Agent returns the underlying adk agent so it can be composed into workflows
+func (a *GenAIAgent) Agent() agent.Agent {
+	return a.agent
+}
 func (a *GenAIAgent) traceEvent(ev *session.Event) {
 	if !a.tracerEnabled || ev == nil {
 		return
@@ -178,8 +193,9 @@ func (a *GenAIAgent) NewInMemorySession(ctx context.Context, userID string) GenA
 		panic(err)
 	}
 	return &GenAISession{
-		session: session,
-		runner:  runner,
+		session:     session,
+		runner:      runner,
+		fileOptions: a.fileOptions,
 	}
 }
 
@@ -212,8 +228,9 @@ func (a *GenAIAgent) NewVertexSession(ctx context.Context, userID string) (GenAI
 	}
 
 	return &GenAISession{
-		session: session,
-		runner:  runnerInstance,
+		session:     session,
+		runner:      runnerInstance,
+		fileOptions: a.fileOptions,
 	}, nil
 }
 
@@ -237,54 +254,105 @@ func (a *GenAIAgent) NewRedisSession(ctx context.Context, userID string, session
 		return nil, fmt.Errorf("failed to create runner: %w", err)
 	}
 	return &GenAISession{
-		session: sessionResp.Session,
-		runner:  runnerInstance,
+		session:     sessionResp.Session,
+		runner:      runnerInstance,
+		fileOptions: a.fileOptions,
 	}, nil
 }
 
+// NewChatSession opens the redis session of a stored chat, the chat generation config
+// overrides the agent one for the whole session
+func NewChatSession(ctx context.Context, a GenAIAgentInterface, chat *genaiconfig.ChatConfig, rdb *redis.Client) (GenAISessionInterface, error) {
+	if chat == nil {
+		return nil, fmt.Errorf("chat config is nil")
+	}
+	sess, err := a.NewRedisSession(ctx, chat.UserID, chat.ID, rdb)
+	if err != nil {
+		return nil, err
+	}
+	if err := sess.SetGenerationConfig(chat.GenerationConfig); err != nil {
+		return nil, fmt.Errorf("chat %s: %w", chat.ID, err)
+	}
+	return sess, nil
+}
+
+// GenAIEmbedderInterface is the embedding subset of GenAIAgentInterface
+type GenAIEmbedderInterface interface {
+	Embed(ctx context.Context, text string, options ...*EmbedOptions) ([][]float32, error)
+	EmbedBatch(ctx context.Context, texts []string, options ...*EmbedBatchOptions) ([]*EmbedBatchResult, error)
+}
+
 type EmbedOptions struct {
-	Model      string
+	Model string
+	// TaskType defaults to DefaultEmbedTaskType whenever options are passed
 	TaskType   string
 	Dimensions int32
+	// Title of the document, only used by the RETRIEVAL_DOCUMENT task type
+	Title string
+	// AutoTruncate silently truncates inputs longer than the model limit (vertex only)
+	AutoTruncate bool
+	// Normalize scales the returned vectors to unit length
+	Normalize bool
+}
+
+// Embedding is a single vector with the statistics reported by the provider
+type Embedding struct {
+	Values     []float32
+	TokenCount float32
+	Truncated  bool
+}
+
+// EmbedResult is the detailed response of EmbedDetailed
+type EmbedResult struct {
+	Model                  string
+	Embeddings             []*Embedding
+	BillableCharacterCount int32
 }
 
 func (a *GenAIAgent) Embed(ctx context.Context, text string, options ...*EmbedOptions) ([][]float32, error) {
-	content, err := adapter.GeminiContentFromPrompt(&genaiconfig.Prompt{Text: text})
+	result, err := a.EmbedDetailed(ctx, text, options...)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrContentConversionFailed, err)
+		return nil, err
 	}
-	embeddingModel := "gemini-embedding-001"
-	var genaiConfig *genai.EmbedContentConfig
-
-	// Check if options were provided and are non-nil
-	if len(options) > 0 && options[0] != nil {
-		opts := options[0]
-
-		// Override model if specified
-		if opts.Model != "" {
-			embeddingModel = opts.Model
-		}
+	response := make([][]float32, len(result.Embeddings))
+	for index, embedding := range result.Embeddings {
+		response[index] = embedding.Values
+	}
+	return response, nil
+}
 
-		// Only set the genaiConfig if dimensions are provided and valid (e.g., > 0)
-		if opts.Dimensions > 0 {
-			dim := opts.Dimensions // Store value in a variable to get its address
-			taskType := "RETRIEVAL_DOCUMENT"
-			if opts.TaskType != "" {
-				taskType = opts.TaskType
-			}
-			genaiConfig = &genai.EmbedContentConfig{
-				OutputDimensionality: &dim,
-				TaskType:             taskType,
-			}
-		}
+// EmbedDetailed embeds the text and returns the vectors with their token statistics
+func (a *GenAIAgent) EmbedDetailed(ctx context.Context, text string, options ...*EmbedOptions) (*EmbedResult, error) {
+	if a.genaiClient == nil {
+		return nil, ErrEmbedClientMissing
 	}
+	content, err := adapter.GeminiContentFromPrompt(&genaiconfig.Prompt{Text: text})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrContentConversionFailed, err)
+	}
+	embeddingModel, genaiConfig := embedContentConfig(options...)
 	embed, err := a.genaiClient.Models.EmbedContent(ctx, embeddingModel, content, genaiConfig)
 	if err != nil {
 		return nil, fmt.Errorf("%w with model : %w", ErrEmbedContentFailed, err)
 	}
-	response := make([][]float32, len(embed.Embeddings))
+	normalize := len(options) > 0 && options[0] != nil && options[0].Normalize
+	result := &EmbedResult{
+		Model:      embeddingModel,
+		Embeddings: make([]*Embedding, len(embed.Embeddings)),
+	}
+	if embed.Metadata != nil {
+		result.BillableCharacterCount = embed.Metadata.BillableCharacterCount
+	}
 	for index, embedding := range embed.Embeddings {
-		response[index] = embedding.Values
+		values := embedding.Values
+		if normalize {
+			values = normalizeVector(values)
+		}
+		result.Embeddings[index] = &Embedding{Values: values}
+		if embedding.Statistics != nil {
+			result.Embeddings[index].TokenCount = embedding.Statistics.TokenCount
+			result.Embeddings[index].Truncated = embedding.Statistics.Truncated
+		}
 	}
-	return response, nil
+	return result, nil
 }
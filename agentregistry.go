@@ -0,0 +1,176 @@
+package genaiclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"github.com/darwishdev/genaiclient/pkg/redisclient"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/adk/agent/llmagent"
+)
+
+var (
+	ErrAgentNotFound     = errors.New("agent config not found")
+	ErrWatchNotSupported = errors.New("agent store can not watch for changes")
+)
+
+// AgentConfigStore is where the registry reads agent configs from, a missing agent is
+// reported as ErrAgentNotFound, redisclient.ErrNotFound or a nil config.
+// redisclient.RedisClientInterface satisfies it
+type AgentConfigStore interface {
+	GetAgent(ctx context.Context, agentID string) (*genaiconfig.AgentConfig, error)
+}
+
+// AgentConfigWatcher is implemented by stores that publish config changes,
+// redisclient does it through keyspace notifications
+type AgentConfigWatcher interface {
+	WatchAgents(ctx context.Context, onChange func(agentID string)) error
+}
+
+type AgentRegistryOptions struct {
+	BeforeModelCallbacks []llmagent.BeforeModelCallback
+	AfterModelCallbacks  []llmagent.AfterModelCallback
+	EnableTracer         bool
+	// CheckInterval makes Get re-read the stored config once the cached agent is older than
+	// it and rebuild when the config changed, 0 only reloads on Watch events or Invalidate
+	CheckInterval time.Duration
+	// Build defaults to NewGeminiAgentFromAgentConfig
+	Build func(cfg genaiconfig.AgentConfig) (GenAIAgentInterface, error)
+}
+
+type GenAIAgentRegistryInterface interface {
+	// Get returns the cached agent, building it from the stored config on first use
+	Get(ctx context.Context, agentID string) (GenAIAgentInterface, error)
+	// Reload rebuilds the agent from the stored config right away
+	Reload(ctx context.Context, agentID string) (GenAIAgentInterface, error)
+	// Invalidate drops the cached agent, the next Get builds it again
+	Invalidate(agentID string)
+	// Watch invalidates agents as the store reports changes until ctx is done
+	Watch(ctx context.Context) error
+}
+
+type AgentRegistry struct {
+	store   AgentConfigStore
+	options AgentRegistryOptions
+	mu      sync.Mutex
+	entries map[string]*agentRegistryEntry
+}
+
+type agentRegistryEntry struct {
+	mu          sync.Mutex
+	agent       GenAIAgentInterface
+	fingerprint [sha256.Size]byte
+	checkedAt   time.Time
+}
+
+// NewAgentRegistry builds agents on demand from the configs in store. Sessions opened on an
+// agent keep using it after a reload, only new sessions pick the new config up
+func NewAgentRegistry(appName string, apiKey string, store AgentConfigStore, options ...AgentRegistryOptions) GenAIAgentRegistryInterface {
+	var opts AgentRegistryOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Build == nil {
+		opts.Build = func(cfg genaiconfig.AgentConfig) (GenAIAgentInterface, error) {
+			return NewGeminiAgentFromAgentConfig(appName, apiKey, cfg,
+				opts.BeforeModelCallbacks, opts.AfterModelCallbacks, opts.EnableTracer)
+		}
+	}
+	return &AgentRegistry{
+		store:   store,
+		options: opts,
+		entries: map[string]*agentRegistryEntry{},
+	}
+}
+
+func (r *AgentRegistry) entry(agentID string) *agentRegistryEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[agentID]
+	if !ok {
+		e = &agentRegistryEntry{}
+		r.entries[agentID] = e
+	}
+	return e
+}
+
+func (r *AgentRegistry) Get(ctx context.Context, agentID string) (GenAIAgentInterface, error) {
+	e := r.entry(agentID)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.agent == nil {
+		return r.load(ctx, agentID, e)
+	}
+	if r.options.CheckInterval <= 0 || time.Since(e.checkedAt) < r.options.CheckInterval {
+		return e.agent, nil
+	}
+	agent, err := r.load(ctx, agentID, e)
+	if err != nil && !errors.Is(err, ErrAgentNotFound) && e.agent != nil {
+		// the store is unreachable or the new config is broken, keep serving the last good agent
+		log.Warn().Err(err).Str("agent", agentID).Msg("Failed to refresh agent, using cached one")
+		e.checkedAt = time.Now()
+		return e.agent, nil
+	}
+	return agent, err
+}
+
+func (r *AgentRegistry) Reload(ctx context.Context, agentID string) (GenAIAgentInterface, error) {
+	e := r.entry(agentID)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.agent = nil
+	return r.load(ctx, agentID, e)
+}
+
+func (r *AgentRegistry) Invalidate(agentID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, agentID)
+}
+
+func (r *AgentRegistry) Watch(ctx context.Context) error {
+	watcher, ok := r.store.(AgentConfigWatcher)
+	if !ok {
+		return ErrWatchNotSupported
+	}
+	return watcher.WatchAgents(ctx, r.Invalidate)
+}
+
+// load reads the stored config and rebuilds the agent when its fingerprint changed,
+// the caller holds e.mu
+func (r *AgentRegistry) load(ctx context.Context, agentID string, e *agentRegistryEntry) (GenAIAgentInterface, error) {
+	cfg, err := r.store.GetAgent(ctx, agentID)
+	if errors.Is(err, ErrAgentNotFound) || errors.Is(err, redisclient.ErrNotFound) || err == nil && cfg == nil {
+		r.Invalidate(agentID)
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load agent config %s: %w", agentID, err)
+	}
+	if cfg.ID == "" {
+		cfg.ID = agentID
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fingerprint agent config %s: %w", agentID, err)
+	}
+	fingerprint := sha256.Sum256(data)
+	if e.agent != nil && fingerprint == e.fingerprint {
+		e.checkedAt = time.Now()
+		return e.agent, nil
+	}
+	agent, err := r.options.Build(*cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build agent %s: %w", agentID, err)
+	}
+	e.agent = agent
+	e.fingerprint = fingerprint
+	e.checkedAt = time.Now()
+	return agent, nil
+}
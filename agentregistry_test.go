@@ -0,0 +1,337 @@
+package genaiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"github.com/darwishdev/genaiclient/pkg/redisclient"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeAgentStore serves agent configs from memory, a missing agent is a nil config
+type fakeAgentStore struct {
+	mu      sync.Mutex
+	configs map[string]genaiconfig.AgentConfig
+	err     error
+	reads   int
+}
+
+func newFakeAgentStore(configs ...genaiconfig.AgentConfig) *fakeAgentStore {
+	s := &fakeAgentStore{configs: map[string]genaiconfig.AgentConfig{}}
+	for _, cfg := range configs {
+		s.configs[cfg.ID] = cfg
+	}
+	return s
+}
+
+func (s *fakeAgentStore) GetAgent(ctx context.Context, agentID string) (*genaiconfig.AgentConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reads++
+	if s.err != nil {
+		return nil, s.err
+	}
+	cfg, ok := s.configs[agentID]
+	if !ok {
+		return nil, nil
+	}
+	return &cfg, nil
+}
+
+func (s *fakeAgentStore) set(cfg genaiconfig.AgentConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configs[cfg.ID] = cfg
+}
+
+func (s *fakeAgentStore) remove(agentID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.configs, agentID)
+}
+
+func (s *fakeAgentStore) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *fakeAgentStore) readCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reads
+}
+
+// watchingAgentStore reports changes sent on its channel until ctx is done
+type watchingAgentStore struct {
+	*fakeAgentStore
+	changes chan string
+}
+
+func (s *watchingAgentStore) WatchAgents(ctx context.Context, onChange func(agentID string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case agentID := <-s.changes:
+			onChange(agentID)
+		}
+	}
+}
+
+// registryBuilds records the configs the registry built agents from
+type registryBuilds struct {
+	mu      sync.Mutex
+	configs []genaiconfig.AgentConfig
+	err     error
+}
+
+func (b *registryBuilds) build(t *testing.T) func(cfg genaiconfig.AgentConfig) (GenAIAgentInterface, error) {
+	return func(cfg genaiconfig.AgentConfig) (GenAIAgentInterface, error) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.err != nil {
+			return nil, b.err
+		}
+		b.configs = append(b.configs, cfg)
+		return newFakeAgent(t, cfg.ID, textLLM(cfg.SystemInstruction)), nil
+	}
+}
+
+func (b *registryBuilds) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.configs)
+}
+
+func newTestRegistry(t *testing.T, store AgentConfigStore, checkInterval time.Duration) (*AgentRegistry, *registryBuilds) {
+	t.Helper()
+	builds := &registryBuilds{}
+	registry := NewAgentRegistry("test_app", "test-key", store, AgentRegistryOptions{
+		CheckInterval: checkInterval,
+		Build:         builds.build(t),
+	})
+	return registry.(*AgentRegistry), builds
+}
+
+// expireRegistryEntry makes the next Get re-read the stored config
+func expireRegistryEntry(r *AgentRegistry, agentID string) {
+	e := r.entry(agentID)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.checkedAt = time.Now().Add(-24 * time.Hour)
+}
+
+func supportConfig(instruction string) genaiconfig.AgentConfig {
+	return genaiconfig.AgentConfig{ID: "support", DefaultModel: "gemini-2.5-flash", SystemInstruction: instruction}
+}
+
+func TestAgentRegistryGetBuildsOnce(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeAgentStore(supportConfig("v1"))
+	registry, builds := newTestRegistry(t, store, 0)
+
+	first, err := registry.Get(ctx, "support")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	second, err := registry.Get(ctx, "support")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if first != second {
+		t.Errorf("Get() built a new agent for an unchanged config")
+	}
+	if builds.count() != 1 || store.readCount() != 1 {
+		t.Errorf("builds = %d, reads = %d, want 1 and 1 without a CheckInterval", builds.count(), store.readCount())
+	}
+
+	// changes are not picked up until the entry is invalidated
+	store.set(supportConfig("v2"))
+	expireRegistryEntry(registry, "support")
+	if again, _ := registry.Get(ctx, "support"); again != first || store.readCount() != 1 {
+		t.Errorf("Get() re-read the store without a CheckInterval")
+	}
+}
+
+func TestAgentRegistryFillsMissingID(t *testing.T) {
+	store := newFakeAgentStore()
+	store.configs["support"] = genaiconfig.AgentConfig{DefaultModel: "gemini-2.5-flash"}
+	registry, builds := newTestRegistry(t, store, 0)
+	if _, err := registry.Get(context.Background(), "support"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if builds.configs[0].ID != "support" {
+		t.Errorf("built config ID = %q, want the requested agent id", builds.configs[0].ID)
+	}
+}
+
+func TestAgentRegistryCheckInterval(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeAgentStore(supportConfig("v1"))
+	registry, builds := newTestRegistry(t, store, time.Hour)
+	first, err := registry.Get(ctx, "support")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	// within the interval the cached agent is served without reading the store
+	if cached, _ := registry.Get(ctx, "support"); cached != first || store.readCount() != 1 {
+		t.Errorf("Get() re-read the store within the CheckInterval")
+	}
+
+	// same fingerprint, the config is re-read but the agent is reused
+	expireRegistryEntry(registry, "support")
+	if same, err := registry.Get(ctx, "support"); err != nil || same != first {
+		t.Errorf("Get() = %v, %v, want the cached agent for an unchanged config", same, err)
+	}
+	if store.readCount() != 2 || builds.count() != 1 {
+		t.Errorf("reads = %d, builds = %d, want 2 and 1", store.readCount(), builds.count())
+	}
+
+	// changed config, rebuilt
+	store.set(supportConfig("v2"))
+	expireRegistryEntry(registry, "support")
+	changed, err := registry.Get(ctx, "support")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if changed == first || builds.count() != 2 || builds.configs[1].SystemInstruction != "v2" {
+		t.Errorf("Get() did not rebuild the agent from the changed config")
+	}
+
+	// store failure, the last good agent is kept and the check is postponed
+	store.setErr(errors.New("connection refused"))
+	expireRegistryEntry(registry, "support")
+	if kept, err := registry.Get(ctx, "support"); err != nil || kept != changed {
+		t.Errorf("Get() = %v, %v, want the last good agent while the store fails", kept, err)
+	}
+	reads := store.readCount()
+	if _, err := registry.Get(ctx, "support"); err != nil || store.readCount() != reads {
+		t.Errorf("Get() retried the failing store within the CheckInterval")
+	}
+
+	// broken config, the last good agent is kept
+	store.setErr(nil)
+	store.set(supportConfig("v3"))
+	builds.err = errors.New("invalid config")
+	expireRegistryEntry(registry, "support")
+	if kept, err := registry.Get(ctx, "support"); err != nil || kept != changed {
+		t.Errorf("Get() = %v, %v, want the last good agent when the build fails", kept, err)
+	}
+
+	// removed config, not found
+	builds.err = nil
+	store.remove("support")
+	expireRegistryEntry(registry, "support")
+	if _, err := registry.Get(ctx, "support"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Get() error = %v, want ErrAgentNotFound once the config is removed", err)
+	}
+}
+
+// errAgentStore reports every agent as missing through err
+type errAgentStore struct{ err error }
+
+func (s errAgentStore) GetAgent(ctx context.Context, agentID string) (*genaiconfig.AgentConfig, error) {
+	return nil, s.err
+}
+
+func TestAgentRegistryNotFound(t *testing.T) {
+	tests := []struct {
+		name  string
+		store AgentConfigStore
+	}{
+		{name: "nil config", store: newFakeAgentStore()},
+		{name: "ErrAgentNotFound", store: errAgentStore{err: ErrAgentNotFound}},
+		{name: "redisclient.ErrNotFound", store: errAgentStore{err: fmt.Errorf("%w: agent missing: %w", redisclient.ErrNotFound, redis.Nil)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, builds := newTestRegistry(t, tt.store, 0)
+			if _, err := registry.Get(context.Background(), "missing"); !errors.Is(err, ErrAgentNotFound) {
+				t.Errorf("Get() error = %v, want ErrAgentNotFound", err)
+			}
+			if builds.count() != 0 {
+				t.Errorf("Get() built an agent for a missing config")
+			}
+		})
+	}
+
+	// other store errors are not reported as missing agents
+	store := newFakeAgentStore()
+	store.setErr(errors.New("connection refused"))
+	registry, _ := newTestRegistry(t, store, 0)
+	if _, err := registry.Get(context.Background(), "support"); err == nil || errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Get() error = %v, want the store error", err)
+	}
+}
+
+func TestAgentRegistryInvalidateAndReload(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeAgentStore(supportConfig("v1"))
+	registry, builds := newTestRegistry(t, store, 0)
+	first, err := registry.Get(ctx, "support")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	store.set(supportConfig("v2"))
+	registry.Invalidate("support")
+	second, err := registry.Get(ctx, "support")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if second == first || builds.count() != 2 || builds.configs[1].SystemInstruction != "v2" {
+		t.Errorf("Get() after Invalidate did not build from the stored config")
+	}
+
+	reloaded, err := registry.Reload(ctx, "support")
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if reloaded == second || builds.count() != 3 {
+		t.Errorf("Reload() did not rebuild the agent")
+	}
+	if cached, _ := registry.Get(ctx, "support"); cached != reloaded {
+		t.Errorf("Get() after Reload did not return the reloaded agent")
+	}
+}
+
+func TestAgentRegistryWatch(t *testing.T) {
+	registry, _ := newTestRegistry(t, newFakeAgentStore(), 0)
+	if err := registry.Watch(context.Background()); !errors.Is(err, ErrWatchNotSupported) {
+		t.Errorf("Watch() error = %v, want ErrWatchNotSupported", err)
+	}
+
+	store := &watchingAgentStore{fakeAgentStore: newFakeAgentStore(supportConfig("v1")), changes: make(chan string)}
+	registry, builds := newTestRegistry(t, store, 0)
+	first, err := registry.Get(context.Background(), "support")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- registry.Watch(ctx) }()
+
+	store.set(supportConfig("v2"))
+	store.changes <- "support"
+	// the unbuffered send returns once the change is received, the next one once it was handled
+	store.changes <- "other"
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Watch() error = %v, want context.Canceled", err)
+	}
+
+	updated, err := registry.Get(context.Background(), "support")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if updated == first || builds.count() != 2 {
+		t.Errorf("Get() after a watched change did not rebuild the agent")
+	}
+}
@@ -0,0 +1,51 @@
+// Command schemagen generates Go structs from a JSON Schema or genai.Schema file.
+//
+//	//go:generate go run github.com/darwishdev/genaiclient/cmd/schemagen -in invoice.schema.json -type Invoice -out invoice_gen.go
+//
+// The package defaults to $GOPACKAGE, which go generate sets.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/darwishdev/genaiclient/pkg/schemagen"
+)
+
+func main() {
+	in := flag.String("in", "", "schema file (JSON Schema or genai.Schema json)")
+	out := flag.String("out", "", "output go file, stdout when empty")
+	typeName := flag.String("type", "", "name of the root type")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package of the generated file")
+	flag.Parse()
+
+	if *in == "" || *typeName == "" || *pkg == "" {
+		fmt.Fprintln(os.Stderr, "schemagen: -in, -type and -package (or $GOPACKAGE) are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "schemagen: %v\n", err)
+		os.Exit(1)
+	}
+	source, err := schemagen.GenerateFromBytes(data, schemagen.Options{
+		Package:  *pkg,
+		TypeName: *typeName,
+		Source:   filepath.Base(*in),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "schemagen: %s: %v\n", *in, err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		os.Stdout.Write(source)
+		return
+	}
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "schemagen: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,354 @@
+package genaiclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidAgentDefinition = errors.New("invalid agent definition")
+	ErrUnknownTool            = errors.New("unknown tool")
+	ErrUnknownSchema          = errors.New("unknown output schema")
+)
+
+// AgentDefinition is an agent declared in a yaml or json file. Tools and the output
+// schema are referenced by the names they were registered with on the loader, sub
+// agents by the name of another definition loaded together with this one
+type AgentDefinition struct {
+	Name             string                        `json:"name" pattern:"^[A-Za-z_][A-Za-z0-9_]*$" description:"unique agent name"`
+	Description      string                        `json:"description,omitempty"`
+	Instruction      string                        `json:"instruction,omitempty"`
+	Model            string                        `json:"model" minLength:"1"`
+	GenerationConfig *genaiconfig.GenerationConfig `json:"generationConfig,omitempty"`
+	SafetySettings   []genaiconfig.SafetySetting   `json:"safetySettings,omitempty"`
+	Tools            []string                      `json:"tools,omitempty" description:"names of registered tools"`
+	SubAgents        []string                      `json:"subAgents,omitempty" description:"names of other agent definitions"`
+	OutputSchema     string                        `json:"outputSchema,omitempty" description:"name of a registered output schema"`
+	OutputKey        string                        `json:"outputKey,omitempty" description:"session state key receiving the final answer"`
+}
+
+// AgentConfig returns the definition as the agent config stored by the redis client
+func (d *AgentDefinition) AgentConfig() genaiconfig.AgentConfig {
+	return genaiconfig.AgentConfig{
+		ID:                      d.Name,
+		Persona:                 d.Description,
+		SystemInstruction:       d.Instruction,
+		DefaultModel:            d.Model,
+		DefaultGenerationConfig: d.GenerationConfig,
+		SafetySettings:          d.SafetySettings,
+	}
+}
+
+// AgentDefinitionSchema is the draft 2020-12 json schema definition files are validated
+// against, it can be handed to editors for completion
+func AgentDefinitionSchema() (map[string]any, error) {
+	return adapter.BuildJSONSchemaFromStruct(AgentDefinition{})
+}
+
+var definitionSchema = sync.OnceValues(func() (*jsonschema.Resolved, error) {
+	raw, err := AgentDefinitionSchema()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	return schema.Resolve(nil)
+})
+
+// ParseAgentDefinitions reads yaml or json (json is valid yaml). A document holds one
+// definition or a list of them, yaml files may hold several documents split by ---
+func ParseAgentDefinitions(data []byte) ([]*AgentDefinition, error) {
+	resolved, err := definitionSchema()
+	if err != nil {
+		return nil, fmt.Errorf("agent definition schema: %w", err)
+	}
+	var definitions []*AgentDefinition
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc any
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidAgentDefinition, err)
+		}
+		if doc == nil {
+			continue
+		}
+		items, ok := doc.([]any)
+		if !ok {
+			items = []any{doc}
+		}
+		for _, item := range items {
+			definition, err := decodeAgentDefinition(resolved, item)
+			if err != nil {
+				return nil, err
+			}
+			definitions = append(definitions, definition)
+		}
+	}
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("%w: no agents defined", ErrInvalidAgentDefinition)
+	}
+	return definitions, nil
+}
+
+// decodeAgentDefinition validates one yaml node against the schema and decodes it through
+// json so the json tags of the config types are reused
+func decodeAgentDefinition(resolved *jsonschema.Resolved, item any) (*AgentDefinition, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAgentDefinition, err)
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAgentDefinition, err)
+	}
+	name := "?"
+	if m, ok := generic.(map[string]any); ok {
+		if n, ok := m["name"].(string); ok {
+			name = n
+		}
+	}
+	if err := resolved.Validate(generic); err != nil {
+		return nil, fmt.Errorf("%w: agent %s: %w", ErrInvalidAgentDefinition, name, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var definition AgentDefinition
+	if err := decoder.Decode(&definition); err != nil {
+		return nil, fmt.Errorf("%w: agent %s: %w", ErrInvalidAgentDefinition, name, err)
+	}
+	return &definition, nil
+}
+
+// LoadAgentDefinitions parses every file, names must be unique across all of them
+func LoadAgentDefinitions(paths ...string) ([]*AgentDefinition, error) {
+	var definitions []*AgentDefinition
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read agent definition %s: %w", path, err)
+		}
+		parsed, err := ParseAgentDefinitions(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		definitions = append(definitions, parsed...)
+	}
+	return definitions, nil
+}
+
+// -----------------------------------------------------------
+// loader
+// -----------------------------------------------------------
+
+type GenAIAgentLoaderInterface interface {
+	RegisterTool(t tool.Tool) error
+	RegisterSchema(name string, schema *genai.Schema) error
+	// Validate checks names, tool, schema and sub agent references without building anything
+	Validate(definitions []*AgentDefinition) error
+	Build(definitions []*AgentDefinition) (map[string]GenAIAgentInterface, error)
+	LoadFiles(paths ...string) (map[string]GenAIAgentInterface, error)
+}
+
+type AgentLoader struct {
+	appName      string
+	apiKey       string
+	enableTracer bool
+	mu           sync.RWMutex
+	tools        map[string]tool.Tool
+	schemas      map[string]*genai.Schema
+}
+
+func NewAgentLoader(appName string, apiKey string, enableTracer bool) GenAIAgentLoaderInterface {
+	return &AgentLoader{
+		appName:      appName,
+		apiKey:       apiKey,
+		enableTracer: enableTracer,
+		tools:        map[string]tool.Tool{},
+		schemas:      map[string]*genai.Schema{},
+	}
+}
+
+func (l *AgentLoader) RegisterTool(t tool.Tool) error {
+	if t == nil {
+		return adapter.ErrNilTool
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.tools[t.Name()]; ok {
+		return fmt.Errorf("%w: %s", adapter.ErrDuplicateTool, t.Name())
+	}
+	l.tools[t.Name()] = t
+	return nil
+}
+
+func (l *AgentLoader) RegisterSchema(name string, schema *genai.Schema) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.schemas[name]; ok {
+		return fmt.Errorf("output schema %s is already registered", name)
+	}
+	l.schemas[name] = schema
+	return nil
+}
+
+// RegisterOutputType registers the schema of T under name for the outputSchema field
+func RegisterOutputType[T any](loader GenAIAgentLoaderInterface, name string) error {
+	var t T
+	schema, err := adapter.BuildSchemaFromStructStrict(t)
+	if err != nil {
+		return fmt.Errorf("output schema error: %w", err)
+	}
+	return loader.RegisterSchema(name, schema)
+}
+
+func (l *AgentLoader) Validate(definitions []*AgentDefinition) error {
+	_, err := l.buildOrder(definitions)
+	return err
+}
+
+// buildOrder validates the references and returns the definitions with sub agents before
+// their parents. adk gives an agent a single parent, so a sub agent may be used only once
+func (l *AgentLoader) buildOrder(definitions []*AgentDefinition) ([]*AgentDefinition, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	byName := make(map[string]*AgentDefinition, len(definitions))
+	for _, definition := range definitions {
+		if _, ok := byName[definition.Name]; ok {
+			return nil, fmt.Errorf("%w: agent %s is defined twice", ErrInvalidAgentDefinition, definition.Name)
+		}
+		byName[definition.Name] = definition
+	}
+	var errs []error
+	parents := map[string]string{}
+	for _, definition := range definitions {
+		for _, name := range definition.Tools {
+			if _, ok := l.tools[name]; !ok {
+				errs = append(errs, fmt.Errorf("%w: agent %s uses %s", ErrUnknownTool, definition.Name, name))
+			}
+		}
+		if definition.OutputSchema != "" && len(definition.Tools) > 0 {
+			// gemini rejects function calling together with a json response schema
+			errs = append(errs, fmt.Errorf("%w: agent %s has both tools and an output schema", ErrInvalidAgentDefinition, definition.Name))
+		}
+		if definition.OutputSchema != "" && definition.GenerationConfig != nil && definition.GenerationConfig.ResponseSchemaConfig != nil {
+			errs = append(errs, fmt.Errorf("%w: agent %s has both an output schema and a response schema config", ErrInvalidAgentDefinition, definition.Name))
+		}
+		if definition.OutputSchema != "" {
+			if _, ok := l.schemas[definition.OutputSchema]; !ok {
+				errs = append(errs, fmt.Errorf("%w: agent %s uses %s", ErrUnknownSchema, definition.Name, definition.OutputSchema))
+			}
+		}
+		for _, sub := range definition.SubAgents {
+			if _, ok := byName[sub]; !ok {
+				errs = append(errs, fmt.Errorf("%w: agent %s has unknown sub agent %s", ErrInvalidAgentDefinition, definition.Name, sub))
+				continue
+			}
+			if parent, ok := parents[sub]; ok {
+				errs = append(errs, fmt.Errorf("%w: agent %s is a sub agent of both %s and %s", ErrInvalidAgentDefinition, sub, parent, definition.Name))
+				continue
+			}
+			parents[sub] = definition.Name
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	order := make([]*AgentDefinition, 0, len(definitions))
+	state := map[string]int{} // 1 visiting, 2 done
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("%w: sub agent cycle %s", ErrInvalidAgentDefinition, strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, sub := range byName[name].SubAgents {
+			if err := visit(sub, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, byName[name])
+		return nil
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Build creates every defined agent, the result is keyed by agent name
+func (l *AgentLoader) Build(definitions []*AgentDefinition) (map[string]GenAIAgentInterface, error) {
+	order, err := l.buildOrder(definitions)
+	if err != nil {
+		return nil, err
+	}
+	agents := make(map[string]GenAIAgentInterface, len(order))
+	for _, definition := range order {
+		built, err := l.build(definition, agents)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", definition.Name, err)
+		}
+		agents[definition.Name] = built
+	}
+	return agents, nil
+}
+
+func (l *AgentLoader) build(definition *AgentDefinition, built map[string]GenAIAgentInterface) (GenAIAgentInterface, error) {
+	l.mu.RLock()
+	cfg := llmagent.Config{OutputKey: definition.OutputKey}
+	for _, name := range definition.Tools {
+		cfg.Tools = append(cfg.Tools, l.tools[name])
+	}
+	if definition.OutputSchema != "" {
+		cfg.OutputSchema = l.schemas[definition.OutputSchema]
+	}
+	l.mu.RUnlock()
+	for _, sub := range definition.SubAgents {
+		cfg.SubAgents = append(cfg.SubAgents, built[sub].Agent())
+	}
+	return NewGeminiAgentFromAgentConfig(l.appName, l.apiKey, definition.AgentConfig(), nil, nil, l.enableTracer, cfg)
+}
+
+func (l *AgentLoader) LoadFiles(paths ...string) (map[string]GenAIAgentInterface, error) {
+	definitions, err := LoadAgentDefinitions(paths...)
+	if err != nil {
+		return nil, err
+	}
+	return l.Build(definitions)
+}
@@ -0,0 +1,327 @@
+package genaiclient
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+func TestParseAgentDefinitions(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantNames []string
+		wantErr   string
+	}{
+		{
+			name:      "single yaml document",
+			input:     "name: support\nmodel: gemini-2.5-flash\ngenerationConfig:\n  temperature: 0.2\n",
+			wantNames: []string{"support"},
+		},
+		{
+			name:      "json",
+			input:     `{"name": "support", "model": "gemini-2.5-flash", "tools": ["lookup"]}`,
+			wantNames: []string{"support"},
+		},
+		{
+			name:      "list of definitions",
+			input:     "- name: a\n  model: m\n- name: b\n  model: m\n",
+			wantNames: []string{"a", "b"},
+		},
+		{
+			name:      "multiple documents with an empty one",
+			input:     "name: a\nmodel: m\n---\n---\n- name: b\n  model: m\n- name: c\n  model: m\n",
+			wantNames: []string{"a", "b", "c"},
+		},
+		{
+			name:    "missing model",
+			input:   "name: support\n",
+			wantErr: "support",
+		},
+		{
+			name:    "empty model",
+			input:   "name: support\nmodel: \"\"\n",
+			wantErr: "support",
+		},
+		{
+			name:    "name not matching the pattern",
+			input:   "name: 1support\nmodel: m\n",
+			wantErr: "1support",
+		},
+		{
+			name:    "unknown field",
+			input:   "name: support\nmodel: m\ntemperature: 0.2\n",
+			wantErr: "support",
+		},
+		{
+			name:    "wrong field type",
+			input:   "name: support\nmodel: m\ntools: lookup\n",
+			wantErr: "support",
+		},
+		{
+			name:    "invalid yaml",
+			input:   "name: [support\n",
+			wantErr: "invalid agent definition",
+		},
+		{
+			name:    "no documents",
+			input:   "---\n",
+			wantErr: "no agents defined",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definitions, err := ParseAgentDefinitions([]byte(tt.input))
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidAgentDefinition) {
+					t.Fatalf("ParseAgentDefinitions() error = %v, want ErrInvalidAgentDefinition", err)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseAgentDefinitions() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAgentDefinitions() error = %v", err)
+			}
+			if len(definitions) != len(tt.wantNames) {
+				t.Fatalf("got %d definitions, want %d", len(definitions), len(tt.wantNames))
+			}
+			for i, name := range tt.wantNames {
+				if definitions[i].Name != name {
+					t.Errorf("definitions[%d].Name = %q, want %q", i, definitions[i].Name, name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAgentDefinitionsFields(t *testing.T) {
+	input := `
+name: billing
+description: Billing specialist
+instruction: Answer billing questions.
+model: gemini-2.5-flash
+generationConfig:
+  temperature: 0.5
+safetySettings:
+  - category: HARM_CATEGORY_HARASSMENT
+    threshold: BLOCK_ONLY_HIGH
+subAgents: [invoices]
+outputSchema: reply
+outputKey: answer
+`
+	definitions, err := ParseAgentDefinitions([]byte(input))
+	if err != nil {
+		t.Fatalf("ParseAgentDefinitions() error = %v", err)
+	}
+	d := definitions[0]
+	if d.Description != "Billing specialist" || d.Instruction != "Answer billing questions." ||
+		d.OutputSchema != "reply" || d.OutputKey != "answer" || len(d.SubAgents) != 1 {
+		t.Errorf("definition = %+v", d)
+	}
+	if d.GenerationConfig == nil || d.GenerationConfig.Temperature == nil || *d.GenerationConfig.Temperature != 0.5 {
+		t.Errorf("GenerationConfig = %+v", d.GenerationConfig)
+	}
+	if len(d.SafetySettings) != 1 || d.SafetySettings[0].Threshold != "BLOCK_ONLY_HIGH" {
+		t.Errorf("SafetySettings = %+v", d.SafetySettings)
+	}
+	cfg := d.AgentConfig()
+	if cfg.ID != "billing" || cfg.DefaultModel != "gemini-2.5-flash" || cfg.Persona != "Billing specialist" {
+		t.Errorf("AgentConfig() = %+v", cfg)
+	}
+}
+
+func newTestTool(t *testing.T, name string) tool.Tool {
+	t.Helper()
+	handler := func(tool.Context, struct{}) struct{} { return struct{}{} }
+	lookup, err := functiontool.New(functiontool.Config{Name: name, Description: name}, handler)
+	if err != nil {
+		t.Fatalf("functiontool.New() error = %v", err)
+	}
+	return lookup
+}
+
+func newTestLoader(t *testing.T) GenAIAgentLoaderInterface {
+	t.Helper()
+	loader := NewAgentLoader("test_app", "test-key", false)
+	if err := loader.RegisterTool(newTestTool(t, "lookup")); err != nil {
+		t.Fatalf("RegisterTool() error = %v", err)
+	}
+	if err := RegisterOutputType[groundedAnswer](loader, "reply"); err != nil {
+		t.Fatalf("RegisterOutputType() error = %v", err)
+	}
+	return loader
+}
+
+func TestAgentLoaderRegister(t *testing.T) {
+	loader := newTestLoader(t)
+	if err := loader.RegisterTool(newTestTool(t, "lookup")); err == nil {
+		t.Errorf("RegisterTool() of a duplicate name should fail")
+	}
+	if err := loader.RegisterSchema("reply", &genai.Schema{Type: genai.TypeString}); err == nil {
+		t.Errorf("RegisterSchema() of a duplicate name should fail")
+	}
+	if err := loader.RegisterTool(nil); !errors.Is(err, adapter.ErrNilTool) {
+		t.Errorf("RegisterTool(nil) error = %v, want ErrNilTool", err)
+	}
+}
+
+func TestAgentLoaderValidate(t *testing.T) {
+	agent := func(name string, subs ...string) *AgentDefinition {
+		return &AgentDefinition{Name: name, Model: "m", SubAgents: subs}
+	}
+	tests := []struct {
+		name        string
+		definitions []*AgentDefinition
+		wantErr     error
+		wantMessage string
+	}{
+		{
+			name:        "valid tree",
+			definitions: []*AgentDefinition{agent("root", "a", "b"), agent("a", "c"), agent("b"), agent("c")},
+		},
+		{
+			name: "tools and output schema on different agents",
+			definitions: []*AgentDefinition{
+				{Name: "root", Model: "m", Tools: []string{"lookup"}, SubAgents: []string{"writer"}},
+				{Name: "writer", Model: "m", OutputSchema: "reply"},
+			},
+		},
+		{
+			name:        "duplicate name",
+			definitions: []*AgentDefinition{agent("a"), agent("a")},
+			wantErr:     ErrInvalidAgentDefinition,
+			wantMessage: "agent a is defined twice",
+		},
+		{
+			name:        "unknown tool",
+			definitions: []*AgentDefinition{{Name: "a", Model: "m", Tools: []string{"missing"}}},
+			wantErr:     ErrUnknownTool,
+			wantMessage: "missing",
+		},
+		{
+			name:        "unknown schema",
+			definitions: []*AgentDefinition{{Name: "a", Model: "m", OutputSchema: "missing"}},
+			wantErr:     ErrUnknownSchema,
+			wantMessage: "missing",
+		},
+		{
+			name:        "unknown sub agent",
+			definitions: []*AgentDefinition{agent("a", "missing")},
+			wantErr:     ErrInvalidAgentDefinition,
+			wantMessage: "unknown sub agent missing",
+		},
+		{
+			name:        "sub agent with two parents",
+			definitions: []*AgentDefinition{agent("a", "c"), agent("b", "c"), agent("c")},
+			wantErr:     ErrInvalidAgentDefinition,
+			wantMessage: "agent c is a sub agent of both a and b",
+		},
+		{
+			name:        "self cycle",
+			definitions: []*AgentDefinition{agent("a", "a")},
+			wantErr:     ErrInvalidAgentDefinition,
+			wantMessage: "sub agent cycle a -> a",
+		},
+		{
+			name:        "cycle",
+			definitions: []*AgentDefinition{agent("a", "b"), agent("b", "c"), agent("c", "a")},
+			wantErr:     ErrInvalidAgentDefinition,
+			wantMessage: "sub agent cycle a -> b -> c -> a",
+		},
+		{
+			name:        "tools and output schema",
+			definitions: []*AgentDefinition{{Name: "a", Model: "m", Tools: []string{"lookup"}, OutputSchema: "reply"}},
+			wantErr:     ErrInvalidAgentDefinition,
+			wantMessage: "agent a has both tools and an output schema",
+		},
+		{
+			name: "output schema and response schema config",
+			definitions: []*AgentDefinition{{Name: "a", Model: "m", OutputSchema: "reply",
+				GenerationConfig: &genaiconfig.GenerationConfig{ResponseSchemaConfig: &genaiconfig.SchemaConfig{}}}},
+			wantErr:     ErrInvalidAgentDefinition,
+			wantMessage: "agent a has both an output schema and a response schema config",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestLoader(t).Validate(tt.definitions)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestAgentLoaderBuildOrder(t *testing.T) {
+	loader := newTestLoader(t).(*AgentLoader)
+	definitions := []*AgentDefinition{
+		{Name: "root", Model: "m", SubAgents: []string{"b", "a"}},
+		{Name: "a", Model: "m", SubAgents: []string{"c"}},
+		{Name: "b", Model: "m"},
+		{Name: "c", Model: "m"},
+	}
+	order, err := loader.buildOrder(definitions)
+	if err != nil {
+		t.Fatalf("buildOrder() error = %v", err)
+	}
+	position := map[string]int{}
+	for i, definition := range order {
+		position[definition.Name] = i
+	}
+	if len(position) != len(definitions) {
+		t.Fatalf("buildOrder() returned %d definitions, want %d", len(order), len(definitions))
+	}
+	for _, definition := range definitions {
+		for _, sub := range definition.SubAgents {
+			if position[sub] > position[definition.Name] {
+				t.Errorf("sub agent %s is built after its parent %s", sub, definition.Name)
+			}
+		}
+	}
+}
+
+func TestAgentLoaderBuild(t *testing.T) {
+	definitions, err := ParseAgentDefinitions([]byte(`
+name: support
+model: gemini-2.5-flash
+tools: [lookup]
+subAgents: [writer]
+---
+name: writer
+model: gemini-2.5-flash
+outputSchema: reply
+outputKey: answer
+`))
+	if err != nil {
+		t.Fatalf("ParseAgentDefinitions() error = %v", err)
+	}
+	agents, err := newTestLoader(t).Build(definitions)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	support, writer := agents["support"], agents["writer"]
+	if support == nil || writer == nil {
+		t.Fatalf("Build() = %v, want support and writer", agents)
+	}
+	subs := support.Agent().SubAgents()
+	if len(subs) != 1 || subs[0] != writer.Agent() {
+		t.Errorf("support sub agents = %v, want the built writer", subs)
+	}
+}
@@ -0,0 +1,219 @@
+package genaiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/genai"
+)
+
+const (
+	DefaultEmbeddingModel = "gemini-embedding-001"
+	// DefaultEmbedTaskType is sent when the options leave TaskType empty
+	DefaultEmbedTaskType = "RETRIEVAL_DOCUMENT"
+	// DefaultEmbedBatchSize is the max number of contents gemini accepts per embed request
+	DefaultEmbedBatchSize   = 100
+	DefaultEmbedConcurrency = 4
+	DefaultEmbedMaxRetries  = 2
+	DefaultEmbedBackoff     = 500 * time.Millisecond
+)
+
+var (
+	ErrEmbedEmptyText     = errors.New("cannot embed empty text")
+	ErrEmbedCountMismatch = errors.New("gemini returned a different number of embeddings than inputs")
+)
+
+// EmbedBatchOptions controls how EmbedBatch splits and runs the requests,
+// the embedded EmbedOptions are applied to every batch
+type EmbedBatchOptions struct {
+	EmbedOptions
+	// BatchSize is the number of texts sent per request (defaults to DefaultEmbedBatchSize)
+	BatchSize int
+	// Concurrency is the max number of in flight requests (defaults to DefaultEmbedConcurrency)
+	Concurrency int
+	// MaxRetries is the number of retries for a failed batch. 0 means DefaultEmbedMaxRetries,
+	// not zero retries, pass a negative value to disable retries
+	MaxRetries int
+	// RetryBackoff is the initial wait between retries, doubled on every attempt
+	RetryBackoff time.Duration
+}
+
+// EmbedBatchResult is the outcome for a single input, results are returned in input order
+type EmbedBatchResult struct {
+	Index  int
+	Vector []float32
+	Err    error
+}
+
+// embedContentConfig maps every embed option independently into the model name and
+// the genai config, the config is nil without options. The task type keeps defaulting
+// to RETRIEVAL_DOCUMENT, it used to be sent only together with Dimensions
+func embedContentConfig(options ...*EmbedOptions) (string, *genai.EmbedContentConfig) {
+	embeddingModel := DefaultEmbeddingModel
+	if len(options) == 0 || options[0] == nil {
+		return embeddingModel, nil
+	}
+	opts := options[0]
+	if opts.Model != "" {
+		embeddingModel = opts.Model
+	}
+	genaiConfig := &genai.EmbedContentConfig{
+		TaskType:     opts.TaskType,
+		Title:        opts.Title,
+		AutoTruncate: opts.AutoTruncate,
+	}
+	if genaiConfig.TaskType == "" {
+		genaiConfig.TaskType = DefaultEmbedTaskType
+	}
+	if opts.Dimensions > 0 {
+		dim := opts.Dimensions // Store value in a variable to get its address
+		genaiConfig.OutputDimensionality = &dim
+	}
+	return embeddingModel, genaiConfig
+}
+
+// normalizeVector returns a unit length copy of the vector
+func normalizeVector(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(vector))
+	for index, v := range vector {
+		out[index] = float32(float64(v) / norm)
+	}
+	return out
+}
+
+func (o *EmbedBatchOptions) withDefaults() *EmbedBatchOptions {
+	out := EmbedBatchOptions{}
+	if o != nil {
+		out = *o
+	}
+	if out.BatchSize <= 0 {
+		out.BatchSize = DefaultEmbedBatchSize
+	}
+	if out.Concurrency <= 0 {
+		out.Concurrency = DefaultEmbedConcurrency
+	}
+	if out.MaxRetries == 0 {
+		out.MaxRetries = DefaultEmbedMaxRetries
+	}
+	if out.MaxRetries < 0 {
+		out.MaxRetries = 0
+	}
+	if out.RetryBackoff <= 0 {
+		out.RetryBackoff = DefaultEmbedBackoff
+	}
+	return &out
+}
+
+// EmbedBatch embeds every text, splitting them into provider sized batches that run
+// with bounded concurrency. A failing batch is retried and, if it still fails, only its
+// items carry the error; the returned error is reserved for failures of the whole call.
+func (a *GenAIAgent) EmbedBatch(ctx context.Context, texts []string, options ...*EmbedBatchOptions) ([]*EmbedBatchResult, error) {
+	if a.genaiClient == nil {
+		return nil, ErrEmbedClientMissing
+	}
+	var opts *EmbedBatchOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	opts = opts.withDefaults()
+	embeddingModel, genaiConfig := embedContentConfig(&opts.EmbedOptions)
+
+	results := make([]*EmbedBatchResult, len(texts))
+	pending := make([]int, 0, len(texts))
+	for index, text := range texts {
+		results[index] = &EmbedBatchResult{Index: index}
+		if text == "" {
+			results[index].Err = ErrEmbedEmptyText
+			continue
+		}
+		pending = append(pending, index)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Concurrency)
+	for start := 0; start < len(pending); start += opts.BatchSize {
+		end := min(start+opts.BatchSize, len(pending))
+		batch := pending[start:end]
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			for _, index := range batch {
+				results[index].Err = ctx.Err()
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(batch []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			vectors, err := a.embedBatchWithRetry(ctx, embeddingModel, genaiConfig, texts, batch, opts)
+			for position, index := range batch {
+				if err != nil {
+					results[index].Err = err
+					continue
+				}
+				results[index].Vector = vectors[position]
+			}
+		}(batch)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (a *GenAIAgent) embedBatchWithRetry(
+	ctx context.Context,
+	embeddingModel string,
+	genaiConfig *genai.EmbedContentConfig,
+	texts []string,
+	batch []int,
+	opts *EmbedBatchOptions,
+) ([][]float32, error) {
+	contents := make([]*genai.Content, len(batch))
+	for position, index := range batch {
+		contents[position] = genai.NewContentFromText(texts[index], genai.RoleUser)
+	}
+	backoff := opts.RetryBackoff
+	var lastErr error
+	for attempt := 0; attempt <= opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			log.Debug().Err(lastErr).Int("attempt", attempt).Int("size", len(batch)).Msg("retrying embed batch")
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			backoff *= 2
+		}
+		embed, err := a.genaiClient.Models.EmbedContent(ctx, embeddingModel, contents, genaiConfig)
+		if err != nil {
+			lastErr = fmt.Errorf("%w with model : %w", ErrEmbedContentFailed, err)
+			continue
+		}
+		if len(embed.Embeddings) != len(contents) {
+			lastErr = fmt.Errorf("%w: got %d want %d", ErrEmbedCountMismatch, len(embed.Embeddings), len(contents))
+			continue
+		}
+		vectors := make([][]float32, len(embed.Embeddings))
+		for position, embedding := range embed.Embeddings {
+			vectors[position] = embedding.Values
+			if opts.Normalize {
+				vectors[position] = normalizeVector(embedding.Values)
+			}
+		}
+		return vectors, nil
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,259 @@
+package genaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+type embedRequest struct {
+	Requests []struct {
+		Content  genai.Content `json:"content"`
+		TaskType string        `json:"taskType"`
+	} `json:"requests"`
+}
+
+// newEmbedTestAgent points an agent at a fake batchEmbedContents endpoint, handle gets
+// the texts of every request and returns their vectors or an http status to fail with
+func newEmbedTestAgent(t *testing.T, handle func(texts []string) ([][]float32, int)) *GenAIAgent {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		texts := make([]string, len(req.Requests))
+		for index, item := range req.Requests {
+			for _, part := range item.Content.Parts {
+				texts[index] += part.Text
+			}
+		}
+		vectors, status := handle(texts)
+		if status != 0 {
+			http.Error(w, `{"error":{"message":"`+http.StatusText(status)+`"}}`, status)
+			return
+		}
+		embeddings := make([]map[string]any, len(vectors))
+		for index, vector := range vectors {
+			embeddings[index] = map[string]any{"values": vector}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": embeddings})
+	}))
+	t.Cleanup(srv.Close)
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: srv.URL},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return &GenAIAgent{appName: "test_app", genaiClient: client}
+}
+
+// lengthVectors embeds every text as a single value holding its length
+func lengthVectors(texts []string) [][]float32 {
+	vectors := make([][]float32, len(texts))
+	for index, text := range texts {
+		vectors[index] = []float32{float32(len(text))}
+	}
+	return vectors
+}
+
+func TestEmbedBatchChunksInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+	a := newEmbedTestAgent(t, func(texts []string) ([][]float32, int) {
+		mu.Lock()
+		sizes = append(sizes, len(texts))
+		mu.Unlock()
+		return lengthVectors(texts), 0
+	})
+	texts := []string{"a", "bb", "", "ccc", "dddd", "eeeee"}
+	results, err := a.EmbedBatch(context.Background(), texts, &EmbedBatchOptions{BatchSize: 2, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if len(sizes) != 3 {
+		t.Errorf("requests = %v, want 3 batches of at most 2", sizes)
+	}
+	for index, result := range results {
+		if result.Index != index {
+			t.Errorf("results[%d].Index = %d", index, result.Index)
+		}
+		if texts[index] == "" {
+			if !errors.Is(result.Err, ErrEmbedEmptyText) {
+				t.Errorf("results[%d].Err = %v, want ErrEmbedEmptyText", index, result.Err)
+			}
+			continue
+		}
+		if result.Err != nil || len(result.Vector) != 1 || result.Vector[0] != float32(len(texts[index])) {
+			t.Errorf("results[%d] = %+v, want the vector of %q", index, result, texts[index])
+		}
+	}
+}
+
+func TestEmbedBatchConcurrencyLimit(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	a := newEmbedTestAgent(t, func(texts []string) ([][]float32, int) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return lengthVectors(texts), 0
+	})
+	texts := strings.Split("a b c d e f g h", " ")
+	if _, err := a.EmbedBatch(context.Background(), texts, &EmbedBatchOptions{BatchSize: 1, Concurrency: 2}); err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if got := peak.Load(); got > 2 || got < 1 {
+		t.Errorf("peak concurrent requests = %d, want at most 2", got)
+	}
+}
+
+func TestEmbedBatchRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		failures   int32
+		wantCalls  int32
+		wantErr    bool
+	}{
+		{name: "default retries recover", maxRetries: 0, failures: 2, wantCalls: 3},
+		{name: "retries exhausted", maxRetries: 1, failures: 5, wantCalls: 2, wantErr: true},
+		{name: "retries disabled", maxRetries: -1, failures: 1, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			a := newEmbedTestAgent(t, func(texts []string) ([][]float32, int) {
+				if calls.Add(1) <= tt.failures {
+					return nil, http.StatusServiceUnavailable
+				}
+				return lengthVectors(texts), 0
+			})
+			results, err := a.EmbedBatch(context.Background(), []string{"x"}, &EmbedBatchOptions{
+				MaxRetries:   tt.maxRetries,
+				RetryBackoff: time.Millisecond,
+			})
+			if err != nil {
+				t.Fatalf("EmbedBatch() error = %v", err)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls.Load(), tt.wantCalls)
+			}
+			if gotErr := results[0].Err != nil; gotErr != tt.wantErr {
+				t.Errorf("results[0].Err = %v, wantErr %v", results[0].Err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(results[0].Err, ErrEmbedContentFailed) {
+				t.Errorf("results[0].Err = %v, want ErrEmbedContentFailed", results[0].Err)
+			}
+		})
+	}
+}
+
+func TestEmbedBatchPerItemErrors(t *testing.T) {
+	a := newEmbedTestAgent(t, func(texts []string) ([][]float32, int) {
+		for _, text := range texts {
+			if text == "bad" {
+				return nil, http.StatusBadRequest
+			}
+		}
+		if len(texts) == 2 {
+			// one vector short of the request
+			return lengthVectors(texts[:1]), 0
+		}
+		return lengthVectors(texts), 0
+	})
+	results, err := a.EmbedBatch(context.Background(), []string{"ok", "bad", "fine", "x", "y", "last"}, &EmbedBatchOptions{
+		BatchSize:  1,
+		MaxRetries: -1,
+	})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	for index, result := range results {
+		failed := result.Err != nil
+		if failed != (index == 1) {
+			t.Errorf("results[%d].Err = %v", index, result.Err)
+		}
+	}
+
+	results, _ = a.EmbedBatch(context.Background(), []string{"x", "y"}, &EmbedBatchOptions{MaxRetries: -1})
+	for index, result := range results {
+		if !errors.Is(result.Err, ErrEmbedCountMismatch) {
+			t.Errorf("results[%d].Err = %v, want ErrEmbedCountMismatch", index, result.Err)
+		}
+	}
+}
+
+func TestEmbedBatchWithoutClient(t *testing.T) {
+	if _, err := (&GenAIAgent{}).EmbedBatch(context.Background(), []string{"x"}); !errors.Is(err, ErrEmbedClientMissing) {
+		t.Errorf("EmbedBatch() error = %v, want ErrEmbedClientMissing", err)
+	}
+}
+
+func TestEmbedContentConfig(t *testing.T) {
+	dims := int32(256)
+	tests := []struct {
+		name      string
+		options   []*EmbedOptions
+		wantModel string
+		want      *genai.EmbedContentConfig
+	}{
+		{name: "no options", wantModel: DefaultEmbeddingModel},
+		{name: "nil options", options: []*EmbedOptions{nil}, wantModel: DefaultEmbeddingModel},
+		{
+			name:      "model only keeps the default task type",
+			options:   []*EmbedOptions{{Model: "other"}},
+			wantModel: "other",
+			want:      &genai.EmbedContentConfig{TaskType: DefaultEmbedTaskType},
+		},
+		{
+			name:      "task type without dimensions",
+			options:   []*EmbedOptions{{TaskType: "RETRIEVAL_QUERY"}},
+			wantModel: DefaultEmbeddingModel,
+			want:      &genai.EmbedContentConfig{TaskType: "RETRIEVAL_QUERY"},
+		},
+		{
+			name:      "dimensions without task type",
+			options:   []*EmbedOptions{{Dimensions: 256}},
+			wantModel: DefaultEmbeddingModel,
+			want:      &genai.EmbedContentConfig{TaskType: DefaultEmbedTaskType, OutputDimensionality: &dims},
+		},
+		{
+			name:      "title and truncation",
+			options:   []*EmbedOptions{{Title: "doc", AutoTruncate: true}},
+			wantModel: DefaultEmbeddingModel,
+			want:      &genai.EmbedContentConfig{TaskType: DefaultEmbedTaskType, Title: "doc", AutoTruncate: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, got := embedContentConfig(tt.options...)
+			if model != tt.wantModel {
+				t.Errorf("model = %s, want %s", model, tt.wantModel)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("config = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
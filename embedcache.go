@@ -0,0 +1,224 @@
+package genaiclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// EmbeddingCacheStore persists vectors by content hash, GetEmbedding must return a nil
+// vector without error on a miss. redisclient.RedisClient implements it.
+type EmbeddingCacheStore interface {
+	SetEmbedding(ctx context.Context, hash string, vector []float32, ttl time.Duration) error
+	GetEmbedding(ctx context.Context, hash string) ([]float32, error)
+}
+
+// EmbedCacheStats are the cache counters since the embedder was created
+type EmbedCacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Errors uint64
+}
+
+// GenAICachedEmbedderInterface is an embedder that serves repeated texts from a cache
+type GenAICachedEmbedderInterface interface {
+	GenAIEmbedderInterface
+	Stats() EmbedCacheStats
+}
+
+type CachedEmbedder struct {
+	embedder GenAIEmbedderInterface
+	store    EmbeddingCacheStore
+	ttl      time.Duration
+	hits     atomic.Uint64
+	misses   atomic.Uint64
+	errors   atomic.Uint64
+}
+
+// NewCachedEmbedder wraps embedder (usually a GenAIAgentInterface) with a cache layer,
+// ttl 0 keeps the vectors forever
+func NewCachedEmbedder(embedder GenAIEmbedderInterface, store EmbeddingCacheStore, ttl time.Duration) GenAICachedEmbedderInterface {
+	return &CachedEmbedder{
+		embedder: embedder,
+		store:    store,
+		ttl:      ttl,
+	}
+}
+
+// EmbeddingCacheKey hashes everything that changes the produced vector. It is built from
+// the request embedContentConfig sends, so nil options (no config) and empty options
+// (the default task type) get different keys
+func EmbeddingCacheKey(text string, options *EmbedOptions) string {
+	model, cfg := embedContentConfig(options)
+	var (
+		taskType, title string
+		dimensions      int32
+		autoTruncate    bool
+		normalize       bool
+	)
+	if cfg != nil {
+		taskType, title, autoTruncate = cfg.TaskType, cfg.Title, cfg.AutoTruncate
+		if cfg.OutputDimensionality != nil {
+			dimensions = *cfg.OutputDimensionality
+		}
+	}
+	if options != nil {
+		normalize = options.Normalize
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s\x00%t\x00%t\x00%s",
+		model, taskType, dimensions, title, autoTruncate, normalize, text)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *CachedEmbedder) Stats() EmbedCacheStats {
+	return EmbedCacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.errors.Load(),
+	}
+}
+
+func (c *CachedEmbedder) lookup(ctx context.Context, key string) []float32 {
+	vector, err := c.store.GetEmbedding(ctx, key)
+	if err != nil {
+		c.errors.Add(1)
+		log.Debug().Err(err).Str("key", key).Msg("embedding cache read failed")
+		return nil
+	}
+	if vector == nil {
+		c.misses.Add(1)
+		return nil
+	}
+	c.hits.Add(1)
+	return vector
+}
+
+func (c *CachedEmbedder) save(ctx context.Context, key string, vector []float32) {
+	if err := c.store.SetEmbedding(ctx, key, vector, c.ttl); err != nil {
+		c.errors.Add(1)
+		log.Debug().Err(err).Str("key", key).Msg("embedding cache write failed")
+	}
+}
+
+func (c *CachedEmbedder) Embed(ctx context.Context, text string, options ...*EmbedOptions) ([][]float32, error) {
+	var opts *EmbedOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	key := EmbeddingCacheKey(text, opts)
+	if vector := c.lookup(ctx, key); vector != nil {
+		return [][]float32{vector}, nil
+	}
+	vectors, err := c.embedder.Embed(ctx, text, options...)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 1 {
+		c.save(ctx, key, vectors[0])
+	}
+	return vectors, nil
+}
+
+// EmbedBatch serves cached texts from the store and only sends the misses to the embedder
+func (c *CachedEmbedder) EmbedBatch(ctx context.Context, texts []string, options ...*EmbedBatchOptions) ([]*EmbedBatchResult, error) {
+	var opts *EmbedBatchOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	// batches always send a config, even without options
+	embedOpts := &EmbedOptions{}
+	if opts != nil {
+		embedOpts = &opts.EmbedOptions
+	}
+	results := make([]*EmbedBatchResult, len(texts))
+	keys := make([]string, len(texts))
+	missTexts := make([]string, 0, len(texts))
+	missIndexes := make([]int, 0, len(texts))
+	for index, text := range texts {
+		results[index] = &EmbedBatchResult{Index: index}
+		if text == "" {
+			results[index].Err = ErrEmbedEmptyText
+			continue
+		}
+		keys[index] = EmbeddingCacheKey(text, embedOpts)
+		if vector := c.lookup(ctx, keys[index]); vector != nil {
+			results[index].Vector = vector
+			continue
+		}
+		missTexts = append(missTexts, text)
+		missIndexes = append(missIndexes, index)
+	}
+	if len(missTexts) == 0 {
+		return results, nil
+	}
+	embedded, err := c.embedder.EmbedBatch(ctx, missTexts, options...)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range embedded {
+		index := missIndexes[res.Index]
+		results[index].Vector = res.Vector
+		results[index].Err = res.Err
+		if res.Err == nil && res.Vector != nil {
+			c.save(ctx, keys[index], res.Vector)
+		}
+	}
+	return results, nil
+}
+
+// -----------------------------------------------------------
+// In memory store
+// -----------------------------------------------------------
+
+type inMemoryEmbeddingEntry struct {
+	vector    []float32
+	expiresAt time.Time
+}
+
+type InMemoryEmbeddingStore struct {
+	mu      sync.RWMutex
+	entries map[string]inMemoryEmbeddingEntry
+}
+
+// NewInMemoryEmbeddingStore is a process local EmbeddingCacheStore, expired entries
+// are dropped lazily on read
+func NewInMemoryEmbeddingStore() EmbeddingCacheStore {
+	return &InMemoryEmbeddingStore{entries: make(map[string]inMemoryEmbeddingEntry)}
+}
+
+func (s *InMemoryEmbeddingStore) SetEmbedding(ctx context.Context, hash string, vector []float32, ttl time.Duration) error {
+	entry := inMemoryEmbeddingEntry{vector: append([]float32(nil), vector...)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	s.mu.Lock()
+	s.entries[hash] = entry
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *InMemoryEmbeddingStore) GetEmbedding(ctx context.Context, hash string) ([]float32, error) {
+	s.mu.RLock()
+	entry, ok := s.entries[hash]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		s.mu.Lock()
+		if current, ok := s.entries[hash]; ok && current.expiresAt.Equal(entry.expiresAt) {
+			delete(s.entries, hash)
+		}
+		s.mu.Unlock()
+		return nil, nil
+	}
+	// callers own the returned slice, the cached one must not change under them
+	return append([]float32(nil), entry.vector...), nil
+}
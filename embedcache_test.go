@@ -0,0 +1,170 @@
+package genaiclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInMemoryEmbeddingStoreCopies(t *testing.T) {
+	store := NewInMemoryEmbeddingStore()
+	ctx := context.Background()
+	vector := []float32{1, 2}
+	if err := store.SetEmbedding(ctx, "k", vector, 0); err != nil {
+		t.Fatalf("SetEmbedding() error = %v", err)
+	}
+	vector[0] = 99
+	got, _ := store.GetEmbedding(ctx, "k")
+	if got[0] != 1 {
+		t.Fatalf("stored vector changed with the input slice: %v", got)
+	}
+	got[1] = 99
+	again, _ := store.GetEmbedding(ctx, "k")
+	if again[1] != 2 {
+		t.Errorf("stored vector changed through the returned slice: %v", again)
+	}
+}
+
+func TestInMemoryEmbeddingStoreTTL(t *testing.T) {
+	store := NewInMemoryEmbeddingStore()
+	ctx := context.Background()
+	store.SetEmbedding(ctx, "k", []float32{1}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if got, err := store.GetEmbedding(ctx, "k"); got != nil || err != nil {
+		t.Errorf("GetEmbedding() = %v, %v, want an expired miss", got, err)
+	}
+	if got, _ := store.GetEmbedding(ctx, "missing"); got != nil {
+		t.Errorf("GetEmbedding() = %v, want nil on a miss", got)
+	}
+}
+
+func TestEmbeddingCacheKey(t *testing.T) {
+	base := EmbeddingCacheKey("hello", nil)
+	empty := EmbeddingCacheKey("hello", &EmbedOptions{})
+	if empty == base {
+		t.Errorf("nil options send no config and should not share a key with empty options")
+	}
+	if empty != EmbeddingCacheKey("hello", &EmbedOptions{Model: DefaultEmbeddingModel, TaskType: DefaultEmbedTaskType}) {
+		t.Errorf("empty options and the explicit defaults send the same request and should share a key")
+	}
+	variants := []*EmbedOptions{
+		{TaskType: "RETRIEVAL_QUERY"},
+		{Dimensions: 256},
+		{Model: "other-model"},
+		{Normalize: true},
+		{Title: "doc"},
+	}
+	for _, opts := range variants {
+		if EmbeddingCacheKey("hello", opts) == base {
+			t.Errorf("options %+v should change the key", opts)
+		}
+	}
+	if EmbeddingCacheKey("hello!", nil) == base {
+		t.Errorf("text should change the key")
+	}
+}
+
+func TestCachedEmbedderBatchDoesNotReuseUnconfiguredEmbed(t *testing.T) {
+	inner := newFakeEmbedder(map[string][]float32{"hello": {1, 0}})
+	cached := NewCachedEmbedder(inner, NewInMemoryEmbeddingStore(), 0)
+	ctx := context.Background()
+	if _, err := cached.Embed(ctx, "hello"); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	// the batch sends the default task type, Embed without options sent none
+	if _, err := cached.EmbedBatch(ctx, []string{"hello"}); err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if inner.callCount("hello") != 2 {
+		t.Errorf("inner embedder called %d times, want 2", inner.callCount("hello"))
+	}
+	if _, err := cached.Embed(ctx, "hello", &EmbedOptions{}); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if inner.callCount("hello") != 2 {
+		t.Errorf("Embed() with empty options should reuse the batch entry, inner called %d times", inner.callCount("hello"))
+	}
+}
+
+type failingEmbeddingStore struct{}
+
+func (failingEmbeddingStore) SetEmbedding(ctx context.Context, hash string, vector []float32, ttl time.Duration) error {
+	return errors.New("down")
+}
+
+func (failingEmbeddingStore) GetEmbedding(ctx context.Context, hash string) ([]float32, error) {
+	return nil, errors.New("down")
+}
+
+func TestCachedEmbedderEmbed(t *testing.T) {
+	inner := newFakeEmbedder(map[string][]float32{"hello": {1, 0}})
+	cached := NewCachedEmbedder(inner, NewInMemoryEmbeddingStore(), 0)
+	ctx := context.Background()
+	for range 3 {
+		vectors, err := cached.Embed(ctx, "hello")
+		if err != nil || len(vectors) != 1 || vectors[0][0] != 1 {
+			t.Fatalf("Embed() = %v, %v", vectors, err)
+		}
+	}
+	if inner.callCount("hello") != 1 {
+		t.Errorf("inner embedder called %d times, want 1", inner.callCount("hello"))
+	}
+	if stats := cached.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 2 hits and 1 miss", stats)
+	}
+	if _, err := cached.Embed(ctx, "unknown"); err == nil {
+		t.Errorf("Embed() expected the inner error")
+	}
+}
+
+func TestCachedEmbedderStoreErrors(t *testing.T) {
+	inner := newFakeEmbedder(map[string][]float32{"hello": {1}})
+	cached := NewCachedEmbedder(inner, failingEmbeddingStore{}, 0)
+	if _, err := cached.Embed(context.Background(), "hello"); err != nil {
+		t.Fatalf("Embed() error = %v, a broken store should not fail the call", err)
+	}
+	if stats := cached.Stats(); stats.Errors != 2 {
+		t.Errorf("Stats().Errors = %d, want the read and write failures", stats.Errors)
+	}
+}
+
+func TestCachedEmbedderEmbedBatch(t *testing.T) {
+	inner := newFakeEmbedder(map[string][]float32{"a": {1}, "b": {2}, "c": {3}})
+	cached := NewCachedEmbedder(inner, NewInMemoryEmbeddingStore(), 0)
+	ctx := context.Background()
+	// empty options send the same config as the batch
+	if _, err := cached.Embed(ctx, "b", &EmbedOptions{}); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	results, err := cached.EmbedBatch(ctx, []string{"a", "b", "", "c", "missing"})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	want := []float32{1, 2, 0, 3, 0}
+	for index, result := range results {
+		switch index {
+		case 2:
+			if !errors.Is(result.Err, ErrEmbedEmptyText) {
+				t.Errorf("results[2].Err = %v, want ErrEmbedEmptyText", result.Err)
+			}
+		case 4:
+			if result.Err == nil {
+				t.Errorf("results[4] expected the inner error")
+			}
+		default:
+			if result.Err != nil || result.Vector[0] != want[index] {
+				t.Errorf("results[%d] = %+v, want %v", index, result, want[index])
+			}
+		}
+	}
+	if inner.callCount("b") != 1 {
+		t.Errorf("cached text b sent to the embedder again")
+	}
+	if _, err := cached.EmbedBatch(ctx, []string{"a", "c"}); err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if inner.callCount("a") != 1 || inner.callCount("c") != 1 {
+		t.Errorf("batch results were not cached")
+	}
+}
@@ -0,0 +1,216 @@
+package genaiclient
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/darwishdev/genaiclient/pkg/vectorstore"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// fakeLLM answers every request with respond and records the requests it got
+type fakeLLM struct {
+	mu       sync.Mutex
+	respond  func(req *model.LLMRequest) (*model.LLMResponse, error)
+	requests []*model.LLMRequest
+}
+
+func (f *fakeLLM) Name() string { return "fake" }
+
+func (f *fakeLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+		yield(f.respond(req))
+	}
+}
+
+func (f *fakeLLM) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+func (f *fakeLLM) lastRequest() *model.LLMRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		return nil
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+func textLLM(text string) *fakeLLM {
+	return &fakeLLM{respond: func(*model.LLMRequest) (*model.LLMResponse, error) {
+		return textResponse(text), nil
+	}}
+}
+
+func textResponse(text string) *model.LLMResponse {
+	return &model.LLMResponse{
+		Content:      genai.NewContentFromText(text, genai.RoleModel),
+		TurnComplete: true,
+		FinishReason: genai.FinishReasonStop,
+	}
+}
+
+func newFakeAgent(t *testing.T, name string, llm model.LLM, cfg ...llmagent.Config) GenAIAgentInterface {
+	t.Helper()
+	var finalCfg llmagent.Config
+	if len(cfg) > 0 {
+		finalCfg = cfg[0]
+	}
+	finalCfg.Name = name
+	finalCfg.Model = llm
+	a, err := NewGenAIAgentFromConfig("test_app", finalCfg, false)
+	if err != nil {
+		t.Fatalf("NewGenAIAgentFromConfig(%s) error = %v", name, err)
+	}
+	return a
+}
+
+// collectEvents drains a session stream failing the test on errors
+func collectEvents(t *testing.T, seq iter.Seq2[*session.Event, error]) []*session.Event {
+	t.Helper()
+	var events []*session.Event
+	for ev, err := range seq {
+		if err != nil {
+			t.Fatalf("stream error = %v", err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// fakeEmbedder returns the vector registered for a text and counts the calls
+type fakeEmbedder struct {
+	mu      sync.Mutex
+	vectors map[string][]float32
+	calls   map[string]int
+}
+
+func newFakeEmbedder(vectors map[string][]float32) *fakeEmbedder {
+	return &fakeEmbedder{vectors: vectors, calls: map[string]int{}}
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, text string, options ...*EmbedOptions) ([][]float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[text]++
+	vector, ok := f.vectors[text]
+	if !ok {
+		return nil, fmt.Errorf("no vector for %q", text)
+	}
+	return [][]float32{vector}, nil
+}
+
+func (f *fakeEmbedder) EmbedBatch(ctx context.Context, texts []string, options ...*EmbedBatchOptions) ([]*EmbedBatchResult, error) {
+	results := make([]*EmbedBatchResult, len(texts))
+	for index, text := range texts {
+		vectors, err := f.Embed(ctx, text)
+		results[index] = &EmbedBatchResult{Index: index, Err: err}
+		if err == nil {
+			results[index].Vector = vectors[0]
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeEmbedder) callCount(text string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[text]
+}
+
+// memoryVectorStore is a brute force vectorstore.VectorStoreInterface for tests
+type memoryVectorStore struct {
+	mu      sync.Mutex
+	records map[string]*vectorstore.Record
+	// failUpsert makes Upsert fail
+	failUpsert error
+}
+
+func newMemoryVectorStore() *memoryVectorStore {
+	return &memoryVectorStore{records: map[string]*vectorstore.Record{}}
+}
+
+func (m *memoryVectorStore) EnsureIndex(ctx context.Context) error { return nil }
+
+func (m *memoryVectorStore) Upsert(ctx context.Context, records ...*vectorstore.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failUpsert != nil {
+		return m.failUpsert
+	}
+	for _, record := range records {
+		copied := *record
+		m.records[record.ID] = &copied
+	}
+	return nil
+}
+
+func (m *memoryVectorStore) Get(ctx context.Context, id string) (*vectorstore.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[id]
+	if !ok {
+		return nil, vectorstore.ErrRecordNotFound
+	}
+	return record, nil
+}
+
+func (m *memoryVectorStore) Search(ctx context.Context, query []float32, options *vectorstore.SearchOptions) ([]*vectorstore.SearchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	opts := vectorstore.SearchOptions{TopK: 5}
+	if options != nil {
+		opts = *options
+	}
+	var results []*vectorstore.SearchResult
+	for _, record := range m.records {
+		if !vectorstore.MatchesFilter(record.Metadata, opts.Filter) {
+			continue
+		}
+		score, ok := vectorstore.Score(vectorstore.MetricCosine, query, record.Vector)
+		if !ok || opts.MinScore != nil && score < *opts.MinScore {
+			continue
+		}
+		results = append(results, &vectorstore.SearchResult{Record: record, Score: score})
+	}
+	return vectorstore.TopK(results, max(opts.TopK, 1)), nil
+}
+
+func (m *memoryVectorStore) DocumentRecordIDs(ctx context.Context, documentID string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id, record := range m.records {
+		if record.DocumentID == documentID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (m *memoryVectorStore) DeleteDocument(ctx context.Context, documentID string) error {
+	ids, _ := m.DocumentRecordIDs(ctx, documentID)
+	return m.Delete(ctx, ids...)
+}
+
+func (m *memoryVectorStore) Delete(ctx context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.records, id)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package genaiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+var ErrInvalidGenerationConfig = errors.New("invalid generation config")
+
+// -----------------------------------------------------------
+// agent level config
+// -----------------------------------------------------------
+
+// GenerateContentConfig maps our generation config into the one llmagent sends with every
+// request. Function tools carry no handler so they are rejected here, register them as
+// adk tools on llmagent.Config.Tools instead; built-in tools and the tool config are kept
+func GenerateContentConfig(cfg *genaiconfig.GenerationConfig) (*genai.GenerateContentConfig, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.Tools) > 0 || len(cfg.ToolGroups) > 0 {
+		return nil, fmt.Errorf("%w: function tools must be added as adk tools", ErrInvalidGenerationConfig)
+	}
+	genConfig, err := adapter.GeminiConfigFromGenerationConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGenerationConfig, err)
+	}
+	return genConfig, nil
+}
+
+// NewGeminiAgentFromAgentConfig builds a gemini agent from our stored agent config:
+// the ID names the agent, the persona describes it and DefaultGenerationConfig is
+// applied to every request
+func NewGeminiAgentFromAgentConfig(
+	appName string,
+	apiKey string,
+	cfg genaiconfig.AgentConfig,
+	beforeModelCallbacks []llmagent.BeforeModelCallback,
+	afterModelCallbacks []llmagent.AfterModelCallback,
+	enableTracer bool,
+	overrideCfg ...llmagent.Config,
+) (GenAIAgentInterface, error) {
+	finalCfg, err := agentConfigToLLMConfig(cfg, overrideCfg...)
+	if err != nil {
+		return nil, err
+	}
+	return NewGeminiAgent(appName, apiKey, cfg.DefaultModel, cfg.ID, cfg.Persona, cfg.SystemInstruction,
+		beforeModelCallbacks, afterModelCallbacks, enableTracer, finalCfg)
+}
+
+// NewStructuredAgentFromAgentConfig is NewStructuredAgent built from our stored agent config
+func NewStructuredAgentFromAgentConfig[TReq any, TRes any](
+	appName string,
+	apiKey string,
+	cfg genaiconfig.AgentConfig,
+	enableTracer bool,
+	overrideCfg ...llmagent.Config,
+) (GenAIStructuredAgentInterface[TReq, TRes], error) {
+	if cfg.DefaultGenerationConfig != nil && cfg.DefaultGenerationConfig.ResponseSchemaConfig != nil {
+		return nil, fmt.Errorf("%w: structured agents take their response schema from the output type", ErrInvalidGenerationConfig)
+	}
+	finalCfg, err := agentConfigToLLMConfig(cfg, overrideCfg...)
+	if err != nil {
+		return nil, err
+	}
+	return NewStructuredAgent[TReq, TRes](appName, apiKey, cfg.DefaultModel, cfg.ID, cfg.Persona, cfg.SystemInstruction,
+		enableTracer, finalCfg)
+}
+
+func agentConfigToLLMConfig(cfg genaiconfig.AgentConfig, overrideCfg ...llmagent.Config) (llmagent.Config, error) {
+	var finalCfg llmagent.Config
+	if len(overrideCfg) > 0 {
+		finalCfg = overrideCfg[0]
+	}
+	genConfig, err := GenerateContentConfig(cfg.DefaultGenerationConfig)
+	if err != nil {
+		return finalCfg, fmt.Errorf("agent %s: %w", cfg.ID, err)
+	}
+	if len(cfg.SafetySettings) > 0 {
+		if genConfig == nil {
+			genConfig = &genai.GenerateContentConfig{}
+		}
+		var generationSafety []genaiconfig.SafetySetting
+		if cfg.DefaultGenerationConfig != nil {
+			generationSafety = cfg.DefaultGenerationConfig.SafetySettings
+		}
+		safety, err := adapter.GeminiSafetySettings(cfg.SafetySettings, generationSafety)
+		if err != nil {
+			return finalCfg, fmt.Errorf("agent %s: %w: %w", cfg.ID, ErrInvalidGenerationConfig, err)
+		}
+		genConfig.SafetySettings = safety
+	}
+	finalCfg.GenerateContentConfig = mergeGenerateContentConfig(genConfig, finalCfg.GenerateContentConfig)
+	return finalCfg, nil
+}
+
+// mergeGenerateContentConfig lays the fields set on override over the stored config,
+// neither input is modified
+func mergeGenerateContentConfig(stored, override *genai.GenerateContentConfig) *genai.GenerateContentConfig {
+	if stored == nil {
+		return override
+	}
+	if override == nil {
+		return stored
+	}
+	merged := *stored
+	dst := reflect.ValueOf(&merged).Elem()
+	src := reflect.ValueOf(override).Elem()
+	for i := range src.NumField() {
+		if field := src.Field(i); dst.Field(i).CanSet() && !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+	return &merged
+}
+
+// -----------------------------------------------------------
+// session and message overrides
+// -----------------------------------------------------------
+
+type generationOverridesKey struct{}
+
+// WithGenerationConfig returns a context whose requests use cfg on top of the agent and
+// session config, only the fields set on cfg are changed
+func WithGenerationConfig(ctx context.Context, cfg *genaiconfig.GenerationConfig) context.Context {
+	if cfg == nil {
+		return ctx
+	}
+	existing, _ := ctx.Value(generationOverridesKey{}).([]*genaiconfig.GenerationConfig)
+	overrides := append(append([]*genaiconfig.GenerationConfig{}, existing...), cfg)
+	return context.WithValue(ctx, generationOverridesKey{}, overrides)
+}
+
+// withBaseGenerationConfig puts cfg below the overrides already on the context
+func withBaseGenerationConfig(ctx context.Context, cfg *genaiconfig.GenerationConfig) context.Context {
+	if cfg == nil {
+		return ctx
+	}
+	existing, _ := ctx.Value(generationOverridesKey{}).([]*genaiconfig.GenerationConfig)
+	overrides := append([]*genaiconfig.GenerationConfig{cfg}, existing...)
+	return context.WithValue(ctx, generationOverridesKey{}, overrides)
+}
+
+// generationOverridesCallback applies the session and message overrides, it runs before
+// the other callbacks so caches see the final request
+func generationOverridesCallback(ctx agent.CallbackContext, llmRequest *model.LLMRequest) (*model.LLMResponse, error) {
+	overrides, _ := ctx.Value(generationOverridesKey{}).([]*genaiconfig.GenerationConfig)
+	for _, override := range overrides {
+		if err := applyGenerationOverride(llmRequest, override); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func applyGenerationOverride(llmRequest *model.LLMRequest, override *genaiconfig.GenerationConfig) error {
+	genConfig, err := GenerateContentConfig(override)
+	if err != nil {
+		return err
+	}
+	if llmRequest.Config == nil {
+		llmRequest.Config = &genai.GenerateContentConfig{}
+	}
+	target := llmRequest.Config
+	if genConfig.Temperature != nil {
+		target.Temperature = genConfig.Temperature
+	}
+	if genConfig.TopP != nil {
+		target.TopP = genConfig.TopP
+	}
+	if genConfig.TopK != nil {
+		target.TopK = genConfig.TopK
+	}
+	if genConfig.MaxOutputTokens != 0 {
+		target.MaxOutputTokens = genConfig.MaxOutputTokens
+	}
+	if genConfig.StopSequences != nil {
+		target.StopSequences = genConfig.StopSequences
+	}
+	if genConfig.ToolConfig != nil {
+		target.ToolConfig = genConfig.ToolConfig
+	}
+	if genConfig.ThinkingConfig != nil {
+		thinking := genai.ThinkingConfig{}
+		if target.ThinkingConfig != nil {
+			thinking = *target.ThinkingConfig
+		}
+		if override.ThinkingBudget != nil {
+			thinking.ThinkingBudget = override.ThinkingBudget
+		}
+		if override.IncludeThoughts != nil {
+			thinking.IncludeThoughts = *override.IncludeThoughts
+		}
+		target.ThinkingConfig = &thinking
+	}
+	if override.ResponseSchemaConfig != nil {
+		target.ResponseMIMEType = genConfig.ResponseMIMEType
+		target.ResponseSchema = genConfig.ResponseSchema
+		target.ResponseJsonSchema = genConfig.ResponseJsonSchema
+	}
+	for _, setting := range genConfig.SafetySettings {
+		target.SafetySettings = replaceSafetySetting(target.SafetySettings, setting)
+	}
+	for _, tool := range genConfig.Tools {
+		if !containsBuiltinTool(target.Tools, tool) {
+			target.Tools = append(target.Tools, tool)
+		}
+	}
+	return nil
+}
+
+// replaceSafetySetting swaps the setting of the same category or appends it
+func replaceSafetySetting(settings []*genai.SafetySetting, setting *genai.SafetySetting) []*genai.SafetySetting {
+	for i, existing := range settings {
+		if existing.Category == setting.Category {
+			settings[i] = setting
+			return settings
+		}
+	}
+	return append(settings, setting)
+}
+
+func containsBuiltinTool(tools []*genai.Tool, builtin *genai.Tool) bool {
+	for _, tool := range tools {
+		if tool.GoogleSearch != nil && builtin.GoogleSearch != nil ||
+			tool.CodeExecution != nil && builtin.CodeExecution != nil ||
+			tool.URLContext != nil && builtin.URLContext != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// withGenerationOverrides puts the overrides callback first
+func withGenerationOverrides(callbacks []llmagent.BeforeModelCallback) []llmagent.BeforeModelCallback {
+	return append([]llmagent.BeforeModelCallback{generationOverridesCallback}, callbacks...)
+}
@@ -0,0 +1,247 @@
+package genaiclient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestGenerationConfigPrecedence(t *testing.T) {
+	llm := textLLM("ok")
+	a := newFakeAgent(t, "qa", llm, llmagent.Config{GenerateContentConfig: &genai.GenerateContentConfig{
+		Temperature:     genai.Ptr[float32](0.1),
+		TopK:            genai.Ptr[float32](5),
+		MaxOutputTokens: 50,
+	}})
+	sess := a.NewInMemorySession(context.Background(), "user")
+	err := sess.SetGenerationConfig(&genaiconfig.GenerationConfig{Temperature: genai.Ptr[float32](0.5), MaxOutputTokens: 100})
+	if err != nil {
+		t.Fatalf("SetGenerationConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		overrides []*genaiconfig.GenerationConfig
+		wantTemp  float32
+		wantTopP  *float32
+	}{
+		{name: "session over agent", wantTemp: 0.5},
+		{
+			name:      "message over session",
+			overrides: []*genaiconfig.GenerationConfig{{Temperature: genai.Ptr[float32](0.9), TopP: genai.Ptr[float32](0.8)}},
+			wantTemp:  0.9,
+			wantTopP:  genai.Ptr[float32](0.8),
+		},
+		{
+			name: "later message override wins",
+			overrides: []*genaiconfig.GenerationConfig{
+				{Temperature: genai.Ptr[float32](0.9)},
+				{Temperature: genai.Ptr[float32](0.7)},
+			},
+			wantTemp: 0.7,
+		},
+		{name: "message override does not stick", wantTemp: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, override := range tt.overrides {
+				ctx = WithGenerationConfig(ctx, override)
+			}
+			collectEvents(t, sess.Send(ctx, "hi"))
+			cfg := llm.lastRequest().Config
+			if cfg.Temperature == nil || *cfg.Temperature != tt.wantTemp {
+				t.Errorf("temperature = %v, want %v", cfg.Temperature, tt.wantTemp)
+			}
+			if cfg.TopK == nil || *cfg.TopK != 5 {
+				t.Errorf("topK = %v, want the agent value", cfg.TopK)
+			}
+			if cfg.MaxOutputTokens != 100 {
+				t.Errorf("maxOutputTokens = %d, want the session value", cfg.MaxOutputTokens)
+			}
+			if tt.wantTopP == nil && cfg.TopP != nil || tt.wantTopP != nil && (cfg.TopP == nil || *cfg.TopP != *tt.wantTopP) {
+				t.Errorf("topP = %v, want %v", cfg.TopP, tt.wantTopP)
+			}
+		})
+	}
+}
+
+func TestAgentConfigOverridePrecedence(t *testing.T) {
+	cfg := genaiconfig.AgentConfig{
+		ID: "qa",
+		DefaultGenerationConfig: &genaiconfig.GenerationConfig{
+			Temperature:     genai.Ptr[float32](0.1),
+			MaxOutputTokens: 100,
+		},
+	}
+	override := &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0.9), TopK: genai.Ptr[float32](3)}
+
+	tests := []struct {
+		name     string
+		override []llmagent.Config
+		wantTemp float32
+		wantTopK *float32
+	}{
+		{name: "stored config only", wantTemp: 0.1},
+		{name: "no generate content config on override", override: []llmagent.Config{{Description: "qa agent"}}, wantTemp: 0.1},
+		{
+			name:     "override fields win",
+			override: []llmagent.Config{{GenerateContentConfig: override}},
+			wantTemp: 0.9,
+			wantTopK: genai.Ptr[float32](3),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finalCfg, err := agentConfigToLLMConfig(cfg, tt.override...)
+			if err != nil {
+				t.Fatalf("agentConfigToLLMConfig() error = %v", err)
+			}
+			got := finalCfg.GenerateContentConfig
+			if got == nil {
+				t.Fatal("generate content config = nil")
+			}
+			if got.Temperature == nil || *got.Temperature != tt.wantTemp {
+				t.Errorf("temperature = %v, want %v", got.Temperature, tt.wantTemp)
+			}
+			if got.MaxOutputTokens != 100 {
+				t.Errorf("maxOutputTokens = %d, want the stored value", got.MaxOutputTokens)
+			}
+			if tt.wantTopK == nil && got.TopK != nil || tt.wantTopK != nil && (got.TopK == nil || *got.TopK != *tt.wantTopK) {
+				t.Errorf("topK = %v, want %v", got.TopK, tt.wantTopK)
+			}
+		})
+	}
+	if *override.Temperature != 0.9 || override.MaxOutputTokens != 0 {
+		t.Errorf("caller override was modified: %+v", override)
+	}
+}
+
+func TestApplyGenerationOverride(t *testing.T) {
+	budget := int32(128)
+	noThoughts := false
+	tests := []struct {
+		name     string
+		base     *genai.GenerateContentConfig
+		override *genaiconfig.GenerationConfig
+		wantErr  error
+		check    func(t *testing.T, cfg *genai.GenerateContentConfig)
+	}{
+		{
+			name: "safety replaced per category",
+			base: &genai.GenerateContentConfig{SafetySettings: []*genai.SafetySetting{
+				{Category: genai.HarmCategoryHarassment, Threshold: genai.HarmBlockThresholdBlockLowAndAbove},
+				{Category: genai.HarmCategoryHateSpeech, Threshold: genai.HarmBlockThresholdBlockLowAndAbove},
+			}},
+			override: &genaiconfig.GenerationConfig{SafetySettings: []genaiconfig.SafetySetting{
+				{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"},
+			}},
+			check: func(t *testing.T, cfg *genai.GenerateContentConfig) {
+				if len(cfg.SafetySettings) != 2 || cfg.SafetySettings[0].Threshold != genai.HarmBlockThresholdBlockOnlyHigh ||
+					cfg.SafetySettings[1].Threshold != genai.HarmBlockThresholdBlockLowAndAbove {
+					t.Errorf("safety settings = %+v", cfg.SafetySettings)
+				}
+			},
+		},
+		{
+			name:     "built-in tools added once",
+			base:     &genai.GenerateContentConfig{Tools: []*genai.Tool{{GoogleSearch: &genai.GoogleSearch{}}}},
+			override: &genaiconfig.GenerationConfig{BuiltinTools: &genaiconfig.BuiltinTools{GoogleSearch: true, CodeExecution: true}},
+			check: func(t *testing.T, cfg *genai.GenerateContentConfig) {
+				if len(cfg.Tools) != 2 || cfg.Tools[1].CodeExecution == nil {
+					t.Errorf("tools = %+v", cfg.Tools)
+				}
+			},
+		},
+		{
+			name:     "thinking merged",
+			base:     &genai.GenerateContentConfig{ThinkingConfig: &genai.ThinkingConfig{IncludeThoughts: true}},
+			override: &genaiconfig.GenerationConfig{ThinkingBudget: &budget},
+			check: func(t *testing.T, cfg *genai.GenerateContentConfig) {
+				if !cfg.ThinkingConfig.IncludeThoughts || cfg.ThinkingConfig.ThinkingBudget == nil || *cfg.ThinkingConfig.ThinkingBudget != budget {
+					t.Errorf("thinking config = %+v", cfg.ThinkingConfig)
+				}
+			},
+		},
+		{
+			name:     "thoughts turned off",
+			base:     &genai.GenerateContentConfig{ThinkingConfig: &genai.ThinkingConfig{IncludeThoughts: true, ThinkingBudget: &budget}},
+			override: &genaiconfig.GenerationConfig{IncludeThoughts: &noThoughts},
+			check: func(t *testing.T, cfg *genai.GenerateContentConfig) {
+				if cfg.ThinkingConfig.IncludeThoughts || cfg.ThinkingConfig.ThinkingBudget == nil {
+					t.Errorf("thinking config = %+v, want thoughts off and the budget kept", cfg.ThinkingConfig)
+				}
+			},
+		},
+		{
+			name:     "nil request config",
+			override: &genaiconfig.GenerationConfig{StopSequences: []string{"END"}},
+			check: func(t *testing.T, cfg *genai.GenerateContentConfig) {
+				if len(cfg.StopSequences) != 1 {
+					t.Errorf("stop sequences = %v", cfg.StopSequences)
+				}
+			},
+		},
+		{
+			name:     "function tools rejected",
+			override: &genaiconfig.GenerationConfig{Tools: []*genaiconfig.Tool{{Name: "x"}}},
+			wantErr:  ErrInvalidGenerationConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &model.LLMRequest{Config: tt.base}
+			err := applyGenerationOverride(req, tt.override)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("applyGenerationOverride() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, req.Config)
+			}
+		})
+	}
+}
+
+func TestStructuredSessionRejectsResponseSchema(t *testing.T) {
+	structured := &GenAIStructuredAgent[string, groundedAnswer]{base: newFakeAgent(t, "qa", textLLM("{}")), outputKey: "result"}
+	sess := structured.NewInMemorySession(context.Background(), "user")
+	err := sess.SetGenerationConfig(&genaiconfig.GenerationConfig{
+		ResponseSchemaConfig: &genaiconfig.SchemaConfig{SchemaJSON: map[string]any{"type": "object"}},
+	})
+	if !errors.Is(err, ErrInvalidGenerationConfig) {
+		t.Errorf("SetGenerationConfig() error = %v, want ErrInvalidGenerationConfig", err)
+	}
+	if err := sess.SetGenerationConfig(&genaiconfig.GenerationConfig{Temperature: genai.Ptr[float32](0.2)}); err != nil {
+		t.Errorf("SetGenerationConfig() error = %v", err)
+	}
+}
+
+func TestNewChatSessionAppliesGenerationConfig(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { rdb.Close() })
+	llm := textLLM("ok")
+	chat := &genaiconfig.ChatConfig{
+		ID:               "chat-" + t.Name(),
+		UserID:           "user",
+		GenerationConfig: &genaiconfig.GenerationConfig{Temperature: genai.Ptr[float32](0.3)},
+	}
+	sess, err := NewChatSession(context.Background(), newFakeAgent(t, "qa", llm), chat, rdb)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+	collectEvents(t, sess.Send(context.Background(), "hi"))
+	if temp := llm.lastRequest().Config.Temperature; temp == nil || *temp != 0.3 {
+		t.Errorf("temperature = %v, want the chat value", temp)
+	}
+}
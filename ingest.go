@@ -0,0 +1,261 @@
+package genaiclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/darwishdev/genaiclient/pkg/chunker"
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"github.com/darwishdev/genaiclient/pkg/vectorstore"
+)
+
+var (
+	ErrIngestEmptyFile   = errors.New("file has no contents to ingest")
+	ErrIngestReadFailed  = errors.New("failed to read file for ingestion")
+	ErrIngestStoreFailed = errors.New("failed to store ingested chunks")
+)
+
+// metadata keys every ingested chunk carries so answers can cite their source
+const (
+	MetadataSource     = "source"
+	MetadataName       = "name"
+	MetadataMIMEType   = "mimeType"
+	MetadataChunkIndex = "chunkIndex"
+	MetadataStart      = "start"
+	MetadataEnd        = "end"
+	// MetadataOffsets tells what start and end index into, OffsetsSource or OffsetsExtracted
+	MetadataOffsets = "offsets"
+)
+
+const (
+	// OffsetsSource offsets are byte offsets into the original file (plain text, markdown)
+	OffsetsSource = "source"
+	// OffsetsExtracted offsets index the extracted text, not the file (e.g. HTML where
+	// tags are stripped), cite them together with the same extractor output
+	OffsetsExtracted = "extracted"
+)
+
+// IngestOptions configures the ingestion pipeline
+type IngestOptions struct {
+	// Splitter defaults to a recursive splitter of 512 tokens with 64 tokens overlap
+	Splitter chunker.Splitter
+	// Embed is applied to every chunk batch, TaskType defaults to RETRIEVAL_DOCUMENT
+	Embed *EmbedBatchOptions
+	// DocumentID derives the document id from the file, defaults to DefaultDocumentID
+	DocumentID func(file genaiconfig.FileConfig) string
+}
+
+// IngestResult reports what was stored for one file
+type IngestResult struct {
+	DocumentID string
+	Chunks     int
+	Stored     int
+	// Errors holds the per chunk embedding failures. When there are any nothing is
+	// stored and the previous version of the document is kept
+	Errors []error
+}
+
+// GenAIIngestorInterface turns files into embedded chunks inside a vector store
+type GenAIIngestorInterface interface {
+	Ingest(ctx context.Context, files ...genaiconfig.FileConfig) ([]*IngestResult, error)
+}
+
+type Ingestor struct {
+	embedder GenAIEmbedderInterface
+	store    vectorstore.VectorStoreInterface
+	opts     IngestOptions
+}
+
+// NewIngestor creates the ingestion pipeline: extract text -> split -> embed in batches -> store
+func NewIngestor(embedder GenAIEmbedderInterface, store vectorstore.VectorStoreInterface, options ...IngestOptions) (GenAIIngestorInterface, error) {
+	var opts IngestOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Splitter == nil {
+		splitter, err := chunker.NewRecursiveSplitter(512, 64)
+		if err != nil {
+			return nil, err
+		}
+		opts.Splitter = splitter
+	}
+	var embed EmbedBatchOptions
+	if opts.Embed != nil {
+		embed = *opts.Embed
+	}
+	if embed.TaskType == "" {
+		embed.TaskType = "RETRIEVAL_DOCUMENT"
+	}
+	opts.Embed = &embed
+	if opts.DocumentID == nil {
+		opts.DocumentID = DefaultDocumentID
+	}
+	return &Ingestor{
+		embedder: embedder,
+		store:    store,
+		opts:     opts,
+	}, nil
+}
+
+// DefaultDocumentID uses the file name, then the path, then the hash of the contents
+func DefaultDocumentID(file genaiconfig.FileConfig) string {
+	if file.Name != "" {
+		return file.Name
+	}
+	if file.Path != "" {
+		return file.Path
+	}
+	sum := sha256.Sum256(file.Contents)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Ingest processes every file, a file level failure stops the call while chunk
+// embedding failures are collected on the file result
+func (i *Ingestor) Ingest(ctx context.Context, files ...genaiconfig.FileConfig) ([]*IngestResult, error) {
+	results := make([]*IngestResult, 0, len(files))
+	for _, file := range files {
+		result, err := i.ingestFile(ctx, file)
+		if err != nil {
+			return results, fmt.Errorf("ingesting %s: %w", DefaultDocumentID(file), err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (i *Ingestor) ingestFile(ctx context.Context, file genaiconfig.FileConfig) (*IngestResult, error) {
+	data, err := readFileConfig(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	mimeType := chunker.DetectMIMEType(file.MIMEType, file.Path)
+	text, err := chunker.ExtractText(mimeType, data)
+	if err != nil {
+		return nil, err
+	}
+	offsets := OffsetsSource
+	if text != string(data) {
+		offsets = OffsetsExtracted
+	}
+	documentID := i.opts.DocumentID(file)
+	result := &IngestResult{DocumentID: documentID}
+	chunks := i.opts.Splitter.Split(text)
+	result.Chunks = len(chunks)
+	if len(chunks) == 0 {
+		return result, nil
+	}
+
+	texts := make([]string, len(chunks))
+	for index, chunk := range chunks {
+		texts[index] = chunk.Text
+	}
+	embedded, err := i.embedder.EmbedBatch(ctx, texts, i.opts.Embed)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*vectorstore.Record, 0, len(chunks))
+	for _, res := range embedded {
+		chunk := chunks[res.Index]
+		if res.Err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("chunk %d: %w", chunk.Index, res.Err))
+			continue
+		}
+		records = append(records, &vectorstore.Record{
+			ID:         fmt.Sprintf("%s#%d", documentID, chunk.Index),
+			DocumentID: documentID,
+			Content:    chunk.Text,
+			Metadata:   chunkMetadata(file, mimeType, offsets, chunk),
+			Vector:     res.Vector,
+		})
+	}
+
+	// a partial version would mix with the stored one, keep the stored version as it is
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+	previous, err := i.store.DocumentRecordIDs(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIngestStoreFailed, err)
+	}
+	if err := i.store.Upsert(ctx, records...); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIngestStoreFailed, err)
+	}
+	result.Stored = len(records)
+	// only now drop the chunks of the previous version the new one no longer has
+	current := make(map[string]bool, len(records))
+	for _, record := range records {
+		current[record.ID] = true
+	}
+	stale := make([]string, 0, len(previous))
+	for _, id := range previous {
+		if !current[id] {
+			stale = append(stale, id)
+		}
+	}
+	if err := i.store.Delete(ctx, stale...); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIngestStoreFailed, err)
+	}
+	return result, nil
+}
+
+func chunkMetadata(file genaiconfig.FileConfig, mimeType string, offsets string, chunk chunker.Chunk) map[string]string {
+	metadata := make(map[string]string, len(file.Metadata)+7)
+	for k, v := range file.Metadata {
+		metadata[k] = fmt.Sprint(v)
+	}
+	metadata[MetadataSource] = file.Path
+	metadata[MetadataName] = file.Name
+	if file.Name == "" && file.Path != "" {
+		metadata[MetadataName] = filepath.Base(file.Path)
+	}
+	metadata[MetadataMIMEType] = mimeType
+	metadata[MetadataChunkIndex] = strconv.Itoa(chunk.Index)
+	metadata[MetadataStart] = strconv.Itoa(chunk.Start)
+	metadata[MetadataEnd] = strconv.Itoa(chunk.End)
+	metadata[MetadataOffsets] = offsets
+	return metadata
+}
+
+// readFileConfig returns the inline contents, or reads the local / remote path
+func readFileConfig(ctx context.Context, file genaiconfig.FileConfig) ([]byte, error) {
+	if len(file.Contents) > 0 {
+		return file.Contents, nil
+	}
+	if file.Path == "" {
+		return nil, ErrIngestEmptyFile
+	}
+	if isRemotePath(file.Path) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.Path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrIngestReadFailed, err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrIngestReadFailed, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%w: %s returned %s", ErrIngestReadFailed, file.Path, resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}
+	data, err := os.ReadFile(filepath.Clean(file.Path))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIngestReadFailed, err)
+	}
+	return data, nil
+}
+
+func isRemotePath(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
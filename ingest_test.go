@@ -0,0 +1,169 @@
+package genaiclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/darwishdev/genaiclient/pkg/chunker"
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+)
+
+// wordEmbedder embeds any text except the ones containing a failing word
+type wordEmbedder struct {
+	fail string
+}
+
+func (w *wordEmbedder) Embed(ctx context.Context, text string, options ...*EmbedOptions) ([][]float32, error) {
+	results, _ := w.EmbedBatch(ctx, []string{text})
+	return [][]float32{results[0].Vector}, results[0].Err
+}
+
+func (w *wordEmbedder) EmbedBatch(ctx context.Context, texts []string, options ...*EmbedBatchOptions) ([]*EmbedBatchResult, error) {
+	results := make([]*EmbedBatchResult, len(texts))
+	for index, text := range texts {
+		results[index] = &EmbedBatchResult{Index: index, Vector: []float32{float32(len(text)), 1}}
+		if w.fail != "" && strings.Contains(text, w.fail) {
+			results[index] = &EmbedBatchResult{Index: index, Err: errors.New("quota exceeded")}
+		}
+	}
+	return results, nil
+}
+
+func newTestIngestor(t *testing.T, embedder GenAIEmbedderInterface, store *memoryVectorStore) GenAIIngestorInterface {
+	t.Helper()
+	splitter, err := chunker.NewFixedTokenSplitter(2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingestor, err := NewIngestor(embedder, store, IngestOptions{Splitter: splitter})
+	if err != nil {
+		t.Fatalf("NewIngestor() error = %v", err)
+	}
+	return ingestor
+}
+
+func TestIngestReplacesDocument(t *testing.T) {
+	store := newMemoryVectorStore()
+	embedder := &wordEmbedder{}
+	ingestor := newTestIngestor(t, embedder, store)
+	ctx := context.Background()
+	file := genaiconfig.FileConfig{Name: "guide.md", Contents: []byte("one two three four five six")}
+	if _, err := ingestor.Ingest(ctx, file); err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if ids, _ := store.DocumentRecordIDs(ctx, "guide.md"); len(ids) != 3 {
+		t.Fatalf("stored %v, want 3 chunks", ids)
+	}
+
+	file.Contents = []byte("uno dos tres")
+	results, err := ingestor.Ingest(ctx, file)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	ids, _ := store.DocumentRecordIDs(ctx, "guide.md")
+	if results[0].Stored != 2 || len(ids) != 2 {
+		t.Fatalf("stored %v (%d), want the 2 chunks of the new version", ids, results[0].Stored)
+	}
+	record, _ := store.Get(ctx, "guide.md#0")
+	if record.Content != "uno dos" || record.Metadata[MetadataOffsets] != OffsetsSource {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestIngestKeepsPreviousVersionOnErrors(t *testing.T) {
+	store := newMemoryVectorStore()
+	embedder := &wordEmbedder{}
+	ingestor := newTestIngestor(t, embedder, store)
+	ctx := context.Background()
+	file := genaiconfig.FileConfig{Name: "guide.md", Contents: []byte("one two three four")}
+	if _, err := ingestor.Ingest(ctx, file); err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+
+	embedder.fail = "broken"
+	file.Contents = []byte("new text broken chunk")
+	results, err := ingestor.Ingest(ctx, file)
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if len(results[0].Errors) != 1 || results[0].Stored != 0 {
+		t.Errorf("result = %+v, want one error and nothing stored", results[0])
+	}
+	record, err := store.Get(ctx, "guide.md#0")
+	if err != nil || record.Content != "one two" {
+		t.Errorf("previous version was touched: %+v, %v", record, err)
+	}
+}
+
+func TestIngestStoreFailureKeepsPreviousVersion(t *testing.T) {
+	store := newMemoryVectorStore()
+	ingestor := newTestIngestor(t, &wordEmbedder{}, store)
+	ctx := context.Background()
+	file := genaiconfig.FileConfig{Name: "guide.md", Contents: []byte("one two three four")}
+	if _, err := ingestor.Ingest(ctx, file); err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	store.failUpsert = errors.New("down")
+	file.Contents = []byte("x")
+	if _, err := ingestor.Ingest(ctx, file); !errors.Is(err, ErrIngestStoreFailed) {
+		t.Fatalf("Ingest() error = %v, want ErrIngestStoreFailed", err)
+	}
+	if ids, _ := store.DocumentRecordIDs(ctx, "guide.md"); len(ids) != 2 {
+		t.Errorf("stored %v, want the previous 2 chunks", ids)
+	}
+}
+
+func TestIngestHTMLOffsets(t *testing.T) {
+	store := newMemoryVectorStore()
+	ingestor := newTestIngestor(t, &wordEmbedder{}, store)
+	ctx := context.Background()
+	_, err := ingestor.Ingest(ctx, genaiconfig.FileConfig{
+		Name:     "page",
+		MIMEType: "text/html",
+		Contents: []byte("<html><body><p>hello world</p></body></html>"),
+	})
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	record, err := store.Get(ctx, "page#0")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if record.Metadata[MetadataOffsets] != OffsetsExtracted || record.Metadata[MetadataStart] != "0" {
+		t.Errorf("metadata = %v, want offsets into the extracted text", record.Metadata)
+	}
+}
+
+func TestIngestPDF(t *testing.T) {
+	store := newMemoryVectorStore()
+	ingestor := newTestIngestor(t, &wordEmbedder{}, store)
+	ctx := context.Background()
+	pdf := "%PDF-1.4\n" +
+		"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+		"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n" +
+		"3 0 obj << /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >> endobj\n" +
+		"4 0 obj << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> endobj\n" +
+		"5 0 obj << /Length 44 >>\nstream\nBT /F1 12 Tf 72 720 Td (hello world) Tj ET\nendstream\nendobj\n" +
+		"trailer << /Root 1 0 R >>\n%%EOF\n"
+	results, err := ingestor.Ingest(ctx, genaiconfig.FileConfig{Path: "report.pdf", Contents: []byte(pdf)})
+	if err != nil {
+		t.Fatalf("Ingest() error = %v", err)
+	}
+	if results[0].Stored != 1 {
+		t.Fatalf("Stored = %d, want 1", results[0].Stored)
+	}
+	record, err := store.Get(ctx, results[0].DocumentID+"#0")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if record.Content != "hello world" || record.Metadata[MetadataOffsets] != OffsetsExtracted {
+		t.Errorf("record = %q %v, want the pdf text with extracted offsets", record.Content, record.Metadata)
+	}
+
+	_, err = ingestor.Ingest(ctx, genaiconfig.FileConfig{Path: "broken.pdf", Contents: []byte("%PDF-1.7")})
+	if !errors.Is(err, chunker.ErrInvalidPDF) {
+		t.Errorf("Ingest() error = %v, want ErrInvalidPDF", err)
+	}
+}
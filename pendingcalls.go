@@ -0,0 +1,64 @@
+package genaiclient
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/adk/agent"
+)
+
+// pendingModelCallTTL bounds how long a before model callback entry waits for its
+// after callback
+const pendingModelCallTTL = 10 * time.Minute
+
+// pendingModelCalls carries state from a before model callback to the after callback
+// of the same model call. The after callback never runs when a later before callback
+// short-circuits or the caller stops reading the stream, so entries are dropped by
+// the next call of the same invocation and expire after ttl.
+type pendingModelCalls[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]pendingModelCall[T]
+}
+
+type pendingModelCall[T any] struct {
+	value     T
+	createdAt time.Time
+}
+
+func newPendingModelCalls[T any](ttl time.Duration) *pendingModelCalls[T] {
+	return &pendingModelCalls[T]{ttl: ttl, entries: make(map[string]pendingModelCall[T])}
+}
+
+func pendingModelCallKey(ctx agent.CallbackContext) string {
+	return ctx.InvocationID() + ":" + ctx.AgentName()
+}
+
+// put stores the value of the current model call and sweeps expired entries
+func (p *pendingModelCalls[T]) put(ctx agent.CallbackContext, value T) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, entry := range p.entries {
+		if now.Sub(entry.createdAt) > p.ttl {
+			delete(p.entries, key)
+		}
+	}
+	p.entries[pendingModelCallKey(ctx)] = pendingModelCall[T]{value: value, createdAt: now}
+}
+
+// take removes and returns the value of the current model call
+func (p *pendingModelCalls[T]) take(ctx agent.CallbackContext) (T, bool) {
+	key := pendingModelCallKey(ctx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.entries[key]
+	delete(p.entries, key)
+	return entry.value, ok
+}
+
+func (p *pendingModelCalls[T]) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
@@ -0,0 +1,44 @@
+package genaiclient
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/adk/agent"
+)
+
+type fakeCallbackContext struct {
+	agent.CallbackContext
+	invocationID string
+	agentName    string
+}
+
+func (c fakeCallbackContext) InvocationID() string { return c.invocationID }
+func (c fakeCallbackContext) AgentName() string    { return c.agentName }
+
+func TestPendingModelCalls(t *testing.T) {
+	pending := newPendingModelCalls[string](time.Hour)
+	first := fakeCallbackContext{invocationID: "inv-1", agentName: "qa"}
+	other := fakeCallbackContext{invocationID: "inv-1", agentName: "writer"}
+	pending.put(first, "a")
+	pending.put(other, "b")
+	if got, ok := pending.take(first); !ok || got != "a" {
+		t.Errorf("take() = %q, %v, want a", got, ok)
+	}
+	if _, ok := pending.take(first); ok {
+		t.Errorf("take() returned an entry twice")
+	}
+	if pending.len() != 1 {
+		t.Errorf("len() = %d, want 1", pending.len())
+	}
+}
+
+func TestPendingModelCallsExpire(t *testing.T) {
+	pending := newPendingModelCalls[string](time.Millisecond)
+	pending.put(fakeCallbackContext{invocationID: "abandoned"}, "a")
+	time.Sleep(5 * time.Millisecond)
+	pending.put(fakeCallbackContext{invocationID: "next"}, "b")
+	if pending.len() != 1 {
+		t.Errorf("len() = %d, want the abandoned entry swept", pending.len())
+	}
+}
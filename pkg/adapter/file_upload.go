@@ -0,0 +1,147 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// DefaultInlineFileLimit is the biggest local file sent inline, larger files go through
+// the Files API when an uploader is configured
+const DefaultInlineFileLimit int64 = 20 << 20
+
+// DefaultFilePollInterval is how often an uploaded file still being processed is checked
+const DefaultFilePollInterval = 2 * time.Second
+
+var (
+	ErrUnsupportedFileURI = errors.New("unsupported file uri")
+	ErrFileProcessing     = errors.New("uploaded file processing failed")
+)
+
+// FileUploader is the part of the gemini Files API used for large files,
+// client.Files satisfies it
+type FileUploader interface {
+	Upload(ctx context.Context, r io.Reader, config *genai.UploadFileConfig) (*genai.File, error)
+	Get(ctx context.Context, name string, config *genai.GetFileConfig) (*genai.File, error)
+}
+
+type FileOptions struct {
+	// Uploader receives local files bigger than InlineLimit, without it they are inlined
+	Uploader FileUploader
+	// InlineLimit defaults to DefaultInlineFileLimit
+	InlineLimit int64
+	// PollInterval defaults to DefaultFilePollInterval
+	PollInterval time.Duration
+}
+
+func (o *FileOptions) inlineLimit() int64 {
+	if o == nil || o.InlineLimit <= 0 {
+		return DefaultInlineFileLimit
+	}
+	return o.InlineLimit
+}
+
+func (o *FileOptions) pollInterval() time.Duration {
+	if o == nil || o.PollInterval <= 0 {
+		return DefaultFilePollInterval
+	}
+	return o.PollInterval
+}
+
+// uploadFile streams r to the Files API and waits until the file can be used in a prompt
+func uploadFile(ctx context.Context, opts *FileOptions, r io.Reader, displayName, mimeType string) (*genai.File, error) {
+	file, err := opts.Uploader.Upload(ctx, r, &genai.UploadFileConfig{
+		DisplayName: displayName,
+		MIMEType:    mimeType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload %q: %w", displayName, err)
+	}
+	ticker := time.NewTicker(opts.pollInterval())
+	defer ticker.Stop()
+	for file.State == genai.FileStateProcessing {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		file, err = opts.Uploader.Get(ctx, file.Name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check uploaded file %q: %w", displayName, err)
+		}
+	}
+	if file.State == genai.FileStateFailed {
+		if file.Error != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrFileProcessing, displayName, file.Error.Message)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrFileProcessing, displayName)
+	}
+	return file, nil
+}
+
+// fileURIScheme returns the scheme of a uri path, "" for local paths
+func fileURIScheme(p string) string {
+	scheme, _, found := strings.Cut(p, "://")
+	if !found || scheme == "" || strings.ContainsAny(scheme, `/\.`) {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
+
+// mimeTypeFromName guesses the mime type from the extension of a path or url
+func mimeTypeFromName(name string) string {
+	if u, err := url.Parse(name); err == nil && u.Scheme != "" {
+		name = path.Base(u.Path)
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return ""
+	}
+	if mimeType, ok := extraMIMETypes[ext]; ok {
+		return mimeType
+	}
+	mediaType, _, err := mime.ParseMediaType(mime.TypeByExtension(ext))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// extraMIMETypes covers the extensions gemini accepts that the system table often misses
+var extraMIMETypes = map[string]string{
+	".md":   "text/markdown",
+	".csv":  "text/csv",
+	".py":   "text/x-python",
+	".go":   "text/plain",
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".m4a":  "audio/mp4",
+	".mp4":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".webp": "image/webp",
+}
+
+// sniffMIMEType detects the mime type from the first bytes of the content
+func sniffMIMEType(head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
@@ -0,0 +1,179 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"google.golang.org/genai"
+)
+
+func TestFileConfigToPartMIMEAndUpload(t *testing.T) {
+	dir := t.TempDir()
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+	write := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		return p
+	}
+	pdfPath := write("report.pdf", []byte("%PDF-1.4 small"))
+	noExtPath := write("screenshot", png)
+	bigPath := write("recording.mp3", bytes.Repeat([]byte("a"), 2048))
+
+	uploader := newMemoryFileUploader()
+	opts := &FileOptions{Uploader: uploader, InlineLimit: 1024}
+
+	tests := []struct {
+		name       string
+		file       genaiconfig.FileConfig
+		opts       *FileOptions
+		wantErr    error
+		wantMIME   string
+		wantURI    string
+		wantInline bool
+	}{
+		{
+			name:       "Extension of a local file",
+			file:       genaiconfig.FileConfig{Path: pdfPath},
+			opts:       opts,
+			wantMIME:   "application/pdf",
+			wantInline: true,
+		},
+		{
+			name:       "Content of a local file without extension",
+			file:       genaiconfig.FileConfig{Path: noExtPath},
+			opts:       opts,
+			wantMIME:   "image/png",
+			wantInline: true,
+		},
+		{
+			name:       "Content of inline data",
+			file:       genaiconfig.FileConfig{Contents: png},
+			wantMIME:   "image/png",
+			wantInline: true,
+		},
+		{
+			name:       "Name of inline data",
+			file:       genaiconfig.FileConfig{Contents: []byte("a,b\n1,2"), Name: "table.csv"},
+			wantMIME:   "text/csv",
+			wantInline: true,
+		},
+		{
+			name:     "GCS path",
+			file:     genaiconfig.FileConfig{Path: "gs://bucket/videos/intro.mp4"},
+			wantMIME: "video/mp4",
+			wantURI:  "gs://bucket/videos/intro.mp4",
+		},
+		{
+			name:    "S3 path",
+			file:    genaiconfig.FileConfig{Path: "s3://bucket/file.pdf"},
+			wantErr: ErrUnsupportedFileURI,
+		},
+		{
+			name:     "Large file goes through the uploader",
+			file:     genaiconfig.FileConfig{Path: bigPath},
+			opts:     opts,
+			wantMIME: "audio/mpeg",
+			wantURI:  "memory://files/upload-1",
+		},
+		{
+			name:       "Large file without uploader is inlined",
+			file:       genaiconfig.FileConfig{Path: bigPath},
+			wantMIME:   "audio/mpeg",
+			wantInline: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			part, err := fileConfigToPart(context.Background(), tt.file, tt.opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("fileConfigToPart() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fileConfigToPart() error = %v", err)
+			}
+			if tt.wantInline {
+				if part.InlineData == nil || part.InlineData.MIMEType != tt.wantMIME {
+					t.Errorf("InlineData = %+v, want mime %s", part.InlineData, tt.wantMIME)
+				}
+				return
+			}
+			if part.FileData == nil || part.FileData.FileURI != tt.wantURI || part.FileData.MIMEType != tt.wantMIME {
+				t.Errorf("FileData = %+v, want %s (%s)", part.FileData, tt.wantURI, tt.wantMIME)
+			}
+		})
+	}
+
+	if got := uploader.uploaded("files/upload-1"); len(got) != 2048 {
+		t.Errorf("uploaded %d bytes, want 2048", len(got))
+	}
+}
+
+// memoryFileUploader is an in memory FileUploader, files are ACTIVE right away
+type memoryFileUploader struct {
+	mu    sync.Mutex
+	files map[string]*genai.File
+	data  map[string][]byte
+}
+
+func newMemoryFileUploader() *memoryFileUploader {
+	return &memoryFileUploader{files: map[string]*genai.File{}, data: map[string][]byte{}}
+}
+
+func (m *memoryFileUploader) Upload(ctx context.Context, r io.Reader, config *genai.UploadFileConfig) (*genai.File, error) {
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &genai.UploadFileConfig{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := config.Name
+	if name == "" {
+		name = fmt.Sprintf("files/upload-%d", len(m.files)+1)
+	}
+	size := int64(buf.Len())
+	file := &genai.File{
+		Name:        name,
+		DisplayName: config.DisplayName,
+		MIMEType:    config.MIMEType,
+		SizeBytes:   &size,
+		URI:         "memory://" + name,
+		State:       genai.FileStateActive,
+	}
+	m.files[name] = file
+	m.data[name] = buf.Bytes()
+	return file, nil
+}
+
+func (m *memoryFileUploader) Get(ctx context.Context, name string, config *genai.GetFileConfig) (*genai.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[name]
+	if !ok {
+		return nil, fmt.Errorf("file %s not found", name)
+	}
+	return file, nil
+}
+
+// uploaded returns the bytes uploaded under name
+func (m *memoryFileUploader) uploaded(name string) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data[name]
+}
@@ -1,12 +1,13 @@
 package adapter
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"reflect"
 	"strings"
 
 	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
@@ -31,34 +32,66 @@ func convertFunctionCallingMode(mode genaiconfig.FunctionCallingMode) (genai.Fun
 		return "", fmt.Errorf("invalid function calling mode: %s", mode)
 	}
 }
+
+// ErrConflictingSchema is returned when a SchemaConfig sets more than one schema source
+var ErrConflictingSchema = errors.New("conflicting schema config")
+
+// resolveSchemaConfig returns the schema a SchemaConfig describes. Exactly one of Schema,
+// SchemaGenAI or SchemaJSON may be set: the first two become a genai.Schema and the last
+// is passed through as a json schema, the api rejects a declaration carrying both kinds.
+func resolveSchemaConfig(config *genaiconfig.SchemaConfig) (*genai.Schema, map[string]any, error) {
+	if config == nil {
+		return nil, nil, nil
+	}
+	var sources []string
+	if config.Schema != nil {
+		sources = append(sources, "Schema")
+	}
+	if config.SchemaGenAI != nil {
+		sources = append(sources, "SchemaGenAI")
+	}
+	if config.SchemaJSON != nil {
+		sources = append(sources, "SchemaJSON")
+	}
+	if len(sources) > 1 {
+		return nil, nil, fmt.Errorf("%w: %s are all set, use only one", ErrConflictingSchema, strings.Join(sources, ", "))
+	}
+	switch {
+	case config.SchemaGenAI != nil:
+		return config.SchemaGenAI, nil, nil
+	case config.Schema != nil:
+		schema, err := BuildSchemaFromStructStrict(config.Schema)
+		if err != nil {
+			return nil, nil, err
+		}
+		return schema, nil, nil
+	default:
+		return nil, config.SchemaJSON, nil
+	}
+}
+
+// BuildGeminiTool turns a tool into a function declaration, the request schema goes to
+// Parameters/ParametersJsonSchema and the response schema to Response/ResponseJsonSchema
 func BuildGeminiTool(tool *genaiconfig.Tool) (*genai.Tool, error) {
 	functionDeclaration := genai.FunctionDeclaration{
 		Name:        tool.Name,
 		Description: tool.Description,
 	}
-	requestConfig := tool.RequestConfig
-	responseConfig := tool.ResponseConfig
-	if requestConfig != nil {
-		if requestConfig.SchemaJSON != nil {
-			functionDeclaration.ParametersJsonSchema = requestConfig.SchemaJSON
-		}
-		if requestConfig.Schema != nil {
-			functionDeclaration.Parameters = buildSchemaFromType(reflect.TypeOf(requestConfig.Schema))
-		}
-		if requestConfig.SchemaGenAI != nil {
-			functionDeclaration.Parameters = requestConfig.SchemaGenAI
-		}
+	parameters, parametersJSON, err := resolveSchemaConfig(tool.RequestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("request schema: %w", err)
 	}
-	if responseConfig != nil {
-		if responseConfig.SchemaJSON != nil {
-			functionDeclaration.ResponseJsonSchema = responseConfig.SchemaJSON
-		}
-		if responseConfig.Schema != nil {
-			functionDeclaration.Parameters = buildSchemaFromType(reflect.TypeOf(responseConfig.Schema))
-		}
-		if responseConfig.SchemaGenAI != nil {
-			functionDeclaration.Parameters = responseConfig.SchemaGenAI
-		}
+	functionDeclaration.Parameters = parameters
+	if parametersJSON != nil {
+		functionDeclaration.ParametersJsonSchema = parametersJSON
+	}
+	response, responseJSON, err := resolveSchemaConfig(tool.ResponseConfig)
+	if err != nil {
+		return nil, fmt.Errorf("response schema: %w", err)
+	}
+	functionDeclaration.Response = response
+	if responseJSON != nil {
+		functionDeclaration.ResponseJsonSchema = responseJSON
 	}
 	return &genai.Tool{
 		FunctionDeclarations: []*genai.FunctionDeclaration{&functionDeclaration},
@@ -69,6 +102,9 @@ func BuildGeminiTools(tools []*genaiconfig.Tool) ([]*genai.Tool, error) {
 	if len(tools) == 0 {
 		return nil, nil
 	}
+	if err := checkDuplicateTools(tools); err != nil {
+		return nil, err
+	}
 	geminiTools := make([]*genai.Tool, len(tools))
 	for index, tool := range tools {
 		geminiTool, err := BuildGeminiTool(tool)
@@ -79,6 +115,94 @@ func BuildGeminiTools(tools []*genaiconfig.Tool) ([]*genai.Tool, error) {
 	}
 	return geminiTools, nil
 }
+
+var (
+	// ErrDuplicateTool is returned when two function tools share a name
+	ErrDuplicateTool = errors.New("duplicate tool name")
+	// ErrNilTool is returned for nil tools and tool groups
+	ErrNilTool = errors.New("nil tool")
+)
+
+func checkDuplicateTools(tools []*genaiconfig.Tool) error {
+	seen := make(map[string]bool, len(tools))
+	for index, tool := range tools {
+		if tool == nil {
+			return fmt.Errorf("%w at index %d", ErrNilTool, index)
+		}
+		if seen[tool.Name] {
+			return fmt.Errorf("%w: %s", ErrDuplicateTool, tool.Name)
+		}
+		seen[tool.Name] = true
+	}
+	return nil
+}
+
+// BuildGeminiToolGroup puts the declarations of all the group tools in one genai.Tool
+func BuildGeminiToolGroup(group *genaiconfig.ToolGroup) (*genai.Tool, error) {
+	if group == nil {
+		return nil, fmt.Errorf("%w group", ErrNilTool)
+	}
+	if err := checkDuplicateTools(group.Tools); err != nil {
+		return nil, fmt.Errorf("tool group %s: %w", group.Name, err)
+	}
+	geminiTool := &genai.Tool{
+		FunctionDeclarations: make([]*genai.FunctionDeclaration, 0, len(group.Tools)),
+	}
+	for _, tool := range group.Tools {
+		converted, err := BuildGeminiTool(tool)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to convert tool %s of group %s to gemini tool: %w", tool.Name, group.Name, err)
+		}
+		geminiTool.FunctionDeclarations = append(geminiTool.FunctionDeclarations, converted.FunctionDeclarations...)
+	}
+	return geminiTool, nil
+}
+
+// BuildGeminiBuiltinTools returns one genai.Tool per enabled built-in tool
+func BuildGeminiBuiltinTools(builtin *genaiconfig.BuiltinTools) []*genai.Tool {
+	if builtin == nil {
+		return nil
+	}
+	var tools []*genai.Tool
+	if builtin.GoogleSearch {
+		tools = append(tools, &genai.Tool{GoogleSearch: &genai.GoogleSearch{}})
+	}
+	if builtin.CodeExecution {
+		tools = append(tools, &genai.Tool{CodeExecution: &genai.ToolCodeExecution{}})
+	}
+	if builtin.URLContext {
+		tools = append(tools, &genai.Tool{URLContext: &genai.URLContext{}})
+	}
+	return tools
+}
+
+// buildGenerationTools converts the standalone tools, the groups and the built-in tools of
+// a generation config, a function name may only be declared once across all of them
+func buildGenerationTools(config *genaiconfig.GenerationConfig) ([]*genai.Tool, error) {
+	all := append([]*genaiconfig.Tool{}, config.Tools...)
+	for index, group := range config.ToolGroups {
+		if group == nil {
+			return nil, fmt.Errorf("%w group at index %d", ErrNilTool, index)
+		}
+		all = append(all, group.Tools...)
+	}
+	if err := checkDuplicateTools(all); err != nil {
+		return nil, err
+	}
+	tools, err := BuildGeminiTools(config.Tools)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range config.ToolGroups {
+		geminiTool, err := BuildGeminiToolGroup(group)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, geminiTool)
+	}
+	return append(tools, BuildGeminiBuiltinTools(config.BuiltinTools)...), nil
+}
+
 func GeminiConfigFromGenerationConfig(config *genaiconfig.GenerationConfig) (*genai.GenerateContentConfig, error) {
 	if config == nil {
 		return nil, fmt.Errorf("Config is null please provide config")
@@ -91,29 +215,41 @@ func GeminiConfigFromGenerationConfig(config *genaiconfig.GenerationConfig) (*ge
 		StopSequences:   config.StopSequences,
 	}
 
+	if config.ThinkingBudget != nil || config.IncludeThoughts != nil {
+		genConfig.ThinkingConfig = &genai.ThinkingConfig{
+			ThinkingBudget:  config.ThinkingBudget,
+			IncludeThoughts: config.IncludeThoughts != nil && *config.IncludeThoughts,
+		}
+	}
+
+	if len(config.SafetySettings) > 0 {
+		safety, err := GeminiSafetySettings(config.SafetySettings)
+		if err != nil {
+			return nil, err
+		}
+		genConfig.SafetySettings = safety
+	}
+
 	// Convert ResponseJSONSchema map to genai.Schema
 	responseSchema := config.ResponseSchemaConfig
 	if responseSchema != nil {
 		genConfig.ResponseMIMEType = "application/json"
-		if responseSchema.SchemaJSON != nil {
-			genConfig.ResponseJsonSchema = responseSchema.SchemaJSON
-		}
-		if responseSchema.Schema != nil {
-			genConfig.ResponseSchema = buildSchemaFromType(reflect.TypeOf(responseSchema.Schema))
+		schema, schemaJSON, err := resolveSchemaConfig(responseSchema)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to convert response schema :%w", err)
 		}
-		if responseSchema.SchemaGenAI != nil {
-			genConfig.ResponseSchema = responseSchema.SchemaGenAI
+		genConfig.ResponseSchema = schema
+		if schemaJSON != nil {
+			genConfig.ResponseJsonSchema = schemaJSON
 		}
 	}
 
 	// Convert Tools from simple format to Gemini's FunctionDeclaration format
-	if len(config.Tools) > 0 {
-		tools, err := BuildGeminiTools(config.Tools)
-		if err != nil {
-			return nil, fmt.Errorf("Unable to convert tools to gemini tools :%w", err)
-		}
-		genConfig.Tools = tools
+	tools, err := buildGenerationTools(config)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to convert tools to gemini tools :%w", err)
 	}
+	genConfig.Tools = tools
 
 	// ToolConfig can be assigned directly if types match
 	if config.ToolConfig != nil {
@@ -131,6 +267,12 @@ func GeminiConfigFromGenerationConfig(config *genaiconfig.GenerationConfig) (*ge
 	return genConfig, nil
 }
 func GeminiContentFromPrompt(prompt *genaiconfig.Prompt) ([]*genai.Content, error) {
+	return GeminiContentFromPromptWithFiles(context.Background(), prompt, nil)
+}
+
+// GeminiContentFromPromptWithFiles is GeminiContentFromPrompt with control over how files
+// are sent, large local files are uploaded through opts.Uploader
+func GeminiContentFromPromptWithFiles(ctx context.Context, prompt *genaiconfig.Prompt, opts *FileOptions) ([]*genai.Content, error) {
 	if prompt.Text == "" && len(prompt.Files) == 0 && prompt.StructuredText == nil {
 		return nil, errors.New("prompt must contain at least text, structured text, or files")
 	}
@@ -157,7 +299,7 @@ func GeminiContentFromPrompt(prompt *genaiconfig.Prompt) ([]*genai.Content, erro
 
 	// 3. Handle files
 	for i, file := range prompt.Files {
-		part, err := fileConfigToPart(file)
+		part, err := fileConfigToPart(ctx, file, opts)
 		if err != nil {
 			return nil, fmt.Errorf("failed to process file at index %d: %w", i, err)
 		}
@@ -172,17 +314,24 @@ func GeminiContentFromPrompt(prompt *genaiconfig.Prompt) ([]*genai.Content, erro
 	return []*genai.Content{content}, nil
 }
 
-func fileConfigToPart(file genaiconfig.FileConfig) (*genai.Part, error) {
+// fileConfigToPart turns a file into inline data or a file uri. An empty MIMEType is
+// guessed from the name extension and then from the content
+func fileConfigToPart(ctx context.Context, file genaiconfig.FileConfig, opts *FileOptions) (*genai.Part, error) {
 	part := &genai.Part{}
 
-	// Determine MIME type (fallback to default)
 	mimeType := file.MIMEType
 	if mimeType == "" {
-		mimeType = "application/octet-stream"
+		mimeType = mimeTypeFromName(file.Name)
+	}
+	if mimeType == "" && file.Path != "" {
+		mimeType = mimeTypeFromName(file.Path)
 	}
 
 	// Case 1: Explicit inline data provided
 	if len(file.Contents) > 0 {
+		if mimeType == "" {
+			mimeType = sniffMIMEType(file.Contents)
+		}
 		part.InlineData = &genai.Blob{
 			MIMEType: mimeType,
 			Data:     file.Contents,
@@ -192,10 +341,14 @@ func fileConfigToPart(file genaiconfig.FileConfig) (*genai.Part, error) {
 
 	// Case 2: Handle file path
 	if file.Path != "" {
-		isRemote := isRemoteURL(file.Path)
-
-		if isRemote {
-			// Remote URI (e.g. gs://, https://, s3://)
+		if scheme := fileURIScheme(file.Path); scheme != "" {
+			if !isRemoteURL(file.Path) {
+				return nil, fmt.Errorf("fileConfigToPart: %w: %s", ErrUnsupportedFileURI, file.Path)
+			}
+			if mimeType == "" {
+				mimeType = "application/octet-stream"
+			}
+			// Remote URI (gs://, https://)
 			part.FileData = &genai.FileData{
 				DisplayName: file.Name,
 				FileURI:     file.Path,
@@ -204,38 +357,168 @@ func fileConfigToPart(file genaiconfig.FileConfig) (*genai.Part, error) {
 			return part, nil
 		}
 
-		// Local file → read contents as inline data
-		data, err := os.ReadFile(filepath.Clean(file.Path))
-		if err != nil {
+		return localFileToPart(ctx, file, mimeType, opts)
+	}
+
+	return nil, fmt.Errorf("fileConfigToPart: both file.Contents and file.Path are empty")
+}
+
+// localFileToPart inlines small files and streams the big ones to the uploader
+func localFileToPart(ctx context.Context, file genaiconfig.FileConfig, mimeType string, opts *FileOptions) (*genai.Part, error) {
+	f, err := os.Open(filepath.Clean(file.Path))
+	if err != nil {
+		return nil, fmt.Errorf("fileConfigToPart: failed to read local file %q: %w", file.Path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("fileConfigToPart: failed to read local file %q: %w", file.Path, err)
+	}
+	if mimeType == "" {
+		head := make([]byte, 512)
+		n, err := io.ReadFull(f, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
 			return nil, fmt.Errorf("fileConfigToPart: failed to read local file %q: %w", file.Path, err)
 		}
+		mimeType = sniffMIMEType(head[:n])
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("fileConfigToPart: failed to read local file %q: %w", file.Path, err)
+		}
+	}
 
-		part.InlineData = &genai.Blob{
-			MIMEType: mimeType,
-			Data:     data,
+	if opts != nil && opts.Uploader != nil && info.Size() > opts.inlineLimit() {
+		displayName := file.Name
+		if displayName == "" {
+			displayName = filepath.Base(file.Path)
 		}
-		return part, nil
+		uploaded, err := uploadFile(ctx, opts, f, displayName, mimeType)
+		if err != nil {
+			return nil, fmt.Errorf("fileConfigToPart: %w", err)
+		}
+		return &genai.Part{FileData: &genai.FileData{
+			DisplayName: displayName,
+			FileURI:     uploaded.URI,
+			MIMEType:    mimeType,
+		}}, nil
 	}
 
-	return nil, fmt.Errorf("fileConfigToPart: both file.Contents and file.Path are empty")
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("fileConfigToPart: failed to read local file %q: %w", file.Path, err)
+	}
+	return &genai.Part{InlineData: &genai.Blob{
+		MIMEType: mimeType,
+		Data:     data,
+	}}, nil
 }
 
-// isRemoteURL checks if a path is a remote URL
+// isRemoteURL checks if a path is a uri gemini can fetch itself
 func isRemoteURL(path string) bool {
-	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+	switch fileURIScheme(path) {
+	case "http", "https", "gs":
+		return true
+	}
+	return false
 }
 
+// ModelResponseFromGeminiContent decodes every candidate, the top level fields of the
+// response mirror the first one
 func ModelResponseFromGeminiContent(res []*genai.Candidate) (*genaiconfig.ModelResponse, error) {
 	if len(res) == 0 {
 		return nil, errors.New("no candidates found in response")
 	}
 	c := res[0]
-	if c.Content == nil || len(c.Content.Parts) == 0 {
+	if c == nil || c.Content == nil || len(c.Content.Parts) == 0 {
+		if blocked := blockedCandidate(c); blocked != nil {
+			return nil, blocked
+		}
+		if c != nil && c.FinishReason != "" {
+			return nil, fmt.Errorf("candidate has no content parts, finish reason %s", c.FinishReason)
+		}
 		return nil, errors.New("candidate has no content parts")
 	}
 
-	var sb strings.Builder
-	var modelResp genaiconfig.ModelResponse
+	modelResp := genaiconfig.ModelResponse{
+		Candidates: make([]genaiconfig.Candidate, 0, len(res)),
+	}
+	for _, candidate := range res {
+		if candidate == nil {
+			continue
+		}
+		modelResp.Candidates = append(modelResp.Candidates, candidateFromGemini(candidate))
+	}
+	first := modelResp.Candidates[0]
+	modelResp.Text = first.Text
+	modelResp.Thoughts = first.Thoughts
+	modelResp.FunctionCalls = first.FunctionCalls
+	if len(first.FunctionCalls) > 0 {
+		modelResp.FunctionCall = &first.FunctionCalls[0]
+	}
+	modelResp.FinishReason = first.FinishReason
+	modelResp.SafetyRatings = first.SafetyRatings
+	return &modelResp, nil
+}
+
+// ModelResponseFromGeminiResponse is ModelResponseFromGeminiContent plus the usage metadata
+func ModelResponseFromGeminiResponse(res *genai.GenerateContentResponse) (*genaiconfig.ModelResponse, error) {
+	if res == nil {
+		return nil, errors.New("response is nil")
+	}
+	if blocked := BlockedErrorFromGemini(res); blocked != nil && blocked.Prompt {
+		return nil, blocked
+	}
+	modelResp, err := ModelResponseFromGeminiContent(res.Candidates)
+	if err != nil {
+		return nil, err
+	}
+	modelResp.Usage = UsageFromGemini(res.UsageMetadata)
+	return modelResp, nil
+}
+
+// UsageFromGemini copies the token counts, nil stays nil
+func UsageFromGemini(usage *genai.GenerateContentResponseUsageMetadata) *genaiconfig.Usage {
+	if usage == nil {
+		return nil
+	}
+	return &genaiconfig.Usage{
+		PromptTokens:     usage.PromptTokenCount,
+		CandidatesTokens: usage.CandidatesTokenCount,
+		ThoughtsTokens:   usage.ThoughtsTokenCount,
+		CachedTokens:     usage.CachedContentTokenCount,
+		ToolUseTokens:    usage.ToolUsePromptTokenCount,
+		TotalTokens:      usage.TotalTokenCount,
+	}
+}
+
+// SafetyRatingsFromGemini copies the category ratings of a candidate or a blocked prompt
+func SafetyRatingsFromGemini(ratings []*genai.SafetyRating) []genaiconfig.SafetyRating {
+	if len(ratings) == 0 {
+		return nil
+	}
+	out := make([]genaiconfig.SafetyRating, 0, len(ratings))
+	for _, rating := range ratings {
+		if rating == nil {
+			continue
+		}
+		out = append(out, genaiconfig.SafetyRating{
+			Category:    string(rating.Category),
+			Probability: string(rating.Probability),
+			Blocked:     rating.Blocked,
+		})
+	}
+	return out
+}
+
+func candidateFromGemini(c *genai.Candidate) genaiconfig.Candidate {
+	candidate := genaiconfig.Candidate{
+		Index:         c.Index,
+		FinishReason:  string(c.FinishReason),
+		SafetyRatings: SafetyRatingsFromGemini(c.SafetyRatings),
+	}
+	if c.Content == nil {
+		return candidate
+	}
+	var text, thoughts strings.Builder
 	for _, part := range c.Content.Parts {
 		if part == nil {
 			continue
@@ -243,23 +526,29 @@ func ModelResponseFromGeminiContent(res []*genai.Candidate) (*genaiconfig.ModelR
 
 		switch {
 		case part.FunctionCall != nil:
-			modelResp.FunctionCall = &genaiconfig.FunctionCall{
+			candidate.FunctionCalls = append(candidate.FunctionCalls, genaiconfig.FunctionCall{
+				ID:   part.FunctionCall.ID,
 				Name: part.FunctionCall.Name,
 				Args: part.FunctionCall.Args,
-			}
+			})
+
+		case part.Thought:
+			thoughts.WriteString(part.Text)
+			thoughts.WriteString("\n")
 
 		case part.Text != "":
-			sb.WriteString(part.Text)
-			sb.WriteString("\n")
+			text.WriteString(part.Text)
+			text.WriteString("\n")
 
 		default:
 			raw, err := json.Marshal(part)
 			if err == nil {
-				sb.WriteString(string(raw))
-				sb.WriteString("\n")
+				text.WriteString(string(raw))
+				text.WriteString("\n")
 			}
 		}
 	}
-	modelResp.Text = strings.TrimSpace(sb.String())
-	return &modelResp, nil
+	candidate.Text = strings.TrimSpace(text.String())
+	candidate.Thoughts = strings.TrimSpace(thoughts.String())
+	return candidate
 }
@@ -1,6 +1,8 @@
 package adapter
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -150,6 +152,130 @@ func TestBuildGeminiTool(t *testing.T) {
 	}
 }
 
+func TestBuildGeminiToolSchemas(t *testing.T) {
+	type weatherRequest struct {
+		City string `json:"city"`
+	}
+	type weatherResponse struct {
+		Celsius float64 `json:"celsius"`
+	}
+	requestGenAI := &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{"city": {Type: genai.TypeString}}}
+	responseGenAI := &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{"celsius": {Type: genai.TypeNumber}}}
+	responseJSON := map[string]interface{}{"type": "object"}
+
+	tests := []struct {
+		name    string
+		tool    *genaiconfig.Tool
+		wantErr error
+		check   func(t *testing.T, fd *genai.FunctionDeclaration)
+	}{
+		{
+			name: "Response genai schema goes to Response",
+			tool: &genaiconfig.Tool{
+				Name:           "weather",
+				RequestConfig:  &genaiconfig.SchemaConfig{SchemaGenAI: requestGenAI},
+				ResponseConfig: &genaiconfig.SchemaConfig{SchemaGenAI: responseGenAI},
+			},
+			check: func(t *testing.T, fd *genai.FunctionDeclaration) {
+				if fd.Parameters != requestGenAI {
+					t.Errorf("Parameters = %+v, want the request schema", fd.Parameters)
+				}
+				if fd.Response != responseGenAI {
+					t.Errorf("Response = %+v, want the response schema", fd.Response)
+				}
+			},
+		},
+		{
+			name: "Struct schemas",
+			tool: &genaiconfig.Tool{
+				Name:           "weather",
+				RequestConfig:  &genaiconfig.SchemaConfig{Schema: weatherRequest{}},
+				ResponseConfig: &genaiconfig.SchemaConfig{Schema: weatherResponse{}},
+			},
+			check: func(t *testing.T, fd *genai.FunctionDeclaration) {
+				if fd.Parameters == nil || fd.Parameters.Properties["city"] == nil {
+					t.Errorf("Parameters = %+v, want the city property", fd.Parameters)
+				}
+				if fd.Response == nil || fd.Response.Properties["celsius"] == nil {
+					t.Errorf("Response = %+v, want the celsius property", fd.Response)
+				}
+			},
+		},
+		{
+			name: "Response json schema goes to ResponseJsonSchema",
+			tool: &genaiconfig.Tool{
+				Name:           "weather",
+				RequestConfig:  &genaiconfig.SchemaConfig{Schema: weatherRequest{}},
+				ResponseConfig: &genaiconfig.SchemaConfig{SchemaJSON: responseJSON},
+			},
+			check: func(t *testing.T, fd *genai.FunctionDeclaration) {
+				if fd.Response != nil || fd.ResponseJsonSchema == nil {
+					t.Errorf("Response = %+v, ResponseJsonSchema = %v", fd.Response, fd.ResponseJsonSchema)
+				}
+				if fd.Parameters == nil || fd.ParametersJsonSchema != nil {
+					t.Errorf("Parameters = %+v, ParametersJsonSchema = %v", fd.Parameters, fd.ParametersJsonSchema)
+				}
+			},
+		},
+		{
+			name: "Map arguments stay supported",
+			tool: &genaiconfig.Tool{
+				Name: "weather",
+				RequestConfig: &genaiconfig.SchemaConfig{Schema: struct {
+					Labels map[string]string `json:"labels"`
+				}{}},
+			},
+			check: func(t *testing.T, fd *genai.FunctionDeclaration) {
+				if labels := fd.Parameters.Properties["labels"]; labels == nil || labels.Type != genai.TypeObject {
+					t.Errorf("labels = %+v, want an object", labels)
+				}
+			},
+		},
+		{
+			name: "Conflicting request schemas",
+			tool: &genaiconfig.Tool{
+				Name:          "weather",
+				RequestConfig: &genaiconfig.SchemaConfig{Schema: weatherRequest{}, SchemaGenAI: requestGenAI},
+			},
+			wantErr: ErrConflictingSchema,
+		},
+		{
+			name: "Conflicting response schemas",
+			tool: &genaiconfig.Tool{
+				Name:           "weather",
+				ResponseConfig: &genaiconfig.SchemaConfig{SchemaGenAI: responseGenAI, SchemaJSON: responseJSON},
+			},
+			wantErr: ErrConflictingSchema,
+		},
+		{
+			name: "Invalid struct tags",
+			tool: &genaiconfig.Tool{
+				Name: "weather",
+				RequestConfig: &genaiconfig.SchemaConfig{Schema: struct {
+					City string `json:"city" minLength:"short"`
+				}{}},
+			},
+			wantErr: ErrInvalidSchemaTag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := BuildGeminiTool(tt.tool)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("BuildGeminiTool() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildGeminiTool() error = %v", err)
+			}
+			tt.check(t, result.FunctionDeclarations[0])
+		})
+	}
+}
+
 func TestBuildGeminiTools(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -218,6 +344,106 @@ func TestBuildGeminiTools(t *testing.T) {
 	}
 }
 
+func TestBuildGenerationTools(t *testing.T) {
+	search := &genaiconfig.Tool{Name: "search_orders"}
+	refund := &genaiconfig.Tool{Name: "refund_order"}
+	weather := &genaiconfig.Tool{Name: "get_weather"}
+
+	tests := []struct {
+		name      string
+		config    *genaiconfig.GenerationConfig
+		wantErr   error
+		wantTools int
+		check     func(t *testing.T, tools []*genai.Tool)
+	}{
+		{
+			name:      "No tools",
+			config:    &genaiconfig.GenerationConfig{},
+			wantTools: 0,
+		},
+		{
+			name: "Group shares one gemini tool",
+			config: &genaiconfig.GenerationConfig{
+				Tools:      []*genaiconfig.Tool{weather},
+				ToolGroups: []*genaiconfig.ToolGroup{{Name: "orders", Tools: []*genaiconfig.Tool{search, refund}}},
+			},
+			wantTools: 2,
+			check: func(t *testing.T, tools []*genai.Tool) {
+				group := tools[1].FunctionDeclarations
+				if len(group) != 2 || group[0].Name != "search_orders" || group[1].Name != "refund_order" {
+					t.Errorf("group declarations = %+v", group)
+				}
+			},
+		},
+		{
+			name: "Built-in tools",
+			config: &genaiconfig.GenerationConfig{
+				BuiltinTools: &genaiconfig.BuiltinTools{GoogleSearch: true, CodeExecution: true, URLContext: true},
+			},
+			wantTools: 3,
+			check: func(t *testing.T, tools []*genai.Tool) {
+				if tools[0].GoogleSearch == nil || tools[1].CodeExecution == nil || tools[2].URLContext == nil {
+					t.Errorf("built-in tools = %+v", tools)
+				}
+			},
+		},
+		{
+			name:    "Duplicate standalone tools",
+			config:  &genaiconfig.GenerationConfig{Tools: []*genaiconfig.Tool{weather, weather}},
+			wantErr: ErrDuplicateTool,
+		},
+		{
+			name: "Duplicate across a tool and a group",
+			config: &genaiconfig.GenerationConfig{
+				Tools:      []*genaiconfig.Tool{search},
+				ToolGroups: []*genaiconfig.ToolGroup{{Name: "orders", Tools: []*genaiconfig.Tool{search, refund}}},
+			},
+			wantErr: ErrDuplicateTool,
+		},
+		{
+			name:    "Nil tool",
+			config:  &genaiconfig.GenerationConfig{Tools: []*genaiconfig.Tool{weather, nil}},
+			wantErr: ErrNilTool,
+		},
+		{
+			name:    "Nil tool group",
+			config:  &genaiconfig.GenerationConfig{ToolGroups: []*genaiconfig.ToolGroup{nil}},
+			wantErr: ErrNilTool,
+		},
+		{
+			name: "Nil tool in a group",
+			config: &genaiconfig.GenerationConfig{
+				ToolGroups: []*genaiconfig.ToolGroup{{Name: "orders", Tools: []*genaiconfig.Tool{search, nil}}},
+			},
+			wantErr: ErrNilTool,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := GeminiConfigFromGenerationConfig(tt.config)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GeminiConfigFromGenerationConfig() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GeminiConfigFromGenerationConfig() error = %v", err)
+			}
+			if len(result.Tools) != tt.wantTools {
+				t.Fatalf("Expected %d tools, got %d", tt.wantTools, len(result.Tools))
+			}
+			if tt.check != nil {
+				tt.check(t, result.Tools)
+			}
+		})
+	}
+	if _, err := BuildGeminiToolGroup(nil); !errors.Is(err, ErrNilTool) {
+		t.Errorf("BuildGeminiToolGroup(nil) error = %v, want ErrNilTool", err)
+	}
+}
+
 func TestGeminiConfigFromGenerationConfig(t *testing.T) {
 	temp := float32(0.7)
 	topP := float32(0.9)
@@ -262,6 +488,16 @@ func TestGeminiConfigFromGenerationConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Config with conflicting response schema",
+			config: &genaiconfig.GenerationConfig{
+				ResponseSchemaConfig: &genaiconfig.SchemaConfig{
+					SchemaJSON:  map[string]interface{}{"type": "object"},
+					SchemaGenAI: &genai.Schema{Type: genai.TypeObject},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "Config with tools",
 			config: &genaiconfig.GenerationConfig{
@@ -423,7 +659,7 @@ func TestFileConfigToPart(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := fileConfigToPart(tt.file)
+			result, err := fileConfigToPart(context.Background(), tt.file, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("fileConfigToPart() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -462,8 +698,13 @@ func TestIsRemoteURL(t *testing.T) {
 			want: false,
 		},
 		{
-			name: "GCS URL (not detected as remote by this function)",
+			name: "GCS URL",
 			path: "gs://bucket/file",
+			want: true,
+		},
+		{
+			name: "S3 URL",
+			path: "s3://bucket/file",
 			want: false,
 		},
 	}
@@ -564,6 +805,77 @@ func TestModelResponseFromGeminiContent(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Parallel function calls",
+			candidates: []*genai.Candidate{
+				{
+					Content: &genai.Content{
+						Parts: []*genai.Part{
+							{FunctionCall: &genai.FunctionCall{ID: "call-1", Name: "get_weather", Args: map[string]interface{}{"city": "Cairo"}}},
+							{FunctionCall: &genai.FunctionCall{ID: "call-2", Name: "get_weather", Args: map[string]interface{}{"city": "Paris"}}},
+							{FunctionCall: &genai.FunctionCall{ID: "call-3", Name: "get_time"}},
+						},
+					},
+					FinishReason: genai.FinishReasonStop,
+				},
+			},
+			wantErr: false,
+			checkFunc: func(t *testing.T, resp *genaiconfig.ModelResponse) {
+				if len(resp.FunctionCalls) != 3 {
+					t.Fatalf("Expected 3 function calls, got %d", len(resp.FunctionCalls))
+				}
+				if resp.FunctionCalls[1].ID != "call-2" || resp.FunctionCalls[1].Args["city"] != "Paris" {
+					t.Errorf("Second call = %+v", resp.FunctionCalls[1])
+				}
+				if resp.FunctionCall == nil || resp.FunctionCall.ID != "call-1" {
+					t.Errorf("FunctionCall should be the first call, got %+v", resp.FunctionCall)
+				}
+				if resp.Text != "" {
+					t.Errorf("Expected no text, got '%s'", resp.Text)
+				}
+				if resp.FinishReason != "STOP" {
+					t.Errorf("Expected finish reason STOP, got '%s'", resp.FinishReason)
+				}
+			},
+		},
+		{
+			name: "Thoughts, safety ratings and every candidate",
+			candidates: []*genai.Candidate{
+				{
+					Content: &genai.Content{
+						Parts: []*genai.Part{
+							{Text: "Comparing the two options", Thought: true},
+							{Text: "Take the train."},
+						},
+					},
+					FinishReason: genai.FinishReasonStop,
+					SafetyRatings: []*genai.SafetyRating{
+						{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityNegligible},
+					},
+				},
+				{
+					Index:        1,
+					Content:      &genai.Content{Parts: []*genai.Part{{Text: "Take the bus."}}},
+					FinishReason: genai.FinishReasonMaxTokens,
+				},
+			},
+			wantErr: false,
+			checkFunc: func(t *testing.T, resp *genaiconfig.ModelResponse) {
+				if resp.Text != "Take the train." || resp.Thoughts != "Comparing the two options" {
+					t.Errorf("Text = '%s', Thoughts = '%s'", resp.Text, resp.Thoughts)
+				}
+				if len(resp.SafetyRatings) != 1 || resp.SafetyRatings[0].Category != "HARM_CATEGORY_HARASSMENT" {
+					t.Errorf("SafetyRatings = %+v", resp.SafetyRatings)
+				}
+				if len(resp.Candidates) != 2 {
+					t.Fatalf("Expected 2 candidates, got %d", len(resp.Candidates))
+				}
+				second := resp.Candidates[1]
+				if second.Index != 1 || second.Text != "Take the bus." || second.FinishReason != "MAX_TOKENS" {
+					t.Errorf("Second candidate = %+v", second)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -580,6 +892,80 @@ func TestModelResponseFromGeminiContent(t *testing.T) {
 	}
 }
 
+func TestGeminiConfigThinking(t *testing.T) {
+	budget := int32(1024)
+	includeThoughts, noThoughts := true, false
+	tests := []struct {
+		name   string
+		config *genaiconfig.GenerationConfig
+		want   *genai.ThinkingConfig
+	}{
+		{
+			name:   "No thinking fields",
+			config: &genaiconfig.GenerationConfig{},
+			want:   nil,
+		},
+		{
+			name:   "Budget and thoughts",
+			config: &genaiconfig.GenerationConfig{ThinkingBudget: &budget, IncludeThoughts: &includeThoughts},
+			want:   &genai.ThinkingConfig{ThinkingBudget: &budget, IncludeThoughts: true},
+		},
+		{
+			name:   "Thoughts with the default budget",
+			config: &genaiconfig.GenerationConfig{IncludeThoughts: &includeThoughts},
+			want:   &genai.ThinkingConfig{IncludeThoughts: true},
+		},
+		{
+			name:   "Thoughts turned off",
+			config: &genaiconfig.GenerationConfig{IncludeThoughts: &noThoughts},
+			want:   &genai.ThinkingConfig{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := GeminiConfigFromGenerationConfig(tt.config)
+			if err != nil {
+				t.Fatalf("GeminiConfigFromGenerationConfig() error = %v", err)
+			}
+			got := result.ThinkingConfig
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("ThinkingConfig = %+v, want %+v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if got.IncludeThoughts != tt.want.IncludeThoughts || (got.ThinkingBudget == nil) != (tt.want.ThinkingBudget == nil) ||
+				got.ThinkingBudget != nil && *got.ThinkingBudget != *tt.want.ThinkingBudget {
+				t.Errorf("ThinkingConfig = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelResponseFromGeminiResponse(t *testing.T) {
+	result, err := ModelResponseFromGeminiResponse(&genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{Content: &genai.Content{Parts: []*genai.Part{{Text: "Hi"}}}},
+		},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     12,
+			CandidatesTokenCount: 3,
+			ThoughtsTokenCount:   40,
+			TotalTokenCount:      55,
+		},
+	})
+	if err != nil {
+		t.Fatalf("ModelResponseFromGeminiResponse() error = %v", err)
+	}
+	want := genaiconfig.Usage{PromptTokens: 12, CandidatesTokens: 3, ThoughtsTokens: 40, TotalTokens: 55}
+	if result.Usage == nil || *result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+	if _, err := ModelResponseFromGeminiResponse(nil); err == nil {
+		t.Error("Expected an error for a nil response")
+	}
+}
+
 // // Benchmark tests
 func BenchmarkConvertFunctionCallingMode(b *testing.B) {
 	for i := 0; i < b.N; i++ {
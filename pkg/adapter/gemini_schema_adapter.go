@@ -2,12 +2,13 @@ package adapter
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
-	"strconv"
 	"strings"
 
 	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"github.com/rs/zerolog/log"
 	genai "google.golang.org/genai"
 )
 
@@ -35,84 +36,196 @@ func BuildSchemaFromStruct[T interface{}](t T) *genai.Schema {
 	return buildSchemaFromType(reflect.TypeOf(t))
 }
 
+// ErrUnsupportedGenAIType is reported by the strict builder for go types a genai.Schema
+// cannot describe
+var ErrUnsupportedGenAIType = errors.New("type is not supported by genai schemas")
+
+// SchemaOptions tunes BuildSchemaFromStructStrict
+type SchemaOptions struct {
+	// RejectMaps reports map fields as ErrUnsupportedGenAIType. By default they become
+	// objects without properties and a warning is logged
+	RejectMaps bool
+}
+
+// BuildSchemaFromStructStrict is BuildSchemaFromStruct that also reports the struct tags
+// that could not be applied (bad values, constraints that do not match the field type).
+// genai.Schema has no additionalProperties, so map fields are objects without properties
+// that Gemini may reject; set RejectMaps to fail on them, or use BuildJSONSchemaFromStruct
+// with ResponseJsonSchema for maps.
+func BuildSchemaFromStructStrict[T interface{}](t T, options ...SchemaOptions) (*genai.Schema, error) {
+	var opts SchemaOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	b := newSchemaBuilder()
+	b.rejectMaps = opts.RejectMaps
+	schema := b.build(reflect.TypeOf(t))
+	if len(b.mapTypes) > 0 && !opts.RejectMaps {
+		log.Warn().Strs("maps", b.mapTypes).Str("type", fmt.Sprint(reflect.TypeOf(t))).
+			Msg("map fields have no properties in a genai schema, use a json schema to describe them")
+	}
+	return schema, errors.Join(b.errs...)
+}
+
+// DefaultSchemaRecursionDepth is how many times a recursive struct is expanded on one
+// path of a genai.Schema, deeper fields are left out
+const DefaultSchemaRecursionDepth = 3
+
+// schemaBuilder walks the go type, invalid tags are skipped and collected on errs.
+// genai.Schema has no references, so recursive structs are expanded up to maxDepth;
+// with useRefs they are emitted once in defs and replaced by placeholders listed in
+// refs, which the json schema output turns into $ref.
+type schemaBuilder struct {
+	errs     []error
+	maxDepth int
+	useRefs  bool
+	active   map[reflect.Type]int
+	defs     map[string]*genai.Schema
+	refs     map[*genai.Schema]string
+	defNames map[reflect.Type]string
+	// mapValues keeps the value schema of map objects for additionalProperties
+	mapValues map[*genai.Schema]*genai.Schema
+	// recursive marks the structs referenced while they are being built
+	recursive map[reflect.Type]bool
+	// refTags lists the union variants emitted as $ref, keyed by definition name
+	refTags map[string][]unionVariant
+	// rejectMaps reports map types on errs
+	rejectMaps bool
+	// mapTypes lists the map types that were built
+	mapTypes []string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		maxDepth:  DefaultSchemaRecursionDepth,
+		active:    map[reflect.Type]int{},
+		defs:      map[string]*genai.Schema{},
+		refs:      map[*genai.Schema]string{},
+		defNames:  map[reflect.Type]string{},
+		mapValues: map[*genai.Schema]*genai.Schema{},
+		recursive: map[reflect.Type]bool{},
+		refTags:   map[string][]unionVariant{},
+	}
+}
+
 func buildSchemaFromType(t reflect.Type) *genai.Schema {
+	return newSchemaBuilder().build(t)
+}
+
+// ref returns a placeholder schema pointing at the definition of t
+func (b *schemaBuilder) ref(t reflect.Type) *genai.Schema {
+	placeholder := &genai.Schema{}
+	b.refs[placeholder] = b.defName(t)
+	return placeholder
+}
+
+func (b *schemaBuilder) defName(t reflect.Type) string {
+	if name, ok := b.defNames[t]; ok {
+		return name
+	}
+	base := strings.Map(func(r rune) rune {
+		if r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, t.Name())
+	name := base
+	taken := func(candidate string) bool {
+		for _, existing := range b.defNames {
+			if existing == candidate {
+				return true
+			}
+		}
+		return false
+	}
+	for index := 2; taken(name); index++ {
+		name = fmt.Sprintf("%s%d", base, index)
+	}
+	b.defNames[t] = name
+	return name
+}
+
+// build returns nil when a recursive struct reached maxDepth, the caller drops the field
+func (b *schemaBuilder) build(t reflect.Type) *genai.Schema {
+	if t.Kind() == reflect.Struct && t.Implements(unionHolderType) {
+		t = reflect.Zero(t).Interface().(unionHolder).unionInterface()
+	}
+	if special := knownTypeSchema(t); special != nil {
+		return special
+	}
 	s := &genai.Schema{}
 
 	switch t.Kind() {
 
 	case reflect.Struct:
+		if b.useRefs && b.recursive[t] {
+			return b.ref(t)
+		}
+		if b.active[t] > 0 {
+			if b.useRefs {
+				b.recursive[t] = true
+				return b.ref(t)
+			}
+			if b.active[t] >= b.maxDepth {
+				return nil
+			}
+		}
+		b.active[t]++
+		defer func() { b.active[t]-- }()
+
 		s.Type = genai.TypeObject
 		s.Properties = map[string]*genai.Schema{}
 		s.PropertyOrdering = []string{}
 
-		for i := 0; i < t.NumField(); i++ {
-			f := t.Field(i)
-
-			if f.PkgPath != "" { // skip unexported
+		for _, field := range structFields(t) {
+			f := field.field
+			fieldSchema := b.build(baseType(f.Type))
+			if fieldSchema == nil { // recursion truncated
 				continue
 			}
-
-			jsonTag := f.Tag.Get("json")
-			parts := strings.Split(jsonTag, ",")
-			fieldName := parts[0]
-			if fieldName == "" {
-				fieldName = f.Name
+			if field.asString && isPrimitiveType(fieldSchema.Type) {
+				// `json:",string"` encodes numbers and bools as json strings
+				fieldSchema = &genai.Schema{Type: genai.TypeString}
 			}
-
-			fieldSchema := buildSchemaFromType(baseType(f.Type))
-
-			// --- NEW: Read description tag ---
-			if desc := f.Tag.Get("description"); desc != "" {
-				fieldSchema.Description = desc
+			if f.Type.Kind() == reflect.Pointer {
+				nullable := true
+				fieldSchema.Nullable = &nullable
 			}
+			b.errs = append(b.errs, applyFieldTags(field.parent, f, fieldSchema)...)
 
-			// --- NEW: Read minLength/maxLength ---
-			if minLen := f.Tag.Get("minLength"); minLen != "" {
-				if v, err := strconv.Atoi(minLen); err == nil {
-					x := int64(v)
-					fieldSchema.MinLength = &x
-				}
-			}
-			if maxLen := f.Tag.Get("maxLength"); maxLen != "" {
-				if v, err := strconv.Atoi(maxLen); err == nil {
-					x := int64(v)
-					fieldSchema.MaxLength = &x
-				}
-			}
+			s.Properties[field.name] = fieldSchema
+			s.PropertyOrdering = append(s.PropertyOrdering, field.name)
 
-			// --- NEW: Read minItems/maxItems ---
-			if minItems := f.Tag.Get("minItems"); minItems != "" {
-				if v, err := strconv.Atoi(minItems); err == nil {
-					x := int64(v)
-					fieldSchema.MinItems = &x
-				}
-			}
-			if maxItems := f.Tag.Get("maxItems"); maxItems != "" {
-				if v, err := strconv.Atoi(maxItems); err == nil {
-					x := int64(v)
-					fieldSchema.MaxItems = &x
-				}
+			// Required unless omitempty / omitzero or promoted through a nil-able embedded pointer
+			if !field.optional {
+				s.Required = append(s.Required, field.name)
 			}
+		}
+		if b.useRefs && b.recursive[t] {
+			b.defs[b.defName(t)] = s
+			return b.ref(t)
+		}
 
-			s.Properties[fieldName] = fieldSchema
-			s.PropertyOrdering = append(s.PropertyOrdering, fieldName)
-
-			// Required if not omitempty
-			isOmitempty := false
-			for _, opt := range parts[1:] {
-				if opt == "omitempty" {
-					isOmitempty = true
-					break
-				}
-			}
-			if !isOmitempty {
-				s.Required = append(s.Required, fieldName)
-			}
+	case reflect.Map:
+		// genai.Schema has no additionalProperties, the value type is described instead
+		b.mapTypes = append(b.mapTypes, t.String())
+		if b.rejectMaps {
+			b.errs = append(b.errs, fmt.Errorf("%w: %s", ErrUnsupportedGenAIType, t))
+		}
+		s.Type = genai.TypeObject
+		value := b.build(baseType(t.Elem()))
+		if value == nil {
+			value = &genai.Schema{}
 		}
+		s.Description = fmt.Sprintf("map of %s keys to %s values", mapKeyKind(t.Key()), schemaTypeName(value))
+		b.mapValues[s] = value
 
 	case reflect.Slice, reflect.Array:
 		s.Type = genai.TypeArray
-		s.Items = buildSchemaFromType(baseType(t.Elem()))
+		s.Items = b.build(baseType(t.Elem()))
+		if s.Items == nil {
+			return nil
+		}
 
 	case reflect.String:
 		s.Type = genai.TypeString
@@ -123,15 +236,27 @@ func buildSchemaFromType(t reflect.Type) *genai.Schema {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		s.Type = genai.TypeInteger
 
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s.Type = genai.TypeInteger
+		zero := float64(0)
+		s.Minimum = &zero
+
 	case reflect.Float32, reflect.Float64:
 		s.Type = genai.TypeNumber
 
+	case reflect.Interface:
+		if info, ok := lookupUnion(t); ok {
+			return b.unionSchema(info)
+		}
+		// any value, leave the type unspecified
+
 	default:
 		s.Type = genai.TypeString
 	}
 
 	return s
 }
+
 func NewToolFromSignatures[TReq, TRes any](
 	name string,
 	description string,
@@ -150,11 +275,9 @@ func NewToolFromSignatures[TReq, TRes any](
 		Name:        name,
 		Description: description,
 		RequestConfig: &genaiconfig.SchemaConfig{
-			// Note: RequestSchemaJSON should be derived from reqSchema, e.g., via a helper function
 			SchemaGenAI: reqSchema,
 		},
 		ResponseConfig: &genaiconfig.SchemaConfig{
-			// Note: ResponseSchemaJSON should be derived from resSchema
 			SchemaGenAI: resSchema,
 		},
 	}, nil
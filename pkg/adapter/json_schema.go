@@ -0,0 +1,423 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	genai "google.golang.org/genai"
+)
+
+// JSONSchemaDraft is the dialect BuildJSONSchemaFromStruct declares in $schema
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var ErrUnsupportedJSONSchema = errors.New("unsupported json schema")
+
+// BuildJSONSchemaFromStruct builds a JSON Schema (draft 2020-12) from the same struct
+// tags as BuildSchemaFromStruct, ready for ResponseJsonSchema / ParametersJsonSchema,
+// OpenAI, MCP or OpenAPI. Recursive structs are emitted once under $defs and
+// referenced with $ref, maps carry their value schema in additionalProperties.
+func BuildJSONSchemaFromStruct[T interface{}](t T) (map[string]any, error) {
+	b := newSchemaBuilder()
+	b.useRefs = true
+	root := b.build(reflect.TypeOf(t))
+	b.tagRefVariants()
+	out := b.toJSON(root)
+	out["$schema"] = JSONSchemaDraft
+	if len(b.defs) > 0 {
+		defs := make(map[string]any, len(b.defs))
+		for name, def := range b.defs {
+			defs[name] = b.toJSON(def)
+		}
+		out["$defs"] = defs
+	}
+	return out, errors.Join(b.errs...)
+}
+
+// GenAISchemaToJSONSchema converts a genai.Schema to the equivalent JSON Schema keywords
+func GenAISchemaToJSONSchema(s *genai.Schema) map[string]any {
+	return (&schemaBuilder{}).toJSON(s)
+}
+
+// toJSON converts the genai schema to JSON Schema keywords, placeholders listed in
+// refs become $ref to their $defs entry
+func (b *schemaBuilder) toJSON(s *genai.Schema) map[string]any {
+	out := map[string]any{}
+	if s == nil {
+		return out
+	}
+	if name, ok := b.refs[s]; ok {
+		out["$ref"] = "#/$defs/" + name
+	}
+	if s.Type != "" {
+		out["type"] = strings.ToLower(string(s.Type))
+	}
+	if s.Title != "" {
+		out["title"] = s.Title
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if s.Format != "" && s.Format != "enum" {
+		out["format"] = s.Format
+	}
+	if s.Pattern != "" {
+		out["pattern"] = s.Pattern
+	}
+	if len(s.Enum) > 0 {
+		values := make([]any, len(s.Enum))
+		for index, v := range s.Enum {
+			values[index] = v
+			if parsed, err := parseTagValue(s, v); err == nil {
+				values[index] = parsed
+			}
+		}
+		out["enum"] = values
+	}
+	if s.Default != nil {
+		out["default"] = s.Default
+	}
+	if s.Example != nil {
+		out["examples"] = []any{s.Example}
+	}
+	setInt := func(key string, v *int64) {
+		if v != nil {
+			out[key] = *v
+		}
+	}
+	setFloat := func(key string, v *float64) {
+		if v != nil {
+			out[key] = *v
+		}
+	}
+	setFloat("minimum", s.Minimum)
+	setFloat("maximum", s.Maximum)
+	setInt("minLength", s.MinLength)
+	setInt("maxLength", s.MaxLength)
+	setInt("minItems", s.MinItems)
+	setInt("maxItems", s.MaxItems)
+	setInt("minProperties", s.MinProperties)
+	setInt("maxProperties", s.MaxProperties)
+	if s.Items != nil {
+		out["items"] = b.toJSON(s.Items)
+	}
+	if len(s.Properties) > 0 {
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = b.toJSON(prop)
+		}
+		out["properties"] = props
+	}
+	if len(s.Required) > 0 {
+		out["required"] = append([]string(nil), s.Required...)
+	}
+	if value, ok := b.mapValues[s]; ok {
+		out["additionalProperties"] = b.toJSON(value)
+	}
+	if len(s.AnyOf) > 0 {
+		anyOf := make([]any, len(s.AnyOf))
+		for index, option := range s.AnyOf {
+			anyOf[index] = b.toJSON(option)
+		}
+		out["anyOf"] = anyOf
+	}
+	if s.Nullable != nil && *s.Nullable {
+		makeNullable(out)
+	}
+	return out
+}
+
+// makeNullable adds null to the accepted types, references and unions are wrapped
+// in an anyOf with the null type
+func makeNullable(out map[string]any) {
+	if typ, ok := out["type"].(string); ok {
+		out["type"] = []any{typ, "null"}
+		if enum, ok := out["enum"].([]any); ok {
+			out["enum"] = append(enum, nil)
+		}
+		return
+	}
+	null := map[string]any{"type": "null"}
+	if ref, ok := out["$ref"]; ok {
+		delete(out, "$ref")
+		out["anyOf"] = []any{map[string]any{"$ref": ref}, null}
+		return
+	}
+	if anyOf, ok := out["anyOf"].([]any); ok {
+		out["anyOf"] = append(anyOf, null)
+	}
+}
+
+// -----------------------------------------------------------
+// JSON Schema -> genai.Schema
+// -----------------------------------------------------------
+
+// JSONSchemaToGenAISchema converts a JSON Schema (as decoded by encoding/json or built
+// by BuildJSONSchemaFromStruct) to a genai.Schema. Local $ref are inlined and recursive
+// references are expanded up to DefaultSchemaRecursionDepth levels. Keywords genai has
+// no equivalent for (additionalProperties, $comment, ...) are dropped.
+func JSONSchemaToGenAISchema(schema map[string]any) (*genai.Schema, error) {
+	r := &jsonSchemaReader{root: schema, active: map[string]int{}}
+	s, err := r.read(schema)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return &genai.Schema{}, nil
+	}
+	return s, nil
+}
+
+type jsonSchemaReader struct {
+	root   map[string]any
+	active map[string]int
+}
+
+func (r *jsonSchemaReader) resolve(ref string) (map[string]any, error) {
+	if ref == "#" {
+		return r.root, nil
+	}
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			defs, _ := r.root[strings.TrimSuffix(strings.TrimPrefix(prefix, "#/"), "/")].(map[string]any)
+			if def, ok := defs[name].(map[string]any); ok {
+				return def, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: cannot resolve $ref %q", ErrUnsupportedJSONSchema, ref)
+}
+
+// read returns nil when a recursive reference reached the depth limit
+func (r *jsonSchemaReader) read(node map[string]any) (*genai.Schema, error) {
+	s := &genai.Schema{}
+	if ref, ok := node["$ref"].(string); ok {
+		if r.active[ref] >= DefaultSchemaRecursionDepth {
+			return nil, nil
+		}
+		target, err := r.resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		r.active[ref]++
+		resolved, err := r.read(target)
+		r.active[ref]--
+		if err != nil || resolved == nil {
+			return resolved, err
+		}
+		// keywords next to $ref refine the referenced schema
+		copied := *resolved
+		s = &copied
+	}
+
+	switch typ := node["type"].(type) {
+	case string:
+		if typ == "null" {
+			s.Nullable = boolPtr(true)
+		} else {
+			s.Type = genai.Type(strings.ToUpper(typ))
+		}
+	case []any:
+		var types []genai.Type
+		for _, t := range typ {
+			name, _ := t.(string)
+			if name == "null" {
+				s.Nullable = boolPtr(true)
+				continue
+			}
+			types = append(types, genai.Type(strings.ToUpper(name)))
+		}
+		if len(types) == 1 {
+			s.Type = types[0]
+		} else {
+			for _, t := range types {
+				s.AnyOf = append(s.AnyOf, &genai.Schema{Type: t})
+			}
+		}
+	}
+
+	if v, ok := node["title"].(string); ok {
+		s.Title = v
+	}
+	if v, ok := node["description"].(string); ok {
+		s.Description = v
+	}
+	if v, ok := node["format"].(string); ok {
+		s.Format = v
+	}
+	if v, ok := node["pattern"].(string); ok {
+		s.Pattern = v
+	}
+	if v, ok := node["const"]; ok {
+		s.Enum = []string{fmt.Sprint(v)}
+	}
+	if values, ok := node["enum"].([]any); ok {
+		s.Enum = nil
+		for _, v := range values {
+			if v == nil {
+				s.Nullable = boolPtr(true)
+				continue
+			}
+			s.Enum = append(s.Enum, fmt.Sprint(v))
+		}
+	}
+	if v, ok := node["default"]; ok {
+		s.Default = v
+	}
+	if v, ok := node["example"]; ok {
+		s.Example = v
+	}
+	if examples, ok := node["examples"].([]any); ok && len(examples) > 0 {
+		s.Example = examples[0]
+	}
+	for key, target := range map[string]**float64{"minimum": &s.Minimum, "maximum": &s.Maximum} {
+		if v, ok := toFloat64(node[key]); ok {
+			*target = &v
+		}
+	}
+	for key, target := range map[string]**int64{
+		"minLength": &s.MinLength, "maxLength": &s.MaxLength,
+		"minItems": &s.MinItems, "maxItems": &s.MaxItems,
+		"minProperties": &s.MinProperties, "maxProperties": &s.MaxProperties,
+	} {
+		if v, ok := toFloat64(node[key]); ok {
+			n := int64(v)
+			*target = &n
+		}
+	}
+
+	if items, ok := node["items"].(map[string]any); ok {
+		itemSchema, err := r.read(items)
+		if err != nil {
+			return nil, err
+		}
+		if itemSchema == nil {
+			return nil, nil
+		}
+		s.Items = itemSchema
+	}
+	if props, ok := node["properties"].(map[string]any); ok {
+		s.Properties = make(map[string]*genai.Schema, len(props))
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: property %q is not a schema", ErrUnsupportedJSONSchema, name)
+			}
+			propSchema, err := r.read(prop)
+			if err != nil {
+				return nil, err
+			}
+			if propSchema == nil { // recursion truncated
+				continue
+			}
+			s.Properties[name] = propSchema
+		}
+	}
+	if required, ok := node["required"]; ok {
+		s.Required = nil
+		for _, name := range toStrings(required) {
+			if _, ok := s.Properties[name]; ok || s.Properties == nil {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+
+	truncated := false
+	for _, key := range []string{"anyOf", "oneOf"} {
+		options, ok := node[key].([]any)
+		if !ok {
+			continue
+		}
+		for _, option := range options {
+			optionNode, ok := option.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s entry is not a schema", ErrUnsupportedJSONSchema, key)
+			}
+			if optionNode["type"] == "null" && len(optionNode) == 1 {
+				s.Nullable = boolPtr(true)
+				continue
+			}
+			optionSchema, err := r.read(optionNode)
+			if err != nil {
+				return nil, err
+			}
+			if optionSchema == nil {
+				truncated = true
+				continue
+			}
+			s.AnyOf = append(s.AnyOf, optionSchema)
+		}
+	}
+	if truncated && len(s.AnyOf) == 0 {
+		return nil, nil
+	}
+	if len(s.AnyOf) == 1 && s.Type == "" {
+		// a single option left next to null, e.g. {"anyOf":[{"$ref":...},{"type":"null"}]}
+		merged := *s.AnyOf[0]
+		if s.Description != "" {
+			merged.Description = s.Description
+		}
+		merged.Nullable = s.Nullable
+		s = &merged
+	}
+
+	if allOf, ok := node["allOf"].([]any); ok {
+		if len(allOf) != 1 {
+			return nil, fmt.Errorf("%w: allOf with %d schemas", ErrUnsupportedJSONSchema, len(allOf))
+		}
+		inner, ok := allOf[0].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: allOf entry is not a schema", ErrUnsupportedJSONSchema)
+		}
+		innerSchema, err := r.read(inner)
+		if err != nil || innerSchema == nil {
+			return innerSchema, err
+		}
+		if s.Description != "" {
+			innerSchema.Description = s.Description
+		}
+		s = innerSchema
+	}
+	return s, nil
+}
+
+func boolPtr(v bool) *bool { return &v }
+
+func toFloat64(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func toStrings(v any) []string {
+	switch values := v.(type) {
+	case []string:
+		return values
+	case []any:
+		out := make([]string, 0, len(values))
+		for _, value := range values {
+			if name, ok := value.(string); ok {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+type TreeNode struct {
+	Value    string      `json:"value"`
+	Children []*TreeNode `json:"children,omitempty" description:"child nodes"`
+}
+
+type Author struct {
+	Name  string `json:"name"`
+	Posts []Post `json:"posts"`
+}
+
+type Post struct {
+	Title    string    `json:"title"`
+	Author   *Author   `json:"author,omitempty"`
+	Comments []Comment `json:"comments"`
+}
+
+type Comment struct {
+	Text    string    `json:"text"`
+	Replies []Comment `json:"replies,omitempty"`
+	Post    *Post     `json:"post,omitempty"`
+}
+
+// schemaDepth follows the property path as long as it exists
+func schemaDepth(s *genai.Schema, path ...string) int {
+	depth := 0
+	for s != nil {
+		depth++
+		next := s
+		for _, key := range path {
+			if next == nil {
+				break
+			}
+			next = next.Properties[key]
+			if next != nil && next.Type == genai.TypeArray {
+				next = next.Items
+			}
+		}
+		s = next
+	}
+	return depth
+}
+
+func Test_buildSchemaFromTypeRecursive(t *testing.T) {
+	tree := BuildSchemaFromStruct(TreeNode{})
+	if got := schemaDepth(tree, "children"); got != DefaultSchemaRecursionDepth {
+		t.Errorf("tree expanded %d levels, want %d", got, DefaultSchemaRecursionDepth)
+	}
+
+	// Author -> Post -> Author is cut once Author repeats maxDepth times
+	author := BuildSchemaFromStruct(Author{})
+	if got := schemaDepth(author, "posts", "author"); got != DefaultSchemaRecursionDepth {
+		t.Errorf("author expanded %d levels, want %d", got, DefaultSchemaRecursionDepth)
+	}
+	comments := author.Properties["posts"].Items.Properties["comments"]
+	if comments == nil || comments.Items.Properties["text"] == nil {
+		t.Fatalf("comments should be expanded, got %+v", comments)
+	}
+}
+
+func TestBuildJSONSchemaFromStructRecursive(t *testing.T) {
+	tree, err := BuildJSONSchemaFromStruct(TreeNode{})
+	if err != nil {
+		t.Fatalf("BuildJSONSchemaFromStruct() error = %v", err)
+	}
+	if tree["$ref"] != "#/$defs/TreeNode" {
+		t.Errorf("root $ref = %v", tree["$ref"])
+	}
+	defs, _ := tree["$defs"].(map[string]any)
+	node, _ := defs["TreeNode"].(map[string]any)
+	if node == nil {
+		t.Fatalf("TreeNode definition missing: %v", tree)
+	}
+	children := node["properties"].(map[string]any)["children"].(map[string]any)
+	if children["description"] != "child nodes" {
+		t.Errorf("children description = %v", children["description"])
+	}
+	if items := children["items"].(map[string]any); items["$ref"] != "#/$defs/TreeNode" {
+		t.Errorf("children items = %v", items)
+	}
+
+	author, err := BuildJSONSchemaFromStruct(Author{})
+	if err != nil {
+		t.Fatalf("BuildJSONSchemaFromStruct() error = %v", err)
+	}
+	defs, _ = author["$defs"].(map[string]any)
+	for _, name := range []string{"Author", "Post", "Comment"} {
+		if _, ok := defs[name]; !ok {
+			t.Errorf("definition %s missing, got %v", name, defs)
+		}
+	}
+	post := defs["Post"].(map[string]any)["properties"].(map[string]any)
+	// pointer fields are nullable, the reference is wrapped in an anyOf with null
+	authorRef := post["author"].(map[string]any)["anyOf"].([]any)
+	if len(authorRef) != 2 || authorRef[0].(map[string]any)["$ref"] != "#/$defs/Author" {
+		t.Errorf("post.author = %v", post["author"])
+	}
+	if post["title"].(map[string]any)["type"] != "string" {
+		t.Errorf("post.title = %v", post["title"])
+	}
+}
+
+type Inventory struct {
+	Name   string            `json:"name" description:"store name" minLength:"1"`
+	Status string            `json:"status" enum:"open,closed"`
+	Stock  map[string]int    `json:"stock"`
+	Owner  *Author           `json:"owner,omitempty"`
+	Rating float64           `json:"rating" minimum:"0" maximum:"5"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func TestBuildJSONSchemaFromStruct(t *testing.T) {
+	js, err := BuildJSONSchemaFromStruct(Inventory{})
+	if err != nil {
+		t.Fatalf("BuildJSONSchemaFromStruct() error = %v", err)
+	}
+	if js["$schema"] != JSONSchemaDraft || js["type"] != "object" {
+		t.Errorf("root = %v", js)
+	}
+	props := js["properties"].(map[string]any)
+	stock := props["stock"].(map[string]any)
+	if additional, ok := stock["additionalProperties"].(map[string]any); !ok || additional["type"] != "integer" {
+		t.Errorf("stock additionalProperties = %v", stock)
+	}
+	status := props["status"].(map[string]any)
+	if enum := status["enum"].([]any); len(enum) != 2 || enum[0] != "open" {
+		t.Errorf("status enum = %v", status["enum"])
+	}
+	if name := props["name"].(map[string]any); name["minLength"] != int64(1) || name["description"] != "store name" {
+		t.Errorf("name = %v", name)
+	}
+}
+
+func TestJSONSchemaConverters(t *testing.T) {
+	js, err := BuildJSONSchemaFromStruct(Inventory{})
+	if err != nil {
+		t.Fatalf("BuildJSONSchemaFromStruct() error = %v", err)
+	}
+	// go through encoding/json like a schema loaded from a file
+	encoded, _ := json.Marshal(js)
+	var decoded map[string]any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	s, err := JSONSchemaToGenAISchema(decoded)
+	if err != nil {
+		t.Fatalf("JSONSchemaToGenAISchema() error = %v", err)
+	}
+	direct := BuildSchemaFromStruct(Inventory{})
+	if s.Type != genai.TypeObject || len(s.Properties) != len(direct.Properties) {
+		t.Fatalf("converted schema = %+v", s)
+	}
+	if len(s.Required) != len(direct.Required) {
+		t.Errorf("required = %v, want %v", s.Required, direct.Required)
+	}
+	if rating := s.Properties["rating"]; rating.Type != genai.TypeNumber || *rating.Maximum != 5 {
+		t.Errorf("rating = %+v", rating)
+	}
+	if name := s.Properties["name"]; *name.MinLength != 1 || name.Description != "store name" {
+		t.Errorf("name = %+v", name)
+	}
+	// the nullable $ref to the recursive Author is inlined with a bounded depth
+	owner := s.Properties["owner"]
+	if owner == nil || owner.Nullable == nil || !*owner.Nullable || owner.Properties["posts"] == nil {
+		t.Fatalf("owner = %+v", owner)
+	}
+	if got := schemaDepth(owner, "posts", "author"); got != DefaultSchemaRecursionDepth {
+		t.Errorf("owner expanded %d levels, want %d", got, DefaultSchemaRecursionDepth)
+	}
+
+	back := GenAISchemaToJSONSchema(direct)
+	if back["type"] != "object" || back["properties"].(map[string]any)["status"] == nil {
+		t.Errorf("GenAISchemaToJSONSchema() = %v", back)
+	}
+
+	if _, err := JSONSchemaToGenAISchema(map[string]any{"$ref": "#/$defs/Missing"}); !errors.Is(err, ErrUnsupportedJSONSchema) {
+		t.Errorf("unresolved $ref error = %v", err)
+	}
+	nullable, err := JSONSchemaToGenAISchema(map[string]any{"type": []any{"string", "null"}, "enum": []any{"a", nil}})
+	if err != nil || nullable.Type != genai.TypeString || !*nullable.Nullable || len(nullable.Enum) != 1 {
+		t.Errorf("nullable = %+v, %v", nullable, err)
+	}
+}
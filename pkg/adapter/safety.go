@@ -0,0 +1,178 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"google.golang.org/genai"
+)
+
+var (
+	ErrInvalidSafetySetting = errors.New("invalid safety setting")
+	// ErrBlocked matches every BlockedError with errors.Is
+	ErrBlocked = errors.New("response blocked")
+)
+
+var harmCategories = map[genai.HarmCategory]bool{
+	genai.HarmCategoryHarassment:            true,
+	genai.HarmCategoryHateSpeech:            true,
+	genai.HarmCategorySexuallyExplicit:      true,
+	genai.HarmCategoryDangerousContent:      true,
+	genai.HarmCategoryCivicIntegrity:        true,
+	genai.HarmCategoryImageHate:             true,
+	genai.HarmCategoryImageDangerousContent: true,
+	genai.HarmCategoryImageHarassment:       true,
+	genai.HarmCategoryImageSexuallyExplicit: true,
+	genai.HarmCategoryJailbreak:             true,
+	genai.HarmCategoryUnspecified:           true,
+}
+
+var harmThresholds = map[genai.HarmBlockThreshold]bool{
+	genai.HarmBlockThresholdBlockLowAndAbove:    true,
+	genai.HarmBlockThresholdBlockMediumAndAbove: true,
+	genai.HarmBlockThresholdBlockOnlyHigh:       true,
+	genai.HarmBlockThresholdBlockNone:           true,
+	genai.HarmBlockThresholdOff:                 true,
+	genai.HarmBlockThresholdUnspecified:         true,
+}
+
+// blockedFinishReasons are the finish reasons meaning the answer was withheld
+var blockedFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:                 true,
+	genai.FinishReasonRecitation:             true,
+	genai.FinishReasonBlocklist:              true,
+	genai.FinishReasonProhibitedContent:      true,
+	genai.FinishReasonSPII:                   true,
+	genai.FinishReasonImageSafety:            true,
+	genai.FinishReasonImageProhibitedContent: true,
+}
+
+// GeminiSafetySettings validates the settings and converts them, a later setting for
+// the same category replaces the earlier one
+func GeminiSafetySettings(settings ...[]genaiconfig.SafetySetting) ([]*genai.SafetySetting, error) {
+	var out []*genai.SafetySetting
+	index := map[genai.HarmCategory]int{}
+	for _, group := range settings {
+		for _, setting := range group {
+			converted, err := geminiSafetySetting(setting)
+			if err != nil {
+				return nil, err
+			}
+			if i, ok := index[converted.Category]; ok {
+				out[i] = converted
+				continue
+			}
+			index[converted.Category] = len(out)
+			out = append(out, converted)
+		}
+	}
+	return out, nil
+}
+
+func geminiSafetySetting(setting genaiconfig.SafetySetting) (*genai.SafetySetting, error) {
+	category := genai.HarmCategory(strings.ToUpper(setting.Category))
+	if !harmCategories[category] {
+		return nil, fmt.Errorf("%w: unknown category %q", ErrInvalidSafetySetting, setting.Category)
+	}
+	threshold := genai.HarmBlockThreshold(strings.ToUpper(setting.Threshold))
+	if !harmThresholds[threshold] {
+		return nil, fmt.Errorf("%w: unknown threshold %q for %s", ErrInvalidSafetySetting, setting.Threshold, category)
+	}
+	converted := &genai.SafetySetting{Category: category, Threshold: threshold}
+	switch method := genai.HarmBlockMethod(strings.ToUpper(setting.Method)); method {
+	case "":
+	case genai.HarmBlockMethodSeverity, genai.HarmBlockMethodProbability:
+		converted.Method = method
+	default:
+		return nil, fmt.Errorf("%w: unknown method %q for %s", ErrInvalidSafetySetting, setting.Method, category)
+	}
+	return converted, nil
+}
+
+// BlockedError is returned when the prompt or the answer was withheld by the safety
+// filters, Ratings holds the category ratings when the response reported them
+type BlockedError struct {
+	// Reason is the block reason of the prompt or the finish reason of the answer
+	Reason  string
+	Message string
+	// Prompt is true when the prompt itself was blocked
+	Prompt  bool
+	Ratings []genaiconfig.SafetyRating
+}
+
+func (e *BlockedError) Error() string {
+	subject := "response"
+	if e.Prompt {
+		subject = "prompt"
+	}
+	msg := fmt.Sprintf("%s blocked: %s", subject, e.Reason)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	var flagged []string
+	for _, rating := range e.Ratings {
+		if rating.Blocked {
+			flagged = append(flagged, rating.Category)
+		}
+	}
+	if len(flagged) > 0 {
+		msg += " (" + strings.Join(flagged, ", ") + ")"
+	}
+	return msg
+}
+
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+var blockedPromptReasons = map[genai.BlockedReason]bool{
+	genai.BlockedReasonSafety:            true,
+	genai.BlockedReasonOther:             true,
+	genai.BlockedReasonBlocklist:         true,
+	genai.BlockedReasonProhibitedContent: true,
+	genai.BlockedReasonImageSafety:       true,
+	genai.BlockedReasonModelArmor:        true,
+	genai.BlockedReasonJailbreak:         true,
+}
+
+// IsBlockedPromptReason reports whether the reason is one the prompt feedback blocks with
+func IsBlockedPromptReason(reason string) bool {
+	return blockedPromptReasons[genai.BlockedReason(reason)]
+}
+
+// IsBlockedFinishReason reports whether the finish reason means the answer was withheld
+func IsBlockedFinishReason(reason genai.FinishReason) bool {
+	return blockedFinishReasons[reason]
+}
+
+// BlockedErrorFromGemini returns the BlockedError of a response, nil when it was not blocked
+func BlockedErrorFromGemini(res *genai.GenerateContentResponse) *BlockedError {
+	if res == nil {
+		return nil
+	}
+	if len(res.Candidates) == 0 && res.PromptFeedback != nil && res.PromptFeedback.BlockReason != "" {
+		return &BlockedError{
+			Reason:  string(res.PromptFeedback.BlockReason),
+			Message: res.PromptFeedback.BlockReasonMessage,
+			Prompt:  true,
+			Ratings: SafetyRatingsFromGemini(res.PromptFeedback.SafetyRatings),
+		}
+	}
+	if len(res.Candidates) > 0 {
+		return blockedCandidate(res.Candidates[0])
+	}
+	return nil
+}
+
+func blockedCandidate(c *genai.Candidate) *BlockedError {
+	if c == nil || !IsBlockedFinishReason(c.FinishReason) {
+		return nil
+	}
+	return &BlockedError{
+		Reason:  string(c.FinishReason),
+		Message: c.FinishMessage,
+		Ratings: SafetyRatingsFromGemini(c.SafetyRatings),
+	}
+}
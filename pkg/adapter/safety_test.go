@@ -0,0 +1,101 @@
+package adapter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"google.golang.org/genai"
+)
+
+func TestGeminiSafetySettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings [][]genaiconfig.SafetySetting
+		want     []genai.SafetySetting
+		wantErr  bool
+	}{
+		{
+			name: "Later settings replace the same category",
+			settings: [][]genaiconfig.SafetySetting{
+				{
+					{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_LOW_AND_ABOVE"},
+					{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_ONLY_HIGH"},
+				},
+				{{Category: "harm_category_harassment", Threshold: "block_none", Method: "severity"}},
+			},
+			want: []genai.SafetySetting{
+				{Category: genai.HarmCategoryHarassment, Threshold: genai.HarmBlockThresholdBlockNone, Method: genai.HarmBlockMethodSeverity},
+				{Category: genai.HarmCategoryHateSpeech, Threshold: genai.HarmBlockThresholdBlockOnlyHigh},
+			},
+		},
+		{
+			name:     "Unknown category",
+			settings: [][]genaiconfig.SafetySetting{{{Category: "HARM_CATEGORY_RUDENESS", Threshold: "BLOCK_NONE"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "Unknown threshold",
+			settings: [][]genaiconfig.SafetySetting{{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_SOME"}}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GeminiSafetySettings(tt.settings...)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSafetySetting) {
+					t.Errorf("GeminiSafetySettings() error = %v, want ErrInvalidSafetySetting", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GeminiSafetySettings() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("GeminiSafetySettings() = %d settings, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if *got[i] != tt.want[i] {
+					t.Errorf("setting %d = %+v, want %+v", i, *got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBlockedResponses(t *testing.T) {
+	ratings := []*genai.SafetyRating{
+		{Category: genai.HarmCategoryDangerousContent, Probability: genai.HarmProbabilityHigh, Blocked: true},
+		{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityNegligible},
+	}
+
+	_, err := ModelResponseFromGeminiResponse(&genai.GenerateContentResponse{
+		PromptFeedback: &genai.GenerateContentResponsePromptFeedback{
+			BlockReason:   genai.BlockedReasonSafety,
+			SafetyRatings: ratings,
+		},
+	})
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || !errors.Is(err, ErrBlocked) {
+		t.Fatalf("prompt block error = %v, want a BlockedError", err)
+	}
+	if !blocked.Prompt || blocked.Reason != "SAFETY" || len(blocked.Ratings) != 2 {
+		t.Errorf("prompt block = %+v", blocked)
+	}
+	if blocked.Error() != "prompt blocked: SAFETY (HARM_CATEGORY_DANGEROUS_CONTENT)" {
+		t.Errorf("Error() = %q", blocked.Error())
+	}
+
+	_, err = ModelResponseFromGeminiContent([]*genai.Candidate{
+		{FinishReason: genai.FinishReasonSafety, SafetyRatings: ratings},
+	})
+	if !errors.As(err, &blocked) || blocked.Prompt || blocked.Reason != "SAFETY" || !blocked.Ratings[0].Blocked {
+		t.Errorf("candidate block error = %v", err)
+	}
+
+	_, err = ModelResponseFromGeminiContent([]*genai.Candidate{{FinishReason: genai.FinishReasonMaxTokens}})
+	if err == nil || errors.Is(err, ErrBlocked) {
+		t.Errorf("empty MAX_TOKENS candidate error = %v, want a non blocked error", err)
+	}
+}
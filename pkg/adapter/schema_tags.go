@@ -0,0 +1,210 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	genai "google.golang.org/genai"
+)
+
+var ErrInvalidSchemaTag = errors.New("invalid schema tag")
+
+// schemaFormats are the formats each primitive type accepts in the format tag
+var schemaFormats = map[genai.Type]map[string]bool{
+	genai.TypeString: {
+		"date-time": true, "date": true, "time": true, "duration": true, "email": true, "uuid": true,
+		"uri": true, "hostname": true, "ipv4": true, "ipv6": true, "byte": true, "binary": true, "enum": true,
+	},
+	genai.TypeInteger: {"int32": true, "int64": true},
+	genai.TypeNumber:  {"float": true, "double": true},
+}
+
+// applyFieldTags maps the constraint tags of a struct field onto its schema:
+//
+//	description, minLength, maxLength, minItems, maxItems,
+//	enum:"a,b,c", format:"date-time", pattern:"^[a-z]+$",
+//	minimum:"0", maximum:"100", example:"...", default:"..."
+//
+// enum, format, pattern, minimum and maximum on a slice of primitives constrain its items.
+// Tags that do not parse or do not fit the field type are skipped and returned as errors.
+func applyFieldTags(parent reflect.Type, f reflect.StructField, s *genai.Schema) []error {
+	var errs []error
+	fail := func(tag string, value string, reason string) {
+		errs = append(errs, fmt.Errorf("%w: %s.%s %s:%q %s", ErrInvalidSchemaTag, parent.Name(), f.Name, tag, value, reason))
+	}
+	lookup := func(tag string) (string, bool) {
+		value, ok := f.Tag.Lookup(tag)
+		return value, ok && value != ""
+	}
+
+	if desc, ok := lookup("description"); ok {
+		s.Description = desc
+	}
+
+	// --- lengths and item counts ---
+	setCount := func(tag string, target **int64, allowed genai.Type) {
+		value, ok := lookup(tag)
+		if !ok {
+			return
+		}
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || v < 0 {
+			fail(tag, value, "must be a non negative integer")
+			return
+		}
+		if s.Type != allowed {
+			fail(tag, value, fmt.Sprintf("only applies to %s fields", allowed))
+			return
+		}
+		*target = &v
+	}
+	setCount("minLength", &s.MinLength, genai.TypeString)
+	setCount("maxLength", &s.MaxLength, genai.TypeString)
+	setCount("minItems", &s.MinItems, genai.TypeArray)
+	setCount("maxItems", &s.MaxItems, genai.TypeArray)
+	if s.MinLength != nil && s.MaxLength != nil && *s.MinLength > *s.MaxLength {
+		fail("minLength", f.Tag.Get("minLength"), "is greater than maxLength")
+	}
+	if s.MinItems != nil && s.MaxItems != nil && *s.MinItems > *s.MaxItems {
+		fail("minItems", f.Tag.Get("minItems"), "is greater than maxItems")
+	}
+
+	// value constraints target the items of a primitive slice
+	scalar := s
+	if s.Type == genai.TypeArray && s.Items != nil && isPrimitiveType(s.Items.Type) {
+		scalar = s.Items
+	}
+
+	if value, ok := lookup("enum"); ok {
+		if scalar.Type != genai.TypeString && scalar.Type != genai.TypeInteger && scalar.Type != genai.TypeNumber {
+			fail("enum", value, "only applies to string and numeric fields")
+		} else {
+			values := splitTagList(value)
+			valid := true
+			for _, v := range values {
+				if _, err := parseTagValue(scalar, v); err != nil {
+					fail("enum", value, fmt.Sprintf("value %q is not a valid %s", v, scalar.Type))
+					valid = false
+				}
+			}
+			if valid {
+				scalar.Enum = values
+			}
+		}
+	}
+
+	if value, ok := lookup("format"); ok {
+		if !schemaFormats[scalar.Type][value] {
+			fail("format", value, fmt.Sprintf("is not a supported %s format", scalar.Type))
+		} else {
+			scalar.Format = value
+		}
+	}
+
+	if value, ok := lookup("pattern"); ok {
+		if scalar.Type != genai.TypeString {
+			fail("pattern", value, "only applies to string fields")
+		} else if _, err := regexp.Compile(value); err != nil {
+			fail("pattern", value, err.Error())
+		} else {
+			scalar.Pattern = value
+		}
+	}
+
+	setBound := func(tag string, target **float64) {
+		value, ok := lookup(tag)
+		if !ok {
+			return
+		}
+		if scalar.Type != genai.TypeInteger && scalar.Type != genai.TypeNumber {
+			fail(tag, value, "only applies to numeric fields")
+			return
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			fail(tag, value, "must be a number")
+			return
+		}
+		if scalar.Type == genai.TypeInteger && v != math.Trunc(v) {
+			fail(tag, value, "must be an integer for integer fields")
+			return
+		}
+		*target = &v
+	}
+	setBound("minimum", &scalar.Minimum)
+	setBound("maximum", &scalar.Maximum)
+	if scalar.Minimum != nil && scalar.Maximum != nil && *scalar.Minimum > *scalar.Maximum {
+		fail("minimum", f.Tag.Get("minimum"), "is greater than maximum")
+	}
+
+	setValue := func(tag string, target *any) {
+		value, ok := lookup(tag)
+		if !ok {
+			return
+		}
+		v, err := parseTagValue(s, value)
+		if err != nil {
+			fail(tag, value, fmt.Sprintf("is not a valid %s", s.Type))
+			return
+		}
+		if len(s.Enum) > 0 && !containsString(s.Enum, value) {
+			fail(tag, value, "is not one of the enum values")
+			return
+		}
+		*target = v
+	}
+	setValue("example", &s.Example)
+	setValue("default", &s.Default)
+
+	return errs
+}
+
+// parseTagValue converts the tag text to a value of the schema type, objects and
+// arrays are read as json
+func parseTagValue(s *genai.Schema, value string) (any, error) {
+	switch s.Type {
+	case genai.TypeString:
+		return value, nil
+	case genai.TypeInteger:
+		return strconv.ParseInt(value, 10, 64)
+	case genai.TypeNumber:
+		return strconv.ParseFloat(value, 64)
+	case genai.TypeBoolean:
+		return strconv.ParseBool(value)
+	}
+	var v any
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func isPrimitiveType(t genai.Type) bool {
+	return t == genai.TypeString || t == genai.TypeInteger || t == genai.TypeNumber || t == genai.TypeBoolean
+}
+
+func splitTagList(value string) []string {
+	parts := strings.Split(value, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package adapter
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+type TaggedRequest struct {
+	Status   string    `json:"status" enum:"open, closed" default:"open"`
+	Email    string    `json:"email" format:"email" pattern:"^.+@.+$" example:"a@b.co"`
+	Age      int       `json:"age" minimum:"0" maximum:"150" example:"42"`
+	Ratio    float64   `json:"ratio" minimum:"0.5" format:"double"`
+	Tags     []string  `json:"tags" enum:"a,b" minItems:"1"`
+	Created  string    `json:"created" format:"date-time"`
+	Scores   []float32 `json:"scores" maximum:"1"`
+	Disabled bool      `json:"disabled" default:"false"`
+}
+
+type InvalidTaggedRequest struct {
+	Count   int    `json:"count" pattern:"^[0-9]+$"`
+	Name    string `json:"name" minimum:"1"`
+	Level   int    `json:"level" enum:"low,high"`
+	Created string `json:"created" format:"int64"`
+	Limit   int    `json:"limit" minimum:"10" maximum:"1"`
+	Mode    string `json:"mode" enum:"a,b" default:"c"`
+	Size    int    `json:"size" example:"big"`
+}
+
+func Test_applyFieldTags(t *testing.T) {
+	schema, err := BuildSchemaFromStructStrict(TaggedRequest{})
+	if err != nil {
+		t.Fatalf("BuildSchemaFromStructStrict() error = %v", err)
+	}
+	props := schema.Properties
+
+	if got := props["status"].Enum; len(got) != 2 || got[0] != "open" || got[1] != "closed" {
+		t.Errorf("status enum = %v", got)
+	}
+	if props["status"].Default != "open" {
+		t.Errorf("status default = %v", props["status"].Default)
+	}
+	if props["email"].Format != "email" || props["email"].Pattern != "^.+@.+$" || props["email"].Example != "a@b.co" {
+		t.Errorf("email schema = %+v", props["email"])
+	}
+	if props["age"].Minimum == nil || *props["age"].Minimum != 0 || props["age"].Maximum == nil || *props["age"].Maximum != 150 {
+		t.Errorf("age bounds = %v %v", props["age"].Minimum, props["age"].Maximum)
+	}
+	if props["age"].Example != int64(42) {
+		t.Errorf("age example = %#v, want int64(42)", props["age"].Example)
+	}
+	if props["ratio"].Format != "double" || *props["ratio"].Minimum != 0.5 {
+		t.Errorf("ratio schema = %+v", props["ratio"])
+	}
+	if items := props["tags"].Items; items == nil || len(items.Enum) != 2 || props["tags"].Enum != nil {
+		t.Errorf("tags enum should constrain the items, got %+v", props["tags"])
+	}
+	if items := props["scores"].Items; items == nil || items.Type != genai.TypeNumber || items.Maximum == nil {
+		t.Errorf("scores maximum should constrain the items, got %+v", props["scores"])
+	}
+	if props["disabled"].Default != false {
+		t.Errorf("disabled default = %#v", props["disabled"].Default)
+	}
+}
+
+func Test_applyFieldTagsInvalid(t *testing.T) {
+	schema, err := BuildSchemaFromStructStrict(InvalidTaggedRequest{})
+	if err == nil {
+		t.Fatalf("BuildSchemaFromStructStrict() expected an error")
+	}
+	if !errors.Is(err, ErrInvalidSchemaTag) {
+		t.Errorf("error should wrap ErrInvalidSchemaTag, got %v", err)
+	}
+	// invalid tags are skipped, the lenient builder still returns the schema
+	lenient := BuildSchemaFromStruct(InvalidTaggedRequest{})
+	for _, s := range []*genai.Schema{schema, lenient} {
+		props := s.Properties
+		if props["count"].Pattern != "" || props["name"].Minimum != nil || props["level"].Enum != nil ||
+			props["created"].Format != "" || props["mode"].Default != nil || props["size"].Example != nil {
+			t.Errorf("invalid tags should not be applied, got %+v", props)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package adapter
+
+import (
+	"encoding"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	genai "google.golang.org/genai"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonNumberType    = reflect.TypeOf(json.Number(""))
+	bigIntType        = reflect.TypeOf(big.Int{})
+	bigFloatType      = reflect.TypeOf(big.Float{})
+	bigRatType        = reflect.TypeOf(big.Rat{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// knownTypeSchema returns the schema of types whose json encoding differs from their
+// go shape, nil means the type is built from its kind
+func knownTypeSchema(t reflect.Type) *genai.Schema {
+	switch t {
+	case timeType:
+		return &genai.Schema{Type: genai.TypeString, Format: "date-time"}
+	case durationType:
+		// encoding/json writes durations as their int64 nanoseconds
+		return &genai.Schema{Type: genai.TypeInteger, Format: "int64", Description: "duration in nanoseconds"}
+	case rawMessageType:
+		return &genai.Schema{}
+	case jsonNumberType:
+		return &genai.Schema{Type: genai.TypeNumber}
+	case bigIntType:
+		return &genai.Schema{Type: genai.TypeInteger}
+	case bigFloatType:
+		return &genai.Schema{Type: genai.TypeString, Description: "decimal number"}
+	case bigRatType:
+		return &genai.Schema{Type: genai.TypeString, Description: "fraction such as 3/4"}
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		// []byte is base64 encoded
+		return &genai.Schema{Type: genai.TypeString, Format: "byte"}
+	}
+	if implements(t, textMarshalerType) && !implements(t, jsonMarshalerType) {
+		return &genai.Schema{Type: genai.TypeString}
+	}
+	return nil
+}
+
+func implements(t reflect.Type, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func mapKeyKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	}
+	return "string"
+}
+
+func schemaTypeName(s *genai.Schema) string {
+	if s.Type == "" {
+		return "any"
+	}
+	return strings.ToLower(string(s.Type))
+}
+
+// schemaField is a struct field as encoding/json sees it after flattening embedded structs
+type schemaField struct {
+	name     string
+	field    reflect.StructField
+	parent   reflect.Type
+	optional bool
+	asString bool
+	tagged   bool
+	depth    int
+}
+
+// structFields lists the json fields of t: `json:"-"` and unexported fields are
+// skipped, untagged embedded structs are flattened and name conflicts are resolved
+// like encoding/json (the shallowest field wins, then the tagged one, else none)
+func structFields(t reflect.Type) []schemaField {
+	var all []schemaField
+	collectStructFields(t, 0, false, map[reflect.Type]bool{}, &all)
+
+	byName := map[string][]int{}
+	order := []string{}
+	for index, f := range all {
+		if _, ok := byName[f.name]; !ok {
+			order = append(order, f.name)
+		}
+		byName[f.name] = append(byName[f.name], index)
+	}
+	out := make([]schemaField, 0, len(order))
+	for _, name := range order {
+		if f, ok := dominantField(all, byName[name]); ok {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func collectStructFields(t reflect.Type, depth int, optional bool, visited map[reflect.Type]bool, out *[]schemaField) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+
+		if f.Anonymous {
+			embedded := f.Type
+			isPointer := embedded.Kind() == reflect.Pointer
+			if isPointer {
+				embedded = embedded.Elem()
+			}
+			if !f.IsExported() && embedded.Kind() != reflect.Struct {
+				continue
+			}
+			if name == "" && embedded.Kind() == reflect.Struct && knownTypeSchema(embedded) == nil {
+				// promote the fields, a nil embedded pointer omits them
+				collectStructFields(embedded, depth+1, optional || isPointer, visited, out)
+				continue
+			}
+		}
+		if !f.IsExported() { // skip unexported
+			continue
+		}
+
+		field := schemaField{
+			name:     name,
+			field:    f,
+			parent:   t,
+			optional: optional,
+			tagged:   name != "",
+			depth:    depth,
+		}
+		if field.name == "" {
+			field.name = f.Name
+		}
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "omitempty", "omitzero":
+				field.optional = true
+			case "string":
+				field.asString = true
+			}
+		}
+		*out = append(*out, field)
+	}
+}
+
+func dominantField(all []schemaField, indexes []int) (schemaField, bool) {
+	if len(indexes) == 1 {
+		return all[indexes[0]], true
+	}
+	minDepth := all[indexes[0]].depth
+	for _, index := range indexes {
+		if all[index].depth < minDepth {
+			minDepth = all[index].depth
+		}
+	}
+	var candidates []schemaField
+	for _, index := range indexes {
+		if all[index].depth == minDepth {
+			candidates = append(candidates, all[index])
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	var tagged []schemaField
+	for _, c := range candidates {
+		if c.tagged {
+			tagged = append(tagged, c)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return schemaField{}, false
+}
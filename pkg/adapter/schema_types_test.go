@@ -0,0 +1,139 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+type AuditFields struct {
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedBy string    `json:"updated_by"`
+}
+
+type Owner struct {
+	OwnerID string `json:"owner_id"`
+}
+
+type Labels struct {
+	ID   string `json:"id"`
+	Note string `json:"note"`
+}
+
+type TypedRecord struct {
+	AuditFields
+	*Owner
+	Labels
+	ID       int             `json:"id"`
+	Attrs    map[string]int  `json:"attrs"`
+	Timeout  time.Duration   `json:"timeout"`
+	Payload  json.RawMessage `json:"payload"`
+	Amount   *big.Int        `json:"amount"`
+	Price    big.Float       `json:"price"`
+	Data     []byte          `json:"data"`
+	Addr     netip.Addr      `json:"addr"`
+	Count    uint32          `json:"count,omitzero"`
+	Quoted   int64           `json:"quoted,string"`
+	Extra    any             `json:"extra,omitempty"`
+	Ignored  string          `json:"-"`
+	Dash     string          `json:"-,"`
+	internal string
+	Nested   map[string]Labels `json:"nested"`
+}
+
+func Test_buildSchemaFromTypeKnownTypes(t *testing.T) {
+	s := BuildSchemaFromStruct(TypedRecord{})
+	props := s.Properties
+
+	tests := []struct {
+		name       string
+		wantType   genai.Type
+		wantFormat string
+	}{
+		{"created_at", genai.TypeString, "date-time"},
+		{"updated_by", genai.TypeString, ""},
+		{"owner_id", genai.TypeString, ""},
+		{"note", genai.TypeString, ""},
+		{"id", genai.TypeInteger, ""},
+		{"attrs", genai.TypeObject, ""},
+		{"timeout", genai.TypeInteger, "int64"},
+		{"payload", "", ""},
+		{"amount", genai.TypeInteger, ""},
+		{"price", genai.TypeString, ""},
+		{"data", genai.TypeString, "byte"},
+		{"addr", genai.TypeString, ""},
+		{"count", genai.TypeInteger, ""},
+		{"quoted", genai.TypeString, ""},
+		{"extra", "", ""},
+		{"-", genai.TypeString, ""},
+		{"nested", genai.TypeObject, ""},
+	}
+	for _, tt := range tests {
+		prop, ok := props[tt.name]
+		if !ok {
+			t.Errorf("property %s not found", tt.name)
+			continue
+		}
+		if prop.Type != tt.wantType || prop.Format != tt.wantFormat {
+			t.Errorf("property %s = %s/%s, want %s/%s", tt.name, prop.Type, prop.Format, tt.wantType, tt.wantFormat)
+		}
+	}
+	if len(props) != len(tests) {
+		t.Errorf("got %d properties %v, want %d", len(props), s.PropertyOrdering, len(tests))
+	}
+	for _, skipped := range []string{"Ignored", "internal", "AuditFields", "Owner", "Labels"} {
+		if _, ok := props[skipped]; ok {
+			t.Errorf("property %s should not be in the schema", skipped)
+		}
+	}
+	// the outer id shadows the promoted Labels.ID
+	if props["id"].Type != genai.TypeInteger {
+		t.Errorf("outer id should win over the embedded one")
+	}
+	if props["count"].Minimum == nil || *props["count"].Minimum != 0 {
+		t.Errorf("unsigned count should have minimum 0")
+	}
+
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	for name, want := range map[string]bool{"created_at": true, "owner_id": false, "count": false, "extra": false, "id": true} {
+		if required[name] != want {
+			t.Errorf("required[%s] = %v, want %v", name, required[name], want)
+		}
+	}
+}
+
+func TestBuildSchemaFromStructStrictRejectsMaps(t *testing.T) {
+	type withMap struct {
+		Name   string                      `json:"name"`
+		Scores []map[string]float64        `json:"scores"`
+		Nested struct{ Attrs map[int]any } `json:"nested"`
+	}
+	// maps stay objects without properties unless rejection is asked for
+	schema, err := BuildSchemaFromStructStrict(withMap{})
+	if err != nil {
+		t.Fatalf("BuildSchemaFromStructStrict() error = %v", err)
+	}
+	if scores := schema.Properties["scores"].Items; scores.Type != genai.TypeObject || len(scores.Properties) != 0 {
+		t.Errorf("scores items = %+v, want an object without properties", scores)
+	}
+	_, err = BuildSchemaFromStructStrict(withMap{}, SchemaOptions{RejectMaps: true})
+	if !errors.Is(err, ErrUnsupportedGenAIType) {
+		t.Fatalf("BuildSchemaFromStructStrict() error = %v, want ErrUnsupportedGenAIType", err)
+	}
+	if !strings.Contains(err.Error(), "map[string]float64") || !strings.Contains(err.Error(), "map[int]interface {}") {
+		t.Errorf("error = %v, want both map types reported", err)
+	}
+	// the json schema keeps supporting maps through additionalProperties
+	if _, err := BuildJSONSchemaFromStruct(withMap{}); err != nil {
+		t.Errorf("BuildJSONSchemaFromStruct() error = %v", err)
+	}
+}
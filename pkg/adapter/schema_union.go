@@ -0,0 +1,224 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	genai "google.golang.org/genai"
+)
+
+var (
+	ErrUnionNotRegistered  = errors.New("union type is not registered")
+	ErrUnknownUnionVariant = errors.New("unknown union variant")
+	ErrInvalidUnion        = errors.New("invalid union registration")
+)
+
+type unionVariant struct {
+	tag string
+	typ reflect.Type
+	// discriminator is only set on the variants waiting for their $defs entry
+	discriminator string
+}
+
+type unionInfo struct {
+	discriminator string
+	variants      []unionVariant
+}
+
+var (
+	unionsMu sync.RWMutex
+	unions   = map[reflect.Type]*unionInfo{}
+)
+
+// RegisterUnion declares the interface I as a sealed union: variants maps each value of
+// the discriminator json field to an implementation (a value or a pointer). Schemas of
+// I, or of Union[I] fields, become an AnyOf of the variants with the discriminator as
+// a required enum property.
+//
+//	adapter.RegisterUnion[Shape]("kind", map[string]Shape{"circle": Circle{}, "square": &Square{}})
+func RegisterUnion[I any](discriminator string, variants map[string]I) error {
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		return fmt.Errorf("%w: %s is not an interface", ErrInvalidUnion, iface)
+	}
+	if discriminator == "" || len(variants) == 0 {
+		return fmt.Errorf("%w: %s needs a discriminator and at least one variant", ErrInvalidUnion, iface)
+	}
+	info := &unionInfo{discriminator: discriminator}
+	for tag, variant := range variants {
+		typ := reflect.TypeOf(variant)
+		if tag == "" || typ == nil {
+			return fmt.Errorf("%w: %s has an empty tag or nil variant", ErrInvalidUnion, iface)
+		}
+		if baseType(typ).Kind() != reflect.Struct {
+			return fmt.Errorf("%w: variant %q of %s is not a struct", ErrInvalidUnion, tag, iface)
+		}
+		info.variants = append(info.variants, unionVariant{tag: tag, typ: typ})
+	}
+	sort.Slice(info.variants, func(i, j int) bool { return info.variants[i].tag < info.variants[j].tag })
+
+	unionsMu.Lock()
+	defer unionsMu.Unlock()
+	unions[iface] = info
+	return nil
+}
+
+func lookupUnion(iface reflect.Type) (*unionInfo, bool) {
+	unionsMu.RLock()
+	defer unionsMu.RUnlock()
+	info, ok := unions[iface]
+	return info, ok
+}
+
+// unionSchema is the AnyOf of the variants, each tagged with its discriminator value
+func (b *schemaBuilder) unionSchema(info *unionInfo) *genai.Schema {
+	s := &genai.Schema{AnyOf: make([]*genai.Schema, 0, len(info.variants))}
+	for _, variant := range info.variants {
+		option := b.build(baseType(variant.typ))
+		if option == nil {
+			continue
+		}
+		if name, isRef := b.refs[option]; isRef {
+			// recursive variants are still being built, their $defs entry is tagged once complete
+			b.refTags[name] = append(b.refTags[name], unionVariant{tag: variant.tag, typ: variant.typ, discriminator: info.discriminator})
+		} else {
+			tagVariantSchema(option, info.discriminator, variant.tag)
+		}
+		s.AnyOf = append(s.AnyOf, option)
+	}
+	return s
+}
+
+// tagVariantSchema adds the discriminator as the first required property of the variant
+func tagVariantSchema(option *genai.Schema, discriminator, tag string) {
+	if option.Properties == nil {
+		return
+	}
+	option.Properties[discriminator] = &genai.Schema{Type: genai.TypeString, Enum: []string{tag}}
+	option.PropertyOrdering = append([]string{discriminator}, removeString(option.PropertyOrdering, discriminator)...)
+	option.Required = append([]string{discriminator}, removeString(option.Required, discriminator)...)
+}
+
+// tagRefVariants adds the discriminators of the union variants emitted as $ref to their definitions
+func (b *schemaBuilder) tagRefVariants() {
+	for name, variants := range b.refTags {
+		def, ok := b.defs[name]
+		if !ok {
+			continue
+		}
+		for _, variant := range variants {
+			tagVariantSchema(def, variant.discriminator, variant.tag)
+		}
+	}
+}
+
+func removeString(values []string, value string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Union holds a value of the registered union I and (un)marshals it with its
+// discriminator, use it for struct fields so model responses decode into the concrete type
+type Union[I any] struct {
+	Value I
+}
+
+type unionHolder interface {
+	unionInterface() reflect.Type
+}
+
+var unionHolderType = reflect.TypeOf((*unionHolder)(nil)).Elem()
+
+func (u Union[I]) unionInterface() reflect.Type {
+	return reflect.TypeOf((*I)(nil)).Elem()
+}
+
+func (u Union[I]) MarshalJSON() ([]byte, error) {
+	value := reflect.ValueOf(&u.Value).Elem()
+	if value.IsNil() {
+		return []byte("null"), nil
+	}
+	info, ok := lookupUnion(u.unionInterface())
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnionNotRegistered, u.unionInterface())
+	}
+	concrete := value.Elem().Type()
+	tag := ""
+	for _, variant := range info.variants {
+		if variant.typ == concrete || baseType(variant.typ) == baseType(concrete) {
+			tag = variant.tag
+			break
+		}
+	}
+	if tag == "" {
+		return nil, fmt.Errorf("%w: %s in %s", ErrUnknownUnionVariant, concrete, u.unionInterface())
+	}
+	encoded, err := json.Marshal(u.Value)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	fields[info.discriminator], _ = json.Marshal(tag)
+	return json.Marshal(fields)
+}
+
+func (u *Union[I]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		var zero I
+		u.Value = zero
+		return nil
+	}
+	value, err := DecodeUnion[I](data)
+	if err != nil {
+		return err
+	}
+	u.Value = value
+	return nil
+}
+
+// DecodeUnion decodes a json object into the variant of I named by its discriminator
+func DecodeUnion[I any](data []byte) (I, error) {
+	var zero I
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	info, ok := lookupUnion(iface)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s", ErrUnionNotRegistered, iface)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return zero, err
+	}
+	var tag string
+	if raw, ok := fields[info.discriminator]; ok {
+		if err := json.Unmarshal(raw, &tag); err != nil {
+			return zero, fmt.Errorf("%w: discriminator %s is not a string", ErrUnknownUnionVariant, info.discriminator)
+		}
+	}
+	for _, variant := range info.variants {
+		if variant.tag != tag {
+			continue
+		}
+		target := reflect.New(baseType(variant.typ))
+		if err := json.Unmarshal(data, target.Interface()); err != nil {
+			return zero, err
+		}
+		if variant.typ.Kind() == reflect.Pointer {
+			return target.Interface().(I), nil
+		}
+		return target.Elem().Interface().(I), nil
+	}
+	return zero, fmt.Errorf("%w: %s=%q in %s", ErrUnknownUnionVariant, info.discriminator, tag, iface)
+}
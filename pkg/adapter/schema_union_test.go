@@ -0,0 +1,154 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64 `json:"radius"`
+}
+
+func (c Circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type Square struct {
+	Side float64 `json:"side"`
+}
+
+func (s *Square) Area() float64 { return s.Side * s.Side }
+
+type Drawing struct {
+	Title  string         `json:"title"`
+	Main   Union[Shape]   `json:"main"`
+	Shapes []Union[Shape] `json:"shapes"`
+	Note   *string        `json:"note"`
+}
+
+func registerShapes(t *testing.T) {
+	t.Helper()
+	err := RegisterUnion("kind", map[string]Shape{"circle": Circle{}, "square": &Square{}})
+	if err != nil {
+		t.Fatalf("RegisterUnion() error = %v", err)
+	}
+}
+
+func TestUnionSchema(t *testing.T) {
+	registerShapes(t)
+	s := BuildSchemaFromStruct(Drawing{})
+
+	if note := s.Properties["note"]; note.Nullable == nil || !*note.Nullable || note.Type != genai.TypeString {
+		t.Errorf("pointer field should be a nullable string, got %+v", note)
+	}
+	if title := s.Properties["title"]; title.Nullable != nil {
+		t.Errorf("value field should not be nullable")
+	}
+
+	main := s.Properties["main"]
+	if len(main.AnyOf) != 2 {
+		t.Fatalf("main should be an anyOf of 2 variants, got %+v", main)
+	}
+	circle := main.AnyOf[0]
+	if kind := circle.Properties["kind"]; kind == nil || len(kind.Enum) != 1 || kind.Enum[0] != "circle" {
+		t.Errorf("circle discriminator = %+v", circle.Properties["kind"])
+	}
+	if circle.Required[0] != "kind" || circle.Properties["radius"] == nil {
+		t.Errorf("circle variant = %+v", circle)
+	}
+	if items := s.Properties["shapes"].Items; items == nil || len(items.AnyOf) != 2 {
+		t.Errorf("shapes items should be the union, got %+v", s.Properties["shapes"])
+	}
+
+	js, err := BuildJSONSchemaFromStruct(Drawing{})
+	if err != nil {
+		t.Fatalf("BuildJSONSchemaFromStruct() error = %v", err)
+	}
+	note := js["properties"].(map[string]any)["note"].(map[string]any)
+	if types, ok := note["type"].([]any); !ok || len(types) != 2 || types[1] != "null" {
+		t.Errorf("note json schema = %v", note)
+	}
+}
+
+func TestUnionRoundTrip(t *testing.T) {
+	registerShapes(t)
+	in := Drawing{
+		Title:  "shapes",
+		Main:   Union[Shape]{Value: Circle{Radius: 2}},
+		Shapes: []Union[Shape]{{Value: &Square{Side: 3}}, {Value: Circle{Radius: 1}}},
+	}
+	encoded, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var out Drawing
+	if err := json.Unmarshal(encoded, &out); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", encoded, err)
+	}
+	if c, ok := out.Main.Value.(Circle); !ok || c.Radius != 2 {
+		t.Errorf("main = %#v, want Circle{2}", out.Main.Value)
+	}
+	if sq, ok := out.Shapes[0].Value.(*Square); !ok || sq.Side != 3 {
+		t.Errorf("shapes[0] = %#v, want &Square{3}", out.Shapes[0].Value)
+	}
+
+	shape, err := DecodeUnion[Shape]([]byte(`{"kind":"square","side":4}`))
+	if err != nil || shape.Area() != 16 {
+		t.Errorf("DecodeUnion() = %#v, %v", shape, err)
+	}
+	if _, err := DecodeUnion[Shape]([]byte(`{"kind":"triangle"}`)); !errors.Is(err, ErrUnknownUnionVariant) {
+		t.Errorf("DecodeUnion() unknown variant error = %v", err)
+	}
+	if err := RegisterUnion[Circle]("kind", map[string]Circle{"circle": {}}); !errors.Is(err, ErrInvalidUnion) {
+		t.Errorf("RegisterUnion() on a struct should fail, got %v", err)
+	}
+}
+
+type Expr interface {
+	Eval() float64
+}
+
+type Literal struct {
+	Value float64 `json:"value"`
+}
+
+func (l Literal) Eval() float64 { return l.Value }
+
+type BinaryOp struct {
+	Op    string      `json:"op"`
+	Left  Union[Expr] `json:"left"`
+	Right Union[Expr] `json:"right"`
+}
+
+func (b BinaryOp) Eval() float64 { return b.Left.Value.Eval() + b.Right.Value.Eval() }
+
+func TestUnionSchemaRecursiveVariant(t *testing.T) {
+	if err := RegisterUnion("type", map[string]Expr{"literal": Literal{}, "binary": BinaryOp{}}); err != nil {
+		t.Fatalf("RegisterUnion() error = %v", err)
+	}
+	js, err := BuildJSONSchemaFromStruct(Union[Expr]{})
+	if err != nil {
+		t.Fatalf("BuildJSONSchemaFromStruct() error = %v", err)
+	}
+	options, _ := js["anyOf"].([]any)
+	if len(options) != 2 || options[0].(map[string]any)["$ref"] != "#/$defs/BinaryOp" {
+		t.Fatalf("anyOf = %v, want the recursive variant as a $ref", js["anyOf"])
+	}
+	def := js["$defs"].(map[string]any)["BinaryOp"].(map[string]any)
+	discriminator, _ := def["properties"].(map[string]any)["type"].(map[string]any)
+	if enum, _ := discriminator["enum"].([]any); len(enum) != 1 || enum[0] != "binary" {
+		t.Errorf("BinaryOp discriminator = %v, want enum [binary]", discriminator)
+	}
+	if required, _ := def["required"].([]string); len(required) == 0 || required[0] != "type" {
+		t.Errorf("BinaryOp required = %v, want the discriminator first", def["required"])
+	}
+	literal := options[1].(map[string]any)["properties"].(map[string]any)["type"].(map[string]any)
+	if enum, _ := literal["enum"].([]any); len(enum) != 1 || enum[0] != "literal" {
+		t.Errorf("Literal discriminator = %v", literal)
+	}
+}
@@ -0,0 +1,257 @@
+package chunker
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidChunkSize = errors.New("chunk size must be greater than zero")
+	ErrInvalidOverlap   = errors.New("chunk overlap must be smaller than the chunk size")
+)
+
+// Chunk is a piece of the source text, Start and End are byte offsets into the source
+// so callers can cite the exact location the chunk came from
+type Chunk struct {
+	Index  int    `json:"index"`
+	Text   string `json:"text"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Tokens int    `json:"tokens"`
+}
+
+// Splitter splits a text into ordered chunks
+type Splitter interface {
+	Split(text string) []Chunk
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+var (
+	wordRe      = regexp.MustCompile(`\S+`)
+	headingRe   = regexp.MustCompile(`(?m)^#{1,6}[ \t]`)
+	paragraphRe = regexp.MustCompile(`\n[ \t]*\n`)
+	sentenceRe  = regexp.MustCompile(`[.!?]+["')\]]*\s+`)
+	lineRe      = regexp.MustCompile(`\n`)
+)
+
+// CountTokens approximates the token count by whitespace separated words
+func CountTokens(text string) int {
+	return len(wordRe.FindAllStringIndex(text, -1))
+}
+
+func toChunks(text string, spans []span) []Chunk {
+	chunks := make([]Chunk, 0, len(spans))
+	for _, s := range spans {
+		s = trimSpan(text, s)
+		if s.start >= s.end {
+			continue
+		}
+		chunkText := text[s.start:s.end]
+		chunks = append(chunks, Chunk{
+			Index:  len(chunks),
+			Text:   chunkText,
+			Start:  s.start,
+			End:    s.end,
+			Tokens: CountTokens(chunkText),
+		})
+	}
+	return chunks
+}
+
+// trimSpan moves the span edges past surrounding whitespace, whole runes at a time so
+// multibyte characters are never cut
+func trimSpan(text string, s span) span {
+	for s.start < s.end {
+		r, size := utf8.DecodeRuneInString(text[s.start:s.end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		s.start += size
+	}
+	for s.end > s.start {
+		r, size := utf8.DecodeLastRuneInString(text[s.start:s.end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		s.end -= size
+	}
+	return s
+}
+
+// -----------------------------------------------------------
+// Fixed token windows
+// -----------------------------------------------------------
+
+type fixedTokenSplitter struct {
+	size    int
+	overlap int
+}
+
+// NewFixedTokenSplitter splits the text into windows of size tokens where consecutive
+// windows share overlap tokens
+func NewFixedTokenSplitter(size int, overlap int) (Splitter, error) {
+	if size <= 0 {
+		return nil, ErrInvalidChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		return nil, ErrInvalidOverlap
+	}
+	return &fixedTokenSplitter{size: size, overlap: overlap}, nil
+}
+
+func (f *fixedTokenSplitter) Split(text string) []Chunk {
+	return toChunks(text, fixedSpans(text, span{0, len(text)}, f.size, f.overlap))
+}
+
+func fixedSpans(text string, s span, size int, overlap int) []span {
+	words := wordRe.FindAllStringIndex(text[s.start:s.end], -1)
+	spans := make([]span, 0, len(words)/size+1)
+	step := size - overlap
+	for first := 0; first < len(words); first += step {
+		last := min(first+size, len(words)) - 1
+		spans = append(spans, span{s.start + words[first][0], s.start + words[last][1]})
+		if last == len(words)-1 {
+			break
+		}
+	}
+	return spans
+}
+
+// -----------------------------------------------------------
+// Sentences
+// -----------------------------------------------------------
+
+type sentenceSplitter struct {
+	maxTokens int
+}
+
+// NewSentenceSplitter groups whole sentences into chunks of at most maxTokens,
+// a single sentence longer than maxTokens is split into fixed windows
+func NewSentenceSplitter(maxTokens int) (Splitter, error) {
+	if maxTokens <= 0 {
+		return nil, ErrInvalidChunkSize
+	}
+	return &sentenceSplitter{maxTokens: maxTokens}, nil
+}
+
+func (s *sentenceSplitter) Split(text string) []Chunk {
+	pieces := splitAt(text, span{0, len(text)}, sentenceRe, false)
+	fitted := make([]span, 0, len(pieces))
+	for _, piece := range pieces {
+		if CountTokens(text[piece.start:piece.end]) > s.maxTokens {
+			fitted = append(fitted, fixedSpans(text, piece, s.maxTokens, 0)...)
+			continue
+		}
+		fitted = append(fitted, piece)
+	}
+	return toChunks(text, mergeSpans(text, fitted, s.maxTokens))
+}
+
+// -----------------------------------------------------------
+// Recursive headings -> paragraphs -> lines -> sentences
+// -----------------------------------------------------------
+
+type separator struct {
+	re *regexp.Regexp
+	// before splits at the start of the match (headings start a new section)
+	before bool
+}
+
+var recursiveSeparators = []separator{
+	{re: headingRe, before: true},
+	{re: paragraphRe},
+	{re: lineRe},
+	{re: sentenceRe},
+}
+
+type recursiveSplitter struct {
+	maxTokens int
+	overlap   int
+}
+
+// NewRecursiveSplitter splits by markdown headings first, then paragraphs, lines and
+// sentences until every chunk fits in maxTokens, falling back to fixed windows with
+// overlap for text that has no separators left
+func NewRecursiveSplitter(maxTokens int, overlap int) (Splitter, error) {
+	if maxTokens <= 0 {
+		return nil, ErrInvalidChunkSize
+	}
+	if overlap < 0 || overlap >= maxTokens {
+		return nil, ErrInvalidOverlap
+	}
+	return &recursiveSplitter{maxTokens: maxTokens, overlap: overlap}, nil
+}
+
+func (r *recursiveSplitter) Split(text string) []Chunk {
+	return toChunks(text, r.split(text, span{0, len(text)}, 0))
+}
+
+func (r *recursiveSplitter) split(text string, s span, level int) []span {
+	if CountTokens(text[s.start:s.end]) <= r.maxTokens {
+		return []span{s}
+	}
+	if level >= len(recursiveSeparators) {
+		return fixedSpans(text, s, r.maxTokens, r.overlap)
+	}
+	sep := recursiveSeparators[level]
+	pieces := splitAt(text, s, sep.re, sep.before)
+	if len(pieces) <= 1 {
+		return r.split(text, s, level+1)
+	}
+	fitted := make([]span, 0, len(pieces))
+	for _, piece := range pieces {
+		fitted = append(fitted, r.split(text, piece, level+1)...)
+	}
+	return mergeSpans(text, fitted, r.maxTokens)
+}
+
+// splitAt cuts the span at every separator match
+func splitAt(text string, s span, re *regexp.Regexp, before bool) []span {
+	matches := re.FindAllStringIndex(text[s.start:s.end], -1)
+	pieces := make([]span, 0, len(matches)+1)
+	cursor := s.start
+	for _, m := range matches {
+		cut := s.start + m[1]
+		if before {
+			cut = s.start + m[0]
+		}
+		if cut <= cursor {
+			continue
+		}
+		pieces = append(pieces, span{cursor, cut})
+		cursor = cut
+	}
+	if cursor < s.end {
+		pieces = append(pieces, span{cursor, s.end})
+	}
+	nonEmpty := pieces[:0]
+	for _, piece := range pieces {
+		if strings.TrimSpace(text[piece.start:piece.end]) != "" {
+			nonEmpty = append(nonEmpty, piece)
+		}
+	}
+	return nonEmpty
+}
+
+// mergeSpans greedily joins adjacent spans while the result fits in maxTokens
+func mergeSpans(text string, spans []span, maxTokens int) []span {
+	merged := make([]span, 0, len(spans))
+	for _, s := range spans {
+		if len(merged) > 0 {
+			last := merged[len(merged)-1]
+			if CountTokens(text[last.start:s.end]) <= maxTokens {
+				merged[len(merged)-1].end = s.end
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
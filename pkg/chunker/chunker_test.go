@@ -0,0 +1,137 @@
+package chunker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func checkOffsets(t *testing.T, source string, chunks []Chunk) {
+	t.Helper()
+	for _, c := range chunks {
+		if source[c.Start:c.End] != c.Text {
+			t.Errorf("chunk %d offsets [%d:%d] do not match its text", c.Index, c.Start, c.End)
+		}
+	}
+}
+
+func TestFixedTokenSplitter(t *testing.T) {
+	source := "one two three four five six seven"
+	splitter, err := NewFixedTokenSplitter(3, 1)
+	if err != nil {
+		t.Fatalf("NewFixedTokenSplitter() error = %v", err)
+	}
+	chunks := splitter.Split(source)
+	want := []string{"one two three", "three four five", "five six seven"}
+	if len(chunks) != len(want) {
+		t.Fatalf("Split() returned %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, c := range chunks {
+		if c.Text != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, c.Text, want[i])
+		}
+	}
+	checkOffsets(t, source, chunks)
+
+	if _, err := NewFixedTokenSplitter(3, 3); err == nil {
+		t.Errorf("NewFixedTokenSplitter() expected error for overlap >= size")
+	}
+}
+
+func TestSplittersKeepMultibyteRunes(t *testing.T) {
+	// х is 0xD1 0x85 and à is 0xC3 0xA0, both trailing bytes are spaces when read alone
+	source := "voilà café смех смех\n\nêtre à été. Ещё смех"
+	fixed, _ := NewFixedTokenSplitter(2, 0)
+	sentences, _ := NewSentenceSplitter(2)
+	recursive, _ := NewRecursiveSplitter(2, 1)
+	for name, splitter := range map[string]Splitter{"fixed": fixed, "sentence": sentences, "recursive": recursive} {
+		chunks := splitter.Split(source)
+		if len(chunks) == 0 {
+			t.Errorf("%s: Split() returned no chunks", name)
+		}
+		for _, c := range chunks {
+			if !utf8.ValidString(c.Text) {
+				t.Errorf("%s: chunk %d %q is not valid utf-8", name, c.Index, c.Text)
+			}
+		}
+		checkOffsets(t, source, chunks)
+	}
+	chunks := fixed.Split(source)
+	if chunks[1].Text != "смех смех" {
+		t.Errorf("chunk 1 = %q, want %q", chunks[1].Text, "смех смех")
+	}
+}
+
+func TestSentenceSplitter(t *testing.T) {
+	source := "First sentence here. Second one! Is this the third? Yes it is."
+	splitter, err := NewSentenceSplitter(5)
+	if err != nil {
+		t.Fatalf("NewSentenceSplitter() error = %v", err)
+	}
+	chunks := splitter.Split(source)
+	want := []string{"First sentence here. Second one!", "Is this the third?", "Yes it is."}
+	if len(chunks) != len(want) {
+		t.Fatalf("Split() returned %d chunks %+v, want %d", len(chunks), chunks, len(want))
+	}
+	for i, c := range chunks {
+		if c.Text != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, c.Text, want[i])
+		}
+	}
+	checkOffsets(t, source, chunks)
+}
+
+func TestRecursiveSplitter(t *testing.T) {
+	source := "# Intro\nGo is fast.\n\n# Usage\nCall the client. Then read the response.\n\nDone."
+	splitter, err := NewRecursiveSplitter(6, 0)
+	if err != nil {
+		t.Fatalf("NewRecursiveSplitter() error = %v", err)
+	}
+	chunks := splitter.Split(source)
+	if len(chunks) < 2 {
+		t.Fatalf("Split() returned %d chunks, want at least 2", len(chunks))
+	}
+	if !strings.HasPrefix(chunks[0].Text, "# Intro") {
+		t.Errorf("first chunk should start with the first heading, got %q", chunks[0].Text)
+	}
+	for _, c := range chunks {
+		if c.Tokens > 6 {
+			t.Errorf("chunk %d has %d tokens, want <= 6", c.Index, c.Tokens)
+		}
+	}
+	checkOffsets(t, source, chunks)
+}
+
+func TestExtractText(t *testing.T) {
+	got, err := ExtractText("text/html", []byte("<html><head><style>p{}</style></head><body><h1>Title</h1><p>Hello <b>world</b></p><script>x()</script></body></html>"))
+	if err != nil {
+		t.Fatalf("ExtractText() error = %v", err)
+	}
+	if !strings.Contains(got, "# Title") || !strings.Contains(got, "Hello world") {
+		t.Errorf("ExtractText() = %q", got)
+	}
+	if strings.Contains(got, "x()") || strings.Contains(got, "p{}") {
+		t.Errorf("ExtractText() kept script or style content: %q", got)
+	}
+	if _, err := ExtractText("application/msword", nil); !errors.Is(err, ErrUnsupportedMIMEType) {
+		t.Errorf("ExtractText() expected error for unregistered mime type")
+	}
+}
+
+func TestDetectMIMEType(t *testing.T) {
+	tests := map[string]string{
+		"notes.md":   "text/markdown",
+		"page.html":  "text/html",
+		"readme.txt": "text/plain",
+		"guide.pdf":  "application/pdf",
+	}
+	for path, want := range tests {
+		if got := DetectMIMEType("", path); got != want {
+			t.Errorf("DetectMIMEType(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if got := DetectMIMEType("text/html; charset=utf-8", "x.md"); got != "text/html" {
+		t.Errorf("DetectMIMEType() should prefer the explicit mime type, got %q", got)
+	}
+}
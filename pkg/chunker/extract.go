@@ -0,0 +1,131 @@
+package chunker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+var ErrUnsupportedMIMEType = errors.New("no text extractor registered for mime type")
+
+// Extractor turns raw file bytes into plain text
+type Extractor func(data []byte) (string, error)
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = map[string]Extractor{
+		"text/plain":      plainText,
+		"text/markdown":   plainText,
+		"text/x-markdown": plainText,
+		"text/html":       htmlText,
+		"application/pdf": pdfText,
+	}
+)
+
+// RegisterExtractor adds or replaces the extractor for a mime type, use it to plug an
+// office document extractor or a full PDF library in place of the built in one
+func RegisterExtractor(mimeType string, extractor Extractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors[mimeType] = extractor
+}
+
+// DetectMIMEType resolves the mime type from the explicit value or the file extension
+func DetectMIMEType(mimeType string, path string) string {
+	if mimeType != "" {
+		if parsed, _, err := mime.ParseMediaType(mimeType); err == nil {
+			return parsed
+		}
+		return mimeType
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return "text/markdown"
+	case ".txt", "":
+		return "text/plain"
+	case ".htm", ".html":
+		return "text/html"
+	case ".pdf":
+		return "application/pdf"
+	}
+	if byExt := mime.TypeByExtension(filepath.Ext(path)); byExt != "" {
+		if parsed, _, err := mime.ParseMediaType(byExt); err == nil {
+			return parsed
+		}
+	}
+	return "application/octet-stream"
+}
+
+// ExtractText converts the data to plain text with the extractor registered for mimeType
+func ExtractText(mimeType string, data []byte) (string, error) {
+	extractorsMu.RLock()
+	extractor, ok := extractors[mimeType]
+	extractorsMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedMIMEType, mimeType)
+	}
+	return extractor(data)
+}
+
+func plainText(data []byte) (string, error) {
+	return string(data), nil
+}
+
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "section": true, "article": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "pre": true, "blockquote": true,
+}
+
+// htmlText keeps the visible text of the document, block elements become blank lines
+// so the recursive splitter can still find paragraphs
+func htmlText(data []byte) (string, error) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	var sb strings.Builder
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return "", err
+			}
+			return strings.TrimSpace(sb.String()), nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == "script" || tag == "style" {
+				skip++
+			}
+			if htmlBlockElements[tag] {
+				sb.WriteString("\n\n")
+			}
+			if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+				sb.WriteString(strings.Repeat("#", int(tag[1]-'0')) + " ")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if (tag == "script" || tag == "style") && skip > 0 {
+				skip--
+			}
+			if htmlBlockElements[tag] {
+				sb.WriteString("\n\n")
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := strings.Join(strings.Fields(string(tokenizer.Text())), " ")
+			if text != "" {
+				sb.WriteString(text)
+				sb.WriteString(" ")
+			}
+		}
+	}
+}
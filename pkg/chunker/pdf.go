@@ -0,0 +1,918 @@
+package chunker
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+var (
+	ErrInvalidPDF   = errors.New("invalid pdf")
+	ErrEncryptedPDF = errors.New("encrypted pdfs are not supported")
+	// ErrPDFNoText is returned for pdfs without a text layer, e.g. scanned pages
+	ErrPDFNoText = errors.New("pdf has no extractable text")
+)
+
+// pdfMaxFormDepth bounds nested form xobjects
+const pdfMaxFormDepth = 8
+
+// pdfText extracts the text layer page by page. It reads the objects directly (no xref),
+// inflates FlateDecode streams, follows object streams and maps glyph codes through the
+// font ToUnicode cmaps, simple fonts without one are read as WinAnsi
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return "", fmt.Errorf("%w: missing %%PDF header", ErrInvalidPDF)
+	}
+	doc := parsePDF(data)
+	if doc.encrypted {
+		return "", ErrEncryptedPDF
+	}
+	catalog, ok := doc.resolve(doc.root).(pdfDict)
+	if !ok {
+		return "", fmt.Errorf("%w: document catalog not found", ErrInvalidPDF)
+	}
+	x := &pdfExtractor{doc: doc, fonts: map[pdfRef]*pdfFont{}}
+	x.walkPages(catalog["Pages"], nil, map[pdfRef]bool{})
+	text := strings.TrimSpace(x.out.String())
+	if text == "" {
+		return "", ErrPDFNoText
+	}
+	return text, nil
+}
+
+// -----------------------------------------------------------
+// objects
+// -----------------------------------------------------------
+
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfString  []byte
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+type pdfDocument struct {
+	objects   map[int]any
+	root      any
+	encrypted bool
+}
+
+var (
+	pdfObjectHeader  = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfTrailer       = regexp.MustCompile(`trailer\s*<<`)
+	pdfInlineImageID = regexp.MustCompile(`\sID\s`)
+	pdfInlineImageEI = regexp.MustCompile(`\sEI(\s|$)`)
+)
+
+// parsePDF collects the indirect objects in file order, later definitions (incremental
+// updates) replace earlier ones
+func parsePDF(data []byte) *pdfDocument {
+	doc := &pdfDocument{objects: map[int]any{}}
+	var objectStreams []*pdfStream
+	pos := 0
+	for {
+		loc := pdfObjectHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		lex := &pdfLexer{data: data, pos: pos + loc[1]}
+		obj, err := lex.object()
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		if dict, ok := obj.(pdfDict); ok && lex.keywordAhead("stream") {
+			stream := &pdfStream{dict: dict, raw: lex.streamData(dict)}
+			obj = stream
+			switch dict["Type"] {
+			case pdfName("ObjStm"):
+				objectStreams = append(objectStreams, stream)
+			case pdfName("XRef"):
+				doc.trailer(dict)
+			}
+		}
+		doc.objects[num] = obj
+		pos = lex.pos
+	}
+	for _, stream := range objectStreams {
+		doc.expandObjectStream(stream)
+	}
+	// classic trailers, the last one describes the latest revision
+	for _, index := range pdfTrailer.FindAllIndex(data, -1) {
+		lex := &pdfLexer{data: data, pos: index[0] + len("trailer")}
+		if dict, ok := mustObject(lex).(pdfDict); ok {
+			doc.trailer(dict)
+		}
+	}
+	if doc.root == nil {
+		for _, obj := range doc.objects {
+			if dict, ok := obj.(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+				doc.root = dict
+			}
+		}
+	}
+	return doc
+}
+
+func mustObject(lex *pdfLexer) any {
+	obj, _ := lex.object()
+	return obj
+}
+
+func (d *pdfDocument) trailer(dict pdfDict) {
+	if root, ok := dict["Root"]; ok {
+		d.root = root
+	}
+	if _, ok := dict["Encrypt"]; ok {
+		d.encrypted = true
+	}
+}
+
+// expandObjectStream adds the compressed objects that were not defined directly
+func (d *pdfDocument) expandObjectStream(stream *pdfStream) {
+	data, err := stream.decode()
+	if err != nil {
+		return
+	}
+	n, _ := pdfInt(stream.dict["N"])
+	first, _ := pdfInt(stream.dict["First"])
+	header := &pdfLexer{data: data}
+	for range n {
+		num, ok1 := pdfInt(mustObject(header))
+		offset, ok2 := pdfInt(mustObject(header))
+		if !ok1 || !ok2 || first+offset >= len(data) {
+			return
+		}
+		if _, defined := d.objects[num]; defined {
+			continue
+		}
+		if obj, err := (&pdfLexer{data: data, pos: first + offset}).object(); err == nil {
+			d.objects[num] = obj
+		}
+	}
+}
+
+// resolve follows indirect references
+func (d *pdfDocument) resolve(obj any) any {
+	for range 32 {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(obj any) pdfDict {
+	switch v := d.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+func pdfInt(obj any) (int, bool) {
+	f, ok := obj.(float64)
+	return int(f), ok
+}
+
+// decode applies the stream filters, only FlateDecode is supported
+func (s *pdfStream) decode() ([]byte, error) {
+	var filters []any
+	switch f := s.dict["Filter"].(type) {
+	case nil:
+		return s.raw, nil
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+	data := s.raw
+	for _, filter := range filters {
+		if filter != pdfName("FlateDecode") {
+			return nil, fmt.Errorf("%w: unsupported stream filter %v", ErrInvalidPDF, filter)
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPDF, err)
+		}
+		inflated, err := io.ReadAll(reader)
+		// truncated streams are common, keep what was inflated
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPDF, err)
+		}
+		data = inflated
+	}
+	return data, nil
+}
+
+// -----------------------------------------------------------
+// lexer
+// -----------------------------------------------------------
+
+type pdfDelimiter byte
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// token returns a number, name, string, keyword or delimiter ('[', ']', '<', '>' for << >>)
+func (l *pdfLexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		return pdfName(l.regular(true)), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<' && l.peek(1) == '<':
+		l.pos += 2
+		return pdfDelimiter('<'), nil
+	case c == '>' && l.peek(1) == '>':
+		l.pos += 2
+		return pdfDelimiter('>'), nil
+	case c == '<':
+		return l.hexString(), nil
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfDelimiter(c), nil
+	case c == ')' || c == '>':
+		l.pos++
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidPDF, c)
+	}
+	word := l.regular(false)
+	if c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+		if n, err := strconv.ParseFloat(word, 64); err == nil {
+			return n, nil
+		}
+	}
+	return pdfKeyword(word), nil
+}
+
+func (l *pdfLexer) peek(offset int) byte {
+	if l.pos+offset < len(l.data) {
+		return l.data[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *pdfLexer) regular(name bool) string {
+	var sb strings.Builder
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) || isPDFDelimiter(c) {
+			break
+		}
+		if name && c == '#' && l.pos+2 < len(l.data) {
+			if b, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				sb.WriteByte(byte(b))
+				l.pos += 3
+				continue
+			}
+		}
+		sb.WriteByte(c)
+		l.pos++
+	}
+	if sb.Len() == 0 && !name && l.pos < len(l.data) {
+		// stray byte, skip it so the lexer always moves forward
+		sb.WriteByte(l.data[l.pos])
+		l.pos++
+	}
+	return sb.String()
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.peek(0) == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for range 2 {
+						if d := l.peek(0); d >= '0' && d <= '7' {
+							v = v*8 + int(d-'0')
+							l.pos++
+						}
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		b, _ := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		out = append(out, byte(b))
+	}
+	return out
+}
+
+// object parses one object, "n g R" becomes a pdfRef
+func (l *pdfLexer) object() (any, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	return l.objectFrom(tok)
+}
+
+func (l *pdfLexer) objectFrom(tok any) (any, error) {
+	switch t := tok.(type) {
+	case float64:
+		save := l.pos
+		if gen, err := l.token(); err == nil {
+			if _, ok := gen.(float64); ok {
+				if r, err := l.token(); err == nil && r == pdfKeyword("R") {
+					return pdfRef{num: int(t), gen: int(gen.(float64))}, nil
+				}
+			}
+		}
+		l.pos = save
+		return t, nil
+	case pdfDelimiter:
+		switch t {
+		case '[':
+			var arr []any
+			for {
+				next, err := l.token()
+				if err != nil {
+					return arr, err
+				}
+				if next == pdfDelimiter(']') {
+					return arr, nil
+				}
+				item, err := l.objectFrom(next)
+				if err != nil {
+					return arr, err
+				}
+				arr = append(arr, item)
+			}
+		case '<':
+			dict := pdfDict{}
+			for {
+				next, err := l.token()
+				if err != nil {
+					return dict, err
+				}
+				if next == pdfDelimiter('>') {
+					return dict, nil
+				}
+				key, ok := next.(pdfName)
+				if !ok {
+					return dict, fmt.Errorf("%w: dictionary key %v", ErrInvalidPDF, next)
+				}
+				value, err := l.object()
+				if err != nil {
+					return dict, err
+				}
+				dict[key] = value
+			}
+		}
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidPDF, byte(t))
+	}
+	return tok, nil
+}
+
+func (l *pdfLexer) keywordAhead(keyword string) bool {
+	l.skipSpace()
+	if bytes.HasPrefix(l.data[l.pos:], []byte(keyword)) {
+		l.pos += len(keyword)
+		return true
+	}
+	return false
+}
+
+// streamData returns the bytes between stream and endstream, /Length is used when it
+// is direct and points at endstream
+func (l *pdfLexer) streamData(dict pdfDict) []byte {
+	if l.peek(0) == '\r' {
+		l.pos++
+	}
+	if l.peek(0) == '\n' {
+		l.pos++
+	}
+	start := l.pos
+	if length, ok := pdfInt(dict["Length"]); ok && length >= 0 && start+length <= len(l.data) {
+		after := &pdfLexer{data: l.data, pos: start + length}
+		if after.keywordAhead("endstream") {
+			l.pos = after.pos
+			return l.data[start : start+length]
+		}
+	}
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		l.pos = len(l.data)
+		return l.data[start:]
+	}
+	l.pos = start + end + len("endstream")
+	return bytes.TrimRight(l.data[start:start+end], "\r\n")
+}
+
+// -----------------------------------------------------------
+// pages and content streams
+// -----------------------------------------------------------
+
+type pdfExtractor struct {
+	doc   *pdfDocument
+	fonts map[pdfRef]*pdfFont
+	out   bytes.Buffer
+	// baseline of the last text matrix
+	lineY   float64
+	hasLine bool
+}
+
+func (x *pdfExtractor) walkPages(node any, inherited pdfDict, seen map[pdfRef]bool) {
+	if ref, ok := node.(pdfRef); ok {
+		if seen[ref] {
+			return
+		}
+		seen[ref] = true
+	}
+	dict := x.doc.dict(node)
+	if dict == nil {
+		return
+	}
+	resources := inherited
+	if r := x.doc.dict(dict["Resources"]); r != nil {
+		resources = r
+	}
+	if kids, ok := x.doc.resolve(dict["Kids"]).([]any); ok {
+		for _, kid := range kids {
+			x.walkPages(kid, resources, seen)
+		}
+		return
+	}
+	var content []byte
+	switch c := x.doc.resolve(dict["Contents"]).(type) {
+	case *pdfStream:
+		content, _ = c.decode()
+	case []any:
+		for _, part := range c {
+			if stream, ok := x.doc.resolve(part).(*pdfStream); ok {
+				if data, err := stream.decode(); err == nil {
+					content = append(append(content, data...), '\n')
+				}
+			}
+		}
+	}
+	x.content(content, resources, 0)
+	x.paragraph()
+}
+
+// content interprets the text operators of a content stream
+func (x *pdfExtractor) content(data []byte, resources pdfDict, depth int) {
+	lex := &pdfLexer{data: data}
+	var operands []any
+	var font *pdfFont
+	for {
+		tok, err := lex.token()
+		if err != nil {
+			return
+		}
+		op, ok := tok.(pdfKeyword)
+		if !ok || op == "true" || op == "false" || op == "null" {
+			if obj, err := lex.objectFrom(tok); err == nil {
+				operands = append(operands, obj)
+			}
+			continue
+		}
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = x.font(resources, name)
+				}
+			}
+		case "Tj":
+			x.show(font, operands)
+		case "'", "\"":
+			x.newline()
+			x.show(font, operands)
+		case "TJ":
+			if len(operands) > 0 {
+				items, _ := operands[len(operands)-1].([]any)
+				for _, item := range items {
+					switch v := item.(type) {
+					case pdfString:
+						x.write(font.decode(v))
+					case float64:
+						// a large negative adjustment is a word gap
+						if v < -200 {
+							x.space()
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, _ := operands[1].(float64); ty != 0 {
+					x.newline()
+				} else {
+					x.space()
+				}
+			}
+		case "Tm":
+			// a new matrix on the same baseline continues the line
+			if len(operands) >= 6 {
+				if y, ok := operands[5].(float64); ok && x.hasLine && y == x.lineY {
+					x.space()
+				} else {
+					x.newline()
+				}
+				x.lineY, x.hasLine = operands[5].(float64)
+			}
+		case "T*":
+			x.newline()
+		case "Do":
+			if len(operands) > 0 && depth < pdfMaxFormDepth {
+				x.form(resources, operands[0], depth)
+			}
+		case "BI":
+			lex.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+func (x *pdfExtractor) form(resources pdfDict, name any, depth int) {
+	n, ok := name.(pdfName)
+	if !ok || resources == nil {
+		return
+	}
+	stream, ok := x.doc.resolve(x.doc.dict(resources["XObject"])[n]).(*pdfStream)
+	if !ok || stream.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := stream.decode()
+	if err != nil {
+		return
+	}
+	formResources := resources
+	if r := x.doc.dict(stream.dict["Resources"]); r != nil {
+		formResources = r
+	}
+	x.newline()
+	x.content(data, formResources, depth+1)
+	x.newline()
+}
+
+// skipInlineImage moves past the binary data of BI ... ID ... EI
+func (l *pdfLexer) skipInlineImage() {
+	id := pdfInlineImageID.FindIndex(l.data[l.pos:])
+	if id == nil {
+		l.pos = len(l.data)
+		return
+	}
+	start := l.pos + id[1]
+	ei := pdfInlineImageEI.FindIndex(l.data[start:])
+	if ei == nil {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos = start + ei[1]
+}
+
+func (x *pdfExtractor) show(font *pdfFont, operands []any) {
+	if len(operands) == 0 {
+		return
+	}
+	if s, ok := operands[len(operands)-1].(pdfString); ok {
+		x.write(font.decode(s))
+	}
+}
+
+func (x *pdfExtractor) write(text string) {
+	x.out.WriteString(text)
+}
+
+func (x *pdfExtractor) lastByte() byte {
+	if x.out.Len() == 0 {
+		return '\n'
+	}
+	return x.out.Bytes()[x.out.Len()-1]
+}
+
+func (x *pdfExtractor) space() {
+	if c := x.lastByte(); c != ' ' && c != '\n' {
+		x.out.WriteByte(' ')
+	}
+}
+
+// paragraph ends the page with a blank line so splitters see the page break
+func (x *pdfExtractor) paragraph() {
+	x.newline()
+	if x.out.Len() > 0 && !bytes.HasSuffix(x.out.Bytes(), []byte("\n\n")) {
+		x.out.WriteByte('\n')
+	}
+}
+
+func (x *pdfExtractor) newline() {
+	for x.lastByte() == ' ' {
+		x.out.Truncate(x.out.Len() - 1)
+	}
+	if x.lastByte() != '\n' {
+		x.out.WriteByte('\n')
+	}
+}
+
+// -----------------------------------------------------------
+// fonts
+// -----------------------------------------------------------
+
+type pdfFont struct {
+	// toUnicode maps glyph codes to text, codes are widths bytes wide
+	toUnicode map[string]string
+	widths    []int
+	composite bool
+}
+
+func (x *pdfExtractor) font(resources pdfDict, name pdfName) *pdfFont {
+	if resources == nil {
+		return nil
+	}
+	ref, isRef := x.doc.dict(resources["Font"])[name].(pdfRef)
+	if isRef {
+		if font, ok := x.fonts[ref]; ok {
+			return font
+		}
+	}
+	dict := x.doc.dict(x.doc.dict(resources["Font"])[name])
+	if dict == nil {
+		return nil
+	}
+	font := &pdfFont{composite: dict["Subtype"] == pdfName("Type0")}
+	if stream, ok := x.doc.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := stream.decode(); err == nil {
+			font.toUnicode, font.widths = parseToUnicode(data)
+		}
+	}
+	if isRef {
+		x.fonts[ref] = font
+	}
+	return font
+}
+
+// decode maps the string through the cmap, composite fonts without one can not be read
+func (f *pdfFont) decode(s pdfString) string {
+	if f == nil || len(f.toUnicode) == 0 {
+		if f != nil && f.composite {
+			return ""
+		}
+		return winAnsiText(s)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		matched := false
+		for _, width := range f.widths {
+			if i+width > len(s) {
+				continue
+			}
+			if text, ok := f.toUnicode[string(s[i:i+width])]; ok {
+				sb.WriteString(text)
+				i += width
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			if !f.composite {
+				sb.WriteString(winAnsiText(s[i : i+1]))
+			}
+			i += f.widths[0]
+		}
+	}
+	return sb.String()
+}
+
+// parseToUnicode reads the bfchar and bfrange sections of a ToUnicode cmap
+func parseToUnicode(data []byte) (map[string]string, []int) {
+	mapping := map[string]string{}
+	widthSet := map[int]bool{}
+	lex := &pdfLexer{data: data}
+	var operands []any
+	section := ""
+	for {
+		tok, err := lex.token()
+		if err != nil {
+			break
+		}
+		if kw, ok := tok.(pdfKeyword); ok {
+			switch kw {
+			case "begincodespacerange", "beginbfchar", "beginbfrange":
+				section = string(kw)
+			case "endcodespacerange":
+				for i := 0; i+1 < len(operands); i += 2 {
+					if lo, ok := operands[i].(pdfString); ok {
+						widthSet[len(lo)] = true
+					}
+				}
+				section = ""
+			case "endbfchar":
+				for i := 0; i+1 < len(operands); i += 2 {
+					src, ok1 := operands[i].(pdfString)
+					dst, ok2 := operands[i+1].(pdfString)
+					if ok1 && ok2 {
+						mapping[string(src)] = utf16Text(dst)
+						widthSet[len(src)] = true
+					}
+				}
+				section = ""
+			case "endbfrange":
+				for i := 0; i+2 < len(operands); i += 3 {
+					lo, ok1 := operands[i].(pdfString)
+					hi, ok2 := operands[i+1].(pdfString)
+					if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+						continue
+					}
+					widthSet[len(lo)] = true
+					start, end := codeValue(lo), codeValue(hi)
+					for code := start; code <= end && code-start < 1<<16; code++ {
+						key := string(codeBytes(code, len(lo)))
+						switch dst := operands[i+2].(type) {
+						case pdfString:
+							mapping[key] = utf16Text(offsetUTF16(dst, code-start))
+						case []any:
+							if int(code-start) < len(dst) {
+								if item, ok := dst[code-start].(pdfString); ok {
+									mapping[key] = utf16Text(item)
+								}
+							}
+						}
+					}
+				}
+				section = ""
+			}
+			operands = operands[:0]
+			continue
+		}
+		if section == "" {
+			continue
+		}
+		if obj, err := lex.objectFrom(tok); err == nil {
+			operands = append(operands, obj)
+		}
+	}
+	widths := make([]int, 0, len(widthSet))
+	for width := 1; width <= 4; width++ {
+		if widthSet[width] {
+			widths = append(widths, width)
+		}
+	}
+	if len(widths) == 0 {
+		widths = []int{1}
+	}
+	return mapping, widths
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+func codeBytes(v uint32, width int) []byte {
+	out := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		out[i] = byte(v)
+		v >>= 8
+	}
+	return out
+}
+
+// offsetUTF16 adds offset to the last code unit of a bfrange destination
+func offsetUTF16(dst []byte, offset uint32) []byte {
+	out := append([]byte(nil), dst...)
+	if len(out) < 2 {
+		return out
+	}
+	last := uint32(out[len(out)-2])<<8 | uint32(out[len(out)-1])
+	last += offset
+	out[len(out)-2], out[len(out)-1] = byte(last>>8), byte(last)
+	return out
+}
+
+func utf16Text(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// winAnsiSpecials are the WinAnsiEncoding codes that differ from Latin-1
+var winAnsiSpecials = map[byte]rune{
+	0x80: '€', 0x85: '…', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”',
+	0x95: '•', 0x96: '–', 0x97: '—', 0x99: '™',
+}
+
+func winAnsiText(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if r, ok := winAnsiSpecials[c]; ok {
+			sb.WriteRune(r)
+			continue
+		}
+		sb.WriteRune(rune(c))
+	}
+	return sb.String()
+}
@@ -0,0 +1,159 @@
+package chunker
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// pdfBuilder writes a minimal pdf, objects are numbered from 1 in the order they are added
+type pdfBuilder struct {
+	objects []string
+	trailer string
+}
+
+func (b *pdfBuilder) add(object string) int {
+	b.objects = append(b.objects, object)
+	return len(b.objects)
+}
+
+func (b *pdfBuilder) stream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func (b *pdfBuilder) flate(dict string, data string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return b.stream(dict+" /Filter /FlateDecode", buf.Bytes())
+}
+
+func (b *pdfBuilder) bytes(root int) []byte {
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(b.objects))
+	for i, object := range b.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(b.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R %s >>\nstartxref\n%d\n%%%%EOF\n", len(b.objects)+1, root, b.trailer, xref)
+	return out.Bytes()
+}
+
+// simplePDF has two pages with a WinAnsi font, the second page is compressed
+func simplePDF(t *testing.T) []byte {
+	t.Helper()
+	b := &pdfBuilder{}
+	font := b.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	first := b.add(b.stream("", []byte("BT /F1 12 Tf 72 720 Td (Hello \\(PDF\\) world) Tj 0 -14 Td [(Sec)10(ond)-500(line)] TJ ET")))
+	second := b.add(b.flate("", "q BT /F1 12 Tf 72 720 Td (Page two \\223quoted\\224) Tj T* (next) Tj ET Q"))
+	pages := len(b.objects) + 4
+	page1 := b.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Contents %d 0 R >>", pages, first))
+	page2 := b.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Contents [%d 0 R] >>", pages, second))
+	catalog := b.add(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	b.add(fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R %d 0 R] /Count 2 /Resources << /Font << /F1 %d 0 R >> >> >>", page1, page2, font))
+	return b.bytes(catalog)
+}
+
+func TestPDFText(t *testing.T) {
+	got, err := ExtractText("application/pdf", simplePDF(t))
+	if err != nil {
+		t.Fatalf("ExtractText() error = %v", err)
+	}
+	want := "Hello (PDF) world\nSecond line\n\nPage two “quoted”\nnext"
+	if got != want {
+		t.Errorf("ExtractText() = %q, want %q", got, want)
+	}
+}
+
+func TestPDFTextToUnicode(t *testing.T) {
+	b := &pdfBuilder{}
+	cmap := b.add(b.flate("", `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0003> <0020>
+<0010> <00E9>
+endbfchar
+2 beginbfrange
+<0024> <0026> <0041>
+<0030> <0031> [<0066006C> <D83DDE00>]
+endbfrange
+endcmap
+end end`))
+	font := b.add(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode %d 0 R >>", cmap))
+	form := b.add(b.stream("/Type /XObject /Subtype /Form /BBox [0 0 100 100]", []byte("BT /F2 10 Tf <0030> Tj ET")))
+	content := b.add(b.stream("", []byte("/Span << /MCID 0 >> BDC BT /F2 10 Tf <002400250026000300100003> Tj ET EMC\n"+
+		"BI /W 1 /H 1 /BPC 8 /CS /G ID \x00) EI\n/X1 Do BT /F2 10 Tf <0031> Tj ET")))
+	// the page and the page tree live in a compressed object stream
+	// numbers after the object stream itself and the catalog
+	pageNum, pagesNum := len(b.objects)+3, len(b.objects)+4
+	page := fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Contents %d 0 R >>", pagesNum, content)
+	pages := fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R] /Count 1 /Resources << /Font << /F2 %d 0 R >> /XObject << /X1 %d 0 R >> >> >>", pageNum, font, form)
+	header := fmt.Sprintf("%d 0 %d %d ", pageNum, pagesNum, len(page)+1)
+	b.add(b.flate(fmt.Sprintf("/Type /ObjStm /N 2 /First %d", len(header)), header+page+" "+pages))
+	catalog := b.add(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesNum))
+
+	got, err := pdfText(b.bytes(catalog))
+	if err != nil {
+		t.Fatalf("pdfText() error = %v", err)
+	}
+	want := "ABC é\nfl\n\U0001F600"
+	if got != want {
+		t.Errorf("pdfText() = %q, want %q", got, want)
+	}
+}
+
+func TestPDFTextErrors(t *testing.T) {
+	encrypted := &pdfBuilder{trailer: "/Encrypt << /Filter /Standard >>"}
+	catalog := encrypted.add("<< /Type /Catalog >>")
+
+	noText := &pdfBuilder{}
+	content := noText.add(noText.stream("", []byte("q 100 0 0 100 0 0 cm /Im1 Do Q")))
+	noTextPage := noText.add(fmt.Sprintf("<< /Type /Page /Contents %d 0 R >>", content))
+	noTextPages := noText.add(fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", noTextPage))
+	noTextCatalog := noText.add(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", noTextPages))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "not a pdf", data: []byte("hello"), wantErr: ErrInvalidPDF},
+		{name: "no catalog", data: []byte("%PDF-1.4\n1 0 obj\n<< >>\nendobj\n"), wantErr: ErrInvalidPDF},
+		{name: "encrypted", data: encrypted.bytes(catalog), wantErr: ErrEncryptedPDF},
+		{name: "scanned pages", data: noText.bytes(noTextCatalog), wantErr: ErrPDFNoText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := pdfText(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("pdfText() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPDFTextTruncated(t *testing.T) {
+	data := simplePDF(t)
+	// a damaged file keeps the text that can still be read
+	for _, cut := range []int{len(data) / 2, len(data) - 40} {
+		if _, err := pdfText(data[:cut]); err != nil && !errors.Is(err, ErrInvalidPDF) && !errors.Is(err, ErrPDFNoText) {
+			t.Errorf("pdfText(truncated) error = %v", err)
+		}
+	}
+	if got, _ := pdfText(data[:len(data)-40]); !strings.Contains(got, "Hello (PDF) world") {
+		t.Errorf("pdfText() lost the text of a file without its trailer: %q", got)
+	}
+}
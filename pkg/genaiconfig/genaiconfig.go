@@ -17,11 +17,13 @@ const (
 	FunctionCallingModeValidated FunctionCallingMode = "VALIDATED"
 )
 
+// SchemaConfig holds a schema in one of three forms, set exactly one of them:
+// a config carrying more than one is rejected by the adapter instead of picking one.
 type SchemaConfig struct {
 	// Optional. Describes the parameters to the function in JSON Schema format. The schema
 	// must describe an object where the properties are the parameters to the function.
 	// For example: ``` { "type": "object", "properties": { "name": { "type": "string" },
-	SchemaJSON map[string]interface{} // json represnetaion of the genai schema on a hash map
+	SchemaJSON map[string]interface{} `json:"schemaJSON,omitempty"` // json represnetaion of the genai schema on a hash map
 	// Optional. Describes the parameters to this function in JSON Schema Object format.
 	// Reflects the Open API 3.03 Parameter Object. string Key: the name of the parameter.
 	// Parameter names are case sensitive. Schema Value: the Schema defining the type used
@@ -30,15 +32,29 @@ type SchemaConfig struct {
 	// A-Z, 0-9, or underscores with a maximum length of 64. Example with 1 required and
 	// 1 optional parameter: type: OBJECT properties: param1: type: STRING param2: type:
 	// INTEGER required: - param1
-	SchemaGenAI *genai.Schema
-	// struct type to infer the schema from it via the reflect and json annotations
-	Schema any
+	SchemaGenAI *genai.Schema `json:"schemaGenAI,omitempty"`
+	// struct type to infer the schema from it via the reflect and json annotations,
+	// it only exists in go code so it is never serialized
+	Schema any `json:"-"`
 }
 type Tool struct {
-	Name           string
-	Description    string
-	RequestConfig  *SchemaConfig
-	ResponseConfig *SchemaConfig
+	Name           string        `json:"name"`
+	Description    string        `json:"description,omitempty"`
+	RequestConfig  *SchemaConfig `json:"requestConfig,omitempty"`
+	ResponseConfig *SchemaConfig `json:"responseConfig,omitempty"`
+}
+
+// ToolGroup bundles several function tools into a single gemini tool
+type ToolGroup struct {
+	Name  string  `json:"name"`
+	Tools []*Tool `json:"tools"`
+}
+
+// BuiltinTools switches on the tools gemini runs on its side
+type BuiltinTools struct {
+	GoogleSearch  bool `json:"googleSearch,omitempty"`
+	CodeExecution bool `json:"codeExecution,omitempty"`
+	URLContext    bool `json:"urlContext,omitempty"`
 }
 
 type ToolConfig struct {
@@ -59,6 +75,9 @@ type AgentConfig struct {
 	SystemInstruction       string            `json:"systemInstruction"`
 	DefaultModel            string            `json:"deaultModel"`
 	DefaultGenerationConfig *GenerationConfig `json:"defaultGenerationConfig"`
+	// SafetySettings apply to every request of the agent, the generation config ones
+	// replace them category by category
+	SafetySettings []SafetySetting `json:"safetySettings,omitempty"`
 }
 type User struct {
 	ID      string
@@ -89,21 +108,76 @@ type ChatConfig struct {
 
 // GenerationConfig provides a comprehensive control panel for all generation requests.
 type GenerationConfig struct {
-	Temperature          *float32 `json:"temperature,omitempty"`
-	TopP                 *float32 `json:"topP,omitempty"`
-	TopK                 *float32 `json:"topK,omitempty"`
-	MaxOutputTokens      int32    `json:"maxOutputTokens,omitempty"`
-	StopSequences        []string `json:"stopSequences,omitempty"`
-	ResponseSchemaConfig *SchemaConfig
-	Tools                []*Tool     `json:"tools,omitempty"`
-	ToolConfig           *ToolConfig `json:"toolConfig,omitempty"`
+	Temperature          *float32      `json:"temperature,omitempty"`
+	TopP                 *float32      `json:"topP,omitempty"`
+	TopK                 *float32      `json:"topK,omitempty"`
+	MaxOutputTokens      int32         `json:"maxOutputTokens,omitempty"`
+	StopSequences        []string      `json:"stopSequences,omitempty"`
+	ResponseSchemaConfig *SchemaConfig `json:"responseSchemaConfig,omitempty"`
+	Tools                []*Tool       `json:"tools,omitempty"`
+	ToolGroups           []*ToolGroup  `json:"toolGroups,omitempty"`
+	BuiltinTools         *BuiltinTools `json:"builtinTools,omitempty"`
+	ToolConfig           *ToolConfig   `json:"toolConfig,omitempty"`
+	// ThinkingBudget caps the thinking tokens, nil keeps the model default,
+	// 0 turns thinking off and -1 lets the model decide
+	ThinkingBudget *int32 `json:"thinkingBudget,omitempty"`
+	// IncludeThoughts asks for thought summaries next to the answer, nil keeps the
+	// agent setting so an override can turn them off with false
+	IncludeThoughts *bool           `json:"includeThoughts,omitempty"`
+	SafetySettings  []SafetySetting `json:"safetySettings,omitempty"`
 }
+
+// SafetySetting sets the block threshold of a harm category, values are the gemini
+// names e.g. HARM_CATEGORY_HARASSMENT and BLOCK_ONLY_HIGH
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+	// Method is SEVERITY or PROBABILITY, vertex only
+	Method string `json:"method,omitempty"`
+}
+
+// ModelResponse is the decoded first candidate, every candidate is kept in Candidates
 type ModelResponse struct {
-	Text         string
-	FunctionCall *FunctionCall
-	Error        error
+	Text string
+	// FunctionCall is the first entry of FunctionCalls
+	FunctionCall  *FunctionCall
+	FunctionCalls []FunctionCall
+	// Thoughts joins the thought summary parts, they are not part of Text
+	Thoughts      string
+	FinishReason  string
+	SafetyRatings []SafetyRating
+	Usage         *Usage
+	Candidates    []Candidate
+	Error         error
+}
+
+type Candidate struct {
+	Index         int32
+	Text          string
+	FunctionCalls []FunctionCall
+	Thoughts      string
+	FinishReason  string
+	SafetyRatings []SafetyRating
+}
+
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// Usage is the token accounting of a whole response
+type Usage struct {
+	PromptTokens     int32 `json:"promptTokens"`
+	CandidatesTokens int32 `json:"candidatesTokens"`
+	ThoughtsTokens   int32 `json:"thoughtsTokens,omitempty"`
+	CachedTokens     int32 `json:"cachedTokens,omitempty"`
+	ToolUseTokens    int32 `json:"toolUseTokens,omitempty"`
+	TotalTokens      int32 `json:"totalTokens"`
 }
 type FunctionCall struct {
+	// ID is set by the api to match the call with its response
+	ID   string
 	Name string
 	Args map[string]interface{}
 }
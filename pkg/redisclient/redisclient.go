@@ -3,6 +3,10 @@ package redisclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
 	"github.com/redis/go-redis/v9"
@@ -15,8 +19,13 @@ const (
 	entityChat        = "chat"
 	allChatsSetKey    = "chats:set"
 	entityChatHistory = "chat:history"
+	entityEmbedding   = "embedding"
+	entityLLMResponse = "llm:response"
 )
 
+// ErrNotFound is returned by the getters for keys that are not stored
+var ErrNotFound = errors.New("not found")
+
 // RedisClientInterface defines the contract for our Data Access Layer (DAL) using Redis.
 // It handles persistence for all entities in the system.
 type RedisClientInterface interface {
@@ -25,6 +34,9 @@ type RedisClientInterface interface {
 	GetAgent(ctx context.Context, agentID string) (*genaiconfig.AgentConfig, error)
 	ListAgents(ctx context.Context) ([]*genaiconfig.AgentConfig, error)
 	RemoveAgent(ctx context.Context, agentID string) error
+	// WatchAgents calls onChange with the id of every agent config written or removed until
+	// ctx is done, redis must publish keyspace events (notify-keyspace-events "K$g" or wider)
+	WatchAgents(ctx context.Context, onChange func(agentID string)) error
 
 	// User Management
 	FindUserByID(ctx context.Context, userID string) (*genaiconfig.User, error)
@@ -38,6 +50,12 @@ type RedisClientInterface interface {
 	// Chat History Management
 	SaveChatMessage(ctx context.Context, chatID string, message genaiconfig.ChatMessage) error
 	GetChatHistory(ctx context.Context, chatID string) ([]genaiconfig.ChatMessage, error)
+	// Embedding Cache
+	SetEmbedding(ctx context.Context, hash string, vector []float32, ttl time.Duration) error
+	GetEmbedding(ctx context.Context, hash string) ([]float32, error)
+	// LLM Response Cache
+	SetLLMResponse(ctx context.Context, hash string, response []byte, ttl time.Duration) error
+	GetLLMResponse(ctx context.Context, hash string) ([]byte, error)
 }
 
 // RedisClient is the concrete implementation of the RedisClientInterface.
@@ -61,9 +79,13 @@ func (r *RedisClient) CreateAgent(ctx context.Context, agent genaiconfig.AgentCo
 	return r.saveToSet(ctx, allAgentsSetKey, agent.ID)
 }
 
+// GetAgent reports a missing agent as ErrNotFound, the error still matches redis.Nil
 func (r *RedisClient) GetAgent(ctx context.Context, agentID string) (*genaiconfig.AgentConfig, error) {
 	key := generateKey(entityAgent, agentID)
 	bytes, err := r.getJSONBytes(ctx, key)
+	if err == redis.Nil {
+		return nil, fmt.Errorf("%w: agent %s: %w", ErrNotFound, agentID, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +111,30 @@ func (r *RedisClient) RemoveAgent(ctx context.Context, agentID string) error {
 	return r.removeFromSet(ctx, allAgentsSetKey, agentID)
 }
 
+func (r *RedisClient) WatchAgents(ctx context.Context, onChange func(agentID string)) error {
+	if r.isDisabled {
+		return nil
+	}
+	prefix := fmt.Sprintf("__keyspace@%d__:%s", r.client.Options().DB, generateKey(entityAgent, ""))
+	pubsub := r.client.PSubscribe(ctx, prefix+"*")
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to agent changes: %w", err)
+	}
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			onChange(strings.TrimPrefix(msg.Channel, prefix))
+		}
+	}
+}
+
 // -----------------------------------------------------------
 // User Management
 // -----------------------------------------------------------
@@ -237,3 +283,57 @@ func (r *RedisClient) GetChatHistory(ctx context.Context, chatID string) ([]gena
 	}
 	return history, nil
 }
+
+// -----------------------------------------------------------
+// Embedding Cache
+// -----------------------------------------------------------
+
+// SetEmbedding stores the vector as little endian float32 bytes, ttl 0 keeps it forever
+func (r *RedisClient) SetEmbedding(ctx context.Context, hash string, vector []float32, ttl time.Duration) error {
+	if r.isDisabled {
+		return nil
+	}
+	return r.client.Set(ctx, generateKey(entityEmbedding, hash), EncodeVector(vector), ttl).Err()
+}
+
+// GetEmbedding returns a nil vector without error when the hash is not cached
+func (r *RedisClient) GetEmbedding(ctx context.Context, hash string) ([]float32, error) {
+	if r.isDisabled {
+		return nil, nil
+	}
+	bytes, err := r.getJSONBytes(ctx, generateKey(entityEmbedding, hash))
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return DecodeVector(bytes)
+}
+
+// -----------------------------------------------------------
+// LLM Response Cache
+// -----------------------------------------------------------
+
+// SetLLMResponse stores the encoded model response by request hash, ttl 0 keeps it forever
+func (r *RedisClient) SetLLMResponse(ctx context.Context, hash string, response []byte, ttl time.Duration) error {
+	if r.isDisabled {
+		return nil
+	}
+	return r.client.Set(ctx, generateKey(entityLLMResponse, hash), response, ttl).Err()
+}
+
+// GetLLMResponse returns nil without error when the hash is not cached
+func (r *RedisClient) GetLLMResponse(ctx context.Context, hash string) ([]byte, error) {
+	if r.isDisabled {
+		return nil, nil
+	}
+	bytes, err := r.getJSONBytes(ctx, generateKey(entityLLMResponse, hash))
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes, nil
+}
@@ -2,8 +2,10 @@ package redisclient
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"math"
 )
 
 // -----------------------------------------------------------
@@ -14,6 +16,32 @@ func generateKey(entityType, id string) string {
 	return fmt.Sprintf("%s:%s", entityType, id)
 }
 
+// -----------------------------------------------------------
+// Vector Encoding
+// -----------------------------------------------------------
+
+// EncodeVector encodes the vector as little endian float32 bytes, the layout
+// RediSearch expects for FLOAT32 vector fields
+func EncodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for index, v := range vector {
+		binary.LittleEndian.PutUint32(buf[index*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// DecodeVector is the inverse of EncodeVector
+func DecodeVector(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid float32 vector length %d", len(data))
+	}
+	vector := make([]float32, len(data)/4)
+	for index := range vector {
+		vector[index] = math.Float32frombits(binary.LittleEndian.Uint32(data[index*4:]))
+	}
+	return vector, nil
+}
+
 // -----------------------------------------------------------
 // Base Helpers
 // -----------------------------------------------------------
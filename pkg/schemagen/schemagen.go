@@ -0,0 +1,640 @@
+// Package schemagen turns JSON Schema or genai.Schema documents into Go structs whose
+// tags round-trip through adapter.BuildSchemaFromStruct.
+package schemagen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+	genai "google.golang.org/genai"
+)
+
+var ErrInvalidSchema = errors.New("invalid schema document")
+
+// Options configures the generated file
+type Options struct {
+	// Package of the generated file, required
+	Package string
+	// TypeName of the root schema, required
+	TypeName string
+	// Source is mentioned in the generated header, usually the schema file name
+	Source string
+}
+
+// GenerateFromBytes detects whether data is a JSON Schema or a genai.Schema (upper case
+// types, nullable, propertyOrdering) and generates the Go source for it
+func GenerateFromBytes(data []byte, opts Options) ([]byte, error) {
+	var node map[string]any
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSchema, err)
+	}
+	if isGenAISchema(node) {
+		schema, err := adapter.BuildSchemaFromJson(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSchema, err)
+		}
+		return GenerateFromGenAI(schema, opts)
+	}
+	return Generate(node, opts)
+}
+
+// GenerateFromGenAI generates the Go source for a genai.Schema, the property ordering
+// is kept as the field order
+func GenerateFromGenAI(schema *genai.Schema, opts Options) ([]byte, error) {
+	node := adapter.GenAISchemaToJSONSchema(schema)
+	addPropertyOrdering(schema, node)
+	addMapValues(node)
+	return Generate(node, opts)
+}
+
+// Generate produces the gofmt'ed Go source for a JSON Schema: objects become structs,
+// $defs become named types and recursive references become pointers
+func Generate(schema map[string]any, opts Options) ([]byte, error) {
+	if opts.Package == "" || opts.TypeName == "" {
+		return nil, fmt.Errorf("%w: package and type name are required", ErrInvalidSchema)
+	}
+	g := &generator{
+		root:     schema,
+		defTypes: map[string]string{},
+		names:    map[string]bool{},
+		imports:  map[string]bool{},
+	}
+	return g.run(opts)
+}
+
+// mapDescription is the description BuildSchemaFromStruct gives map fields, genai.Schema
+// has no additionalProperties so it is the only trace of the value type
+var mapDescription = regexp.MustCompile(`^map of (?:string|integer) keys to (string|integer|number|boolean) values$`)
+
+// addMapValues turns the map descriptions of a converted genai schema back into
+// additionalProperties. Only scalar values are described, maps of objects, arrays or
+// any values, and maps with a custom description, stay map[string]any.
+func addMapValues(node map[string]any) {
+	if node == nil {
+		return
+	}
+	if node["type"] == "object" && node["properties"] == nil && node["additionalProperties"] == nil {
+		desc, _ := node["description"].(string)
+		if match := mapDescription.FindStringSubmatch(desc); match != nil {
+			node["additionalProperties"] = map[string]any{"type": match[1]}
+		}
+	}
+	props, _ := node["properties"].(map[string]any)
+	for _, prop := range props {
+		child, _ := prop.(map[string]any)
+		addMapValues(child)
+	}
+	items, _ := node["items"].(map[string]any)
+	addMapValues(items)
+	anyOf, _ := node["anyOf"].([]any)
+	for _, option := range anyOf {
+		child, _ := option.(map[string]any)
+		addMapValues(child)
+	}
+	defs, _ := node["$defs"].(map[string]any)
+	for _, def := range defs {
+		child, _ := def.(map[string]any)
+		addMapValues(child)
+	}
+}
+
+func isGenAISchema(node map[string]any) bool {
+	if _, ok := node["propertyOrdering"]; ok {
+		return true
+	}
+	if _, ok := node["nullable"]; ok {
+		return true
+	}
+	typ, ok := node["type"].(string)
+	return ok && typ != "" && typ == strings.ToUpper(typ)
+}
+
+// addPropertyOrdering copies the genai property ordering next to the json properties
+func addPropertyOrdering(s *genai.Schema, node map[string]any) {
+	if s == nil || node == nil {
+		return
+	}
+	if len(s.PropertyOrdering) > 0 {
+		order := make([]any, len(s.PropertyOrdering))
+		for index, name := range s.PropertyOrdering {
+			order[index] = name
+		}
+		node["propertyOrdering"] = order
+	}
+	props, _ := node["properties"].(map[string]any)
+	for name, prop := range s.Properties {
+		child, _ := props[name].(map[string]any)
+		addPropertyOrdering(prop, child)
+	}
+	items, _ := node["items"].(map[string]any)
+	addPropertyOrdering(s.Items, items)
+	anyOf, _ := node["anyOf"].([]any)
+	for index, option := range s.AnyOf {
+		if index < len(anyOf) {
+			child, _ := anyOf[index].(map[string]any)
+			addPropertyOrdering(option, child)
+		}
+	}
+}
+
+type generator struct {
+	root     map[string]any
+	defs     map[string]any
+	defTypes map[string]string
+	cyclic   map[string]bool
+	names    map[string]bool
+	imports  map[string]bool
+	decls    []string
+}
+
+func (g *generator) run(opts Options) ([]byte, error) {
+	g.defs, _ = g.root["$defs"].(map[string]any)
+	if g.defs == nil {
+		g.defs, _ = g.root["definitions"].(map[string]any)
+	}
+	defNames := make([]string, 0, len(g.defs))
+	for name := range g.defs {
+		defNames = append(defNames, name)
+	}
+	sort.Strings(defNames)
+
+	g.names[exportName(opts.TypeName)] = true
+	for _, name := range defNames {
+		typeName := exportName(name)
+		if typeName == exportName(opts.TypeName) {
+			// the root is usually a $ref to its own definition
+			g.defTypes[name] = typeName
+			continue
+		}
+		g.defTypes[name] = g.uniqueName(typeName)
+	}
+	g.cyclic = cyclicDefs(g.defs)
+
+	rootName := exportName(opts.TypeName)
+	if ref, ok := g.root["$ref"].(string); ok {
+		name, err := g.refName(ref)
+		if err != nil {
+			return nil, err
+		}
+		if g.defTypes[name] != rootName {
+			g.decls = append(g.decls, fmt.Sprintf("type %s = %s\n", rootName, g.defTypes[name]))
+		}
+	} else if err := g.namedType(rootName, g.root); err != nil {
+		return nil, err
+	}
+	for _, name := range defNames {
+		def, ok := g.defs[name].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: definition %s is not a schema", ErrInvalidSchema, name)
+		}
+		if err := g.namedType(g.defTypes[name], def); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	source := ""
+	if opts.Source != "" {
+		source = " from " + opts.Source
+	}
+	fmt.Fprintf(&buf, "// Code generated by schemagen%s. DO NOT EDIT.\n\npackage %s\n\n", source, opts.Package)
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			imports = append(imports, strconv.Quote(path))
+		}
+		sort.Strings(imports)
+		fmt.Fprintf(&buf, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	for _, decl := range g.decls {
+		buf.WriteString(decl)
+		buf.WriteString("\n")
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+func (g *generator) refName(ref string) (string, error) {
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			if _, ok := g.defTypes[name]; ok {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: cannot resolve $ref %q", ErrInvalidSchema, ref)
+}
+
+func (g *generator) uniqueName(name string) string {
+	candidate := name
+	for index := 2; g.names[candidate]; index++ {
+		candidate = fmt.Sprintf("%s%d", name, index)
+	}
+	g.names[candidate] = true
+	return candidate
+}
+
+// namedType declares name as the struct of an object schema, or as the go type of any other schema
+func (g *generator) namedType(name string, node map[string]any) error {
+	if _, ok := node["properties"]; ok {
+		return g.structDecl(name, node)
+	}
+	index := len(g.decls)
+	g.decls = append(g.decls, "")
+	typ, err := g.goType(node, name)
+	if err != nil {
+		return err
+	}
+	g.decls[index] = docComment(node) + fmt.Sprintf("type %s %s\n", name, typ)
+	return nil
+}
+
+func (g *generator) structDecl(name string, node map[string]any) error {
+	index := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	props, _ := node["properties"].(map[string]any)
+	required := map[string]bool{}
+	switch list := node["required"].(type) {
+	case []any:
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				required[s] = true
+			}
+		}
+	case []string:
+		for _, s := range list {
+			required[s] = true
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString(docComment(node))
+	fmt.Fprintf(&sb, "type %s struct {\n", name)
+	fieldNames := map[string]bool{}
+	for _, key := range propertyOrder(node, props) {
+		prop, ok := props[key].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%w: property %s.%s is not a schema", ErrInvalidSchema, name, key)
+		}
+		fieldName := exportName(key)
+		for suffix := 2; fieldNames[fieldName]; suffix++ {
+			fieldName = fmt.Sprintf("%s%d", exportName(key), suffix)
+		}
+		fieldNames[fieldName] = true
+		fieldType, err := g.goType(prop, name+exportName(key))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "\t%s %s %s\n", fieldName, fieldType, fieldTag(key, prop, fieldType, !required[key]))
+	}
+	sb.WriteString("}\n")
+	g.decls[index] = sb.String()
+	return nil
+}
+
+// goType returns the go type of the schema, hint names the structs of inline objects
+func (g *generator) goType(node map[string]any, hint string) (string, error) {
+	if ref, ok := node["$ref"].(string); ok {
+		name, err := g.refName(ref)
+		if err != nil {
+			return "", err
+		}
+		if g.cyclic[name] {
+			return "*" + g.defTypes[name], nil
+		}
+		return g.defTypes[name], nil
+	}
+
+	types, nullable := schemaTypes(node)
+	options := nonNullOptions(node)
+	if len(options) > 0 && len(types) == 0 {
+		if len(options) == 1 {
+			typ, err := g.goType(options[0], hint)
+			if err != nil {
+				return "", err
+			}
+			return nullableType(typ, nullable || len(options) != countOptions(node)), nil
+		}
+		return "any", nil
+	}
+	if len(types) != 1 {
+		return "any", nil
+	}
+
+	typeFormat, _ := node["format"].(string)
+	var typ string
+	switch types[0] {
+	case "string":
+		switch typeFormat {
+		case "date-time":
+			g.imports["time"] = true
+			typ = "time.Time"
+		case "byte":
+			typ = "[]byte"
+		default:
+			typ = "string"
+		}
+	case "integer":
+		typ = "int"
+	case "number":
+		typ = "float64"
+	case "boolean":
+		typ = "bool"
+	case "array":
+		items, _ := node["items"].(map[string]any)
+		if items == nil {
+			return "[]any", nil
+		}
+		elem, err := g.goType(items, hint+"Item")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case "object":
+		if _, ok := node["properties"]; ok {
+			name := g.uniqueName(hint)
+			if err := g.structDecl(name, node); err != nil {
+				return "", err
+			}
+			typ = name
+		} else if additional, ok := node["additionalProperties"].(map[string]any); ok {
+			value, err := g.goType(additional, hint+"Value")
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + value, nil
+		} else {
+			return "map[string]any", nil
+		}
+	default:
+		return "", fmt.Errorf("%w: unknown type %q", ErrInvalidSchema, types[0])
+	}
+	return nullableType(typ, nullable), nil
+}
+
+func nullableType(typ string, nullable bool) string {
+	if !nullable || typ == "any" || strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") {
+		return typ
+	}
+	return "*" + typ
+}
+
+// schemaTypes returns the non null types and whether null is accepted
+func schemaTypes(node map[string]any) ([]string, bool) {
+	var types []string
+	nullable := false
+	switch typ := node["type"].(type) {
+	case string:
+		types = append(types, typ)
+	case []any:
+		for _, t := range typ {
+			if name, ok := t.(string); ok {
+				types = append(types, name)
+			}
+		}
+	}
+	out := types[:0]
+	for _, t := range types {
+		if t == "null" {
+			nullable = true
+			continue
+		}
+		out = append(out, t)
+	}
+	return out, nullable
+}
+
+func countOptions(node map[string]any) int {
+	count := 0
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if options, ok := node[key].([]any); ok {
+			count += len(options)
+		}
+	}
+	return count
+}
+
+func nonNullOptions(node map[string]any) []map[string]any {
+	var out []map[string]any
+	for _, key := range []string{"anyOf", "oneOf"} {
+		options, _ := node[key].([]any)
+		for _, option := range options {
+			optionNode, ok := option.(map[string]any)
+			if !ok || optionNode["type"] == "null" {
+				continue
+			}
+			out = append(out, optionNode)
+		}
+	}
+	return out
+}
+
+func propertyOrder(node map[string]any, props map[string]any) []string {
+	seen := map[string]bool{}
+	var order []string
+	if list, ok := node["propertyOrdering"].([]any); ok {
+		for _, v := range list {
+			if name, ok := v.(string); ok && props[name] != nil && !seen[name] {
+				order = append(order, name)
+				seen[name] = true
+			}
+		}
+	}
+	rest := make([]string, 0, len(props))
+	for name := range props {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(order, rest...)
+}
+
+func docComment(node map[string]any) string {
+	desc, _ := node["description"].(string)
+	if desc == "" {
+		return ""
+	}
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(desc), "\n") {
+		fmt.Fprintf(&sb, "// %s\n", line)
+	}
+	return sb.String()
+}
+
+// fieldTag renders the json and constraint tags adapter.BuildSchemaFromStruct reads
+func fieldTag(key string, node map[string]any, goType string, optional bool) string {
+	jsonTag := key
+	if optional {
+		jsonTag += ",omitempty"
+	}
+	parts := []string{"json:" + strconv.Quote(jsonTag)}
+	add := func(tag string, value string) {
+		parts = append(parts, tag+":"+strconv.Quote(value))
+	}
+	if v, ok := node["description"].(string); ok && v != "" {
+		add("description", v)
+	}
+	if v, ok := node["format"].(string); ok && v != "" && !formatImplied(v, goType) {
+		add("format", v)
+	}
+	if v, ok := node["pattern"].(string); ok && v != "" {
+		add("pattern", v)
+	}
+	if values, ok := node["enum"].([]any); ok {
+		var enum []string
+		valid := true
+		for _, value := range values {
+			if value == nil {
+				continue
+			}
+			text := tagValue(value)
+			if strings.Contains(text, ",") {
+				valid = false
+			}
+			enum = append(enum, text)
+		}
+		if valid && len(enum) > 0 {
+			add("enum", strings.Join(enum, ","))
+		}
+	}
+	for _, key := range []string{"minLength", "maxLength", "minItems", "maxItems", "minimum", "maximum"} {
+		if v, ok := numberValue(node[key]); ok {
+			add(key, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+	if examples, ok := node["examples"].([]any); ok && len(examples) > 0 {
+		add("example", tagValue(examples[0]))
+	} else if v, ok := node["example"]; ok {
+		add("example", tagValue(v))
+	}
+	if v, ok := node["default"]; ok {
+		add("default", tagValue(v))
+	}
+	tag := strings.Join(parts, " ")
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// numberValue reads numbers decoded by encoding/json or set by adapter.GenAISchemaToJSONSchema
+func numberValue(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func formatImplied(format string, goType string) bool {
+	base := strings.TrimPrefix(goType, "*")
+	return (format == "date-time" && base == "time.Time") || (format == "byte" && base == "[]byte")
+}
+
+func tagValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
+
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "API": true, "HTTP": true, "JSON": true,
+	"UUID": true, "SQL": true, "IP": true, "HTML": true, "XML": true,
+}
+
+// exportName turns a json key or definition name into an exported go identifier
+func exportName(key string) string {
+	parts := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var sb strings.Builder
+	for _, part := range parts {
+		if initialisms[strings.ToUpper(part)] {
+			sb.WriteString(strings.ToUpper(part))
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		sb.WriteString(string(runes))
+	}
+	name := sb.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		return "X" + name
+	}
+	return name
+}
+
+// cyclicDefs marks the definitions that can reach themselves through $ref, their
+// references are generated as pointers
+func cyclicDefs(defs map[string]any) map[string]bool {
+	edges := map[string][]string{}
+	for name, def := range defs {
+		collectRefs(def, func(ref string) {
+			for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+				if target, ok := strings.CutPrefix(ref, prefix); ok {
+					edges[name] = append(edges[name], target)
+				}
+			}
+		})
+	}
+	cyclic := map[string]bool{}
+	for name := range defs {
+		visited := map[string]bool{}
+		stack := append([]string(nil), edges[name]...)
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if current == name {
+				cyclic[name] = true
+				break
+			}
+			if visited[current] {
+				continue
+			}
+			visited[current] = true
+			stack = append(stack, edges[current]...)
+		}
+	}
+	return cyclic
+}
+
+func collectRefs(node any, visit func(string)) {
+	switch v := node.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			visit(ref)
+		}
+		for _, child := range v {
+			collectRefs(child, visit)
+		}
+	case []any:
+		for _, child := range v {
+			collectRefs(child, visit)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package schemagen
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+)
+
+type LineItem struct {
+	SKU      string  `json:"sku" minLength:"3" pattern:"^[A-Z0-9-]+$"`
+	Quantity int     `json:"quantity" minimum:"1" example:"2"`
+	Price    float64 `json:"price,omitempty" description:"unit price"`
+}
+
+type Invoice struct {
+	ID       string            `json:"id" format:"uuid" description:"invoice id"`
+	Status   string            `json:"status" enum:"draft,sent,paid" default:"draft"`
+	IssuedAt time.Time         `json:"issued_at"`
+	Lines    []LineItem        `json:"lines" minItems:"1"`
+	Note     *string           `json:"note,omitempty"`
+	Tags     []string          `json:"tags,omitempty" maxItems:"5"`
+	Paid     bool              `json:"paid"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Counts   map[string]int    `json:"counts,omitempty"`
+	// genai schemas do not describe object values, they come back as map[string]any
+	LinesBySKU map[string]LineItem `json:"lines_by_sku,omitempty"`
+}
+
+func TestGenerateFromJSONSchema(t *testing.T) {
+	schema := map[string]any{
+		"type":     "object",
+		"required": []any{"name", "children"},
+		"properties": map[string]any{
+			"name":     map[string]any{"type": "string", "maxLength": float64(20)},
+			"children": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/Node"}},
+			"labels":   map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "integer"}},
+			"parent":   map[string]any{"anyOf": []any{map[string]any{"$ref": "#/$defs/Node"}, map[string]any{"type": "null"}}},
+			"user-id":  map[string]any{"type": []any{"string", "null"}},
+		},
+		"$defs": map[string]any{
+			"Node": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"next": map[string]any{"$ref": "#/$defs/Node"}},
+			},
+		},
+	}
+	source, err := Generate(schema, Options{Package: "models", TypeName: "tree"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	want := []string{
+		"package models",
+		"type Tree struct {",
+		"Children []*Node `json:\"children\"`",
+		"Labels   map[string]int `json:\"labels,omitempty\"`",
+		"Name     string `json:\"name\" maxLength:\"20\"`",
+		"Parent   *Node `json:\"parent,omitempty\"`",
+		"UserID   *string `json:\"user-id,omitempty\"`",
+		"type Node struct {",
+		"Next *Node `json:\"next,omitempty\"`",
+	}
+	normalized := strings.Join(strings.Fields(string(source)), " ")
+	for _, line := range want {
+		if !strings.Contains(normalized, strings.Join(strings.Fields(line), " ")) {
+			t.Errorf("generated source is missing %q:\n%s", line, source)
+		}
+	}
+
+	if _, err := Generate(map[string]any{"$ref": "#/$defs/Missing"}, Options{Package: "models", TypeName: "X"}); err == nil {
+		t.Errorf("Generate() expected an error for an unresolved $ref")
+	}
+}
+
+func TestGenerateFromGenAIMapValues(t *testing.T) {
+	source, err := GenerateFromGenAI(adapter.BuildSchemaFromStruct(Invoice{}), Options{Package: "models", TypeName: "Invoice"})
+	if err != nil {
+		t.Fatalf("GenerateFromGenAI() error = %v", err)
+	}
+	normalized := strings.Join(strings.Fields(string(source)), " ")
+	for _, want := range []string{"Metadata map[string]string", "Counts map[string]int", "LinesBySku map[string]any"} {
+		if !strings.Contains(normalized, want) {
+			t.Errorf("generated source is missing %q:\n%s", want, source)
+		}
+	}
+}
+
+// TestGenerateRoundTrip compiles the structs generated from a genai.Schema and checks
+// BuildSchemaFromStruct gives back the same schema
+func TestGenerateRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a generated program")
+	}
+	original := adapter.BuildSchemaFromStruct(Invoice{})
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	source, err := GenerateFromBytes(data, Options{Package: "main", TypeName: "Invoice"})
+	if err != nil {
+		t.Fatalf("GenerateFromBytes() error = %v", err)
+	}
+
+	// the program gets its own module pointing back at this repo, so nothing is
+	// written inside the package tree
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	goMod := "module roundtrip\n\ngo 1.24\n\nrequire github.com/darwishdev/genaiclient v0.0.0\n\n" +
+		"replace github.com/darwishdev/genaiclient => " + root + "\n"
+	program := `package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+)
+
+func main() {
+	json.NewEncoder(os.Stdout).Encode(adapter.BuildSchemaFromStruct(Invoice{}))
+}
+`
+	goSum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"go.mod":         []byte(goMod),
+		"go.sum":         goSum,
+		"main.go":        []byte(program),
+		"invoice_gen.go": source,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// resolve the dependencies from the module cache only
+	env := append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	var stderr bytes.Buffer
+	tidy := exec.Command("go", "mod", "tidy")
+	tidy.Dir, tidy.Env, tidy.Stderr = dir, env, &stderr
+	if err := tidy.Run(); err != nil {
+		t.Fatalf("go mod tidy error = %v\n%s", err, stderr.String())
+	}
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir, cmd.Env, cmd.Stderr = dir, env, &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("go run error = %v\n%s\n%s", err, stderr.String(), source)
+	}
+	var got, want any
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("round trip schema differs\n got: %s\nwant: %s\nsource:\n%s", gotJSON, wantJSON, source)
+	}
+}
@@ -0,0 +1,35 @@
+// Package similarity scores embedding vectors against each other, it has no
+// dependencies so routers, caches and vector stores can share it
+package similarity
+
+import "math"
+
+// Dot returns the dot product of a and b, ok is false when they cannot be compared
+func Dot(a, b []float32) (float64, bool) {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0, false
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot, true
+}
+
+// Cosine returns the cosine similarity of a and b, ok is false when they cannot be
+// compared or one of them is a zero vector
+func Cosine(a, b []float32) (float64, bool) {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0, false
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0, false
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), true
+}
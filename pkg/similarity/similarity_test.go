@@ -0,0 +1,37 @@
+package similarity
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScores(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       []float32
+		wantCosine float64
+		wantDot    float64
+		wantOK     bool
+	}{
+		{name: "same direction", a: []float32{1, 0}, b: []float32{2, 0}, wantCosine: 1, wantDot: 2, wantOK: true},
+		{name: "orthogonal", a: []float32{1, 0}, b: []float32{0, 1}, wantCosine: 0, wantDot: 0, wantOK: true},
+		{name: "opposite", a: []float32{1, 1}, b: []float32{-1, -1}, wantCosine: -1, wantDot: -2, wantOK: true},
+		{name: "length mismatch", a: []float32{1}, b: []float32{1, 2}},
+		{name: "empty", a: nil, b: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cosine, ok := Cosine(tt.a, tt.b)
+			if ok != tt.wantOK || math.Abs(cosine-tt.wantCosine) > 1e-9 {
+				t.Errorf("Cosine() = %v, %v, want %v, %v", cosine, ok, tt.wantCosine, tt.wantOK)
+			}
+			dot, ok := Dot(tt.a, tt.b)
+			if ok != tt.wantOK || math.Abs(dot-tt.wantDot) > 1e-9 {
+				t.Errorf("Dot() = %v, %v, want %v, %v", dot, ok, tt.wantDot, tt.wantOK)
+			}
+		})
+	}
+	if _, ok := Cosine([]float32{0, 0}, []float32{1, 1}); ok {
+		t.Errorf("Cosine() of a zero vector should not be ok")
+	}
+}
@@ -0,0 +1,36 @@
+package vectorstore
+
+import (
+	"sort"
+
+	"github.com/darwishdev/genaiclient/pkg/similarity"
+)
+
+// Score compares two vectors with the metric, ok is false when they cannot be compared
+func Score(metric Metric, a, b []float32) (float64, bool) {
+	if metric == MetricDotProduct {
+		return similarity.Dot(a, b)
+	}
+	return similarity.Cosine(a, b)
+}
+
+// MatchesFilter reports whether metadata has every filter key with the same value
+func MatchesFilter(metadata map[string]string, filter map[string]string) bool {
+	for k, v := range filter {
+		if metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// TopK sorts the results by descending score and keeps the first k
+func TopK(results []*SearchResult, k int) []*SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
@@ -0,0 +1,105 @@
+package vectorstore
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric Metric
+		a      []float32
+		b      []float32
+		want   float64
+		wantOk bool
+	}{
+		{
+			name:   "Cosine identical",
+			metric: MetricCosine,
+			a:      []float32{1, 2, 3},
+			b:      []float32{2, 4, 6},
+			want:   1,
+			wantOk: true,
+		},
+		{
+			name:   "Cosine orthogonal",
+			metric: MetricCosine,
+			a:      []float32{1, 0},
+			b:      []float32{0, 1},
+			want:   0,
+			wantOk: true,
+		},
+		{
+			name:   "Dot product",
+			metric: MetricDotProduct,
+			a:      []float32{1, 2, 3},
+			b:      []float32{4, 5, 6},
+			want:   32,
+			wantOk: true,
+		},
+		{
+			name:   "Dimension mismatch",
+			metric: MetricCosine,
+			a:      []float32{1, 2},
+			b:      []float32{1, 2, 3},
+			wantOk: false,
+		},
+		{
+			name:   "Zero vector cosine",
+			metric: MetricCosine,
+			a:      []float32{0, 0},
+			b:      []float32{1, 1},
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Score(tt.metric, tt.a, tt.b)
+			if ok != tt.wantOk {
+				t.Fatalf("Score() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesFilter(t *testing.T) {
+	metadata := map[string]string{"lang": "en", "source": "docs"}
+	if !MatchesFilter(metadata, nil) {
+		t.Errorf("MatchesFilter() with empty filter should match")
+	}
+	if !MatchesFilter(metadata, map[string]string{"lang": "en"}) {
+		t.Errorf("MatchesFilter() should match a subset filter")
+	}
+	if MatchesFilter(metadata, map[string]string{"lang": "ar"}) {
+		t.Errorf("MatchesFilter() should not match a different value")
+	}
+	if MatchesFilter(metadata, map[string]string{"missing": "x"}) {
+		t.Errorf("MatchesFilter() should not match a missing key")
+	}
+}
+
+func TestTopK(t *testing.T) {
+	results := []*SearchResult{
+		{Record: &Record{ID: "a"}, Score: 0.2},
+		{Record: &Record{ID: "b"}, Score: 0.9},
+		{Record: &Record{ID: "c"}, Score: 0.5},
+	}
+	got := TopK(results, 2)
+	if len(got) != 2 {
+		t.Fatalf("TopK() returned %d results, want 2", len(got))
+	}
+	if got[0].Record.ID != "b" || got[1].Record.ID != "c" {
+		t.Errorf("TopK() order = [%s %s], want [b c]", got[0].Record.ID, got[1].Record.ID)
+	}
+}
+
+func TestEscapeTag(t *testing.T) {
+	if got := escapeTag("a-b c"); got != `a\-b\ c` {
+		t.Errorf("escapeTag() = %q", got)
+	}
+}
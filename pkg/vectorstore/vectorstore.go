@@ -0,0 +1,456 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/darwishdev/genaiclient/pkg/redisclient"
+	"github.com/redis/go-redis/v9"
+)
+
+type Metric string
+
+const (
+	// MetricCosine scores by cosine similarity (1 is identical)
+	MetricCosine Metric = "COSINE"
+	// MetricDotProduct scores by the raw dot product, use it with normalized vectors
+	MetricDotProduct Metric = "DOT"
+)
+
+const (
+	defaultNamespace = "default"
+	defaultTopK      = 5
+	scanBatchSize    = 256
+	metaFieldPrefix  = "meta_"
+	scoreField       = "__score"
+)
+
+var (
+	ErrRecordNotFound    = errors.New("vector record not found")
+	ErrDimensionMismatch = errors.New("vector dimensions do not match the store")
+	ErrEmptyVector       = errors.New("vector is empty")
+	ErrMissingID         = errors.New("record id is required")
+	ErrFilterNotIndexed  = errors.New("metadata filter field is not indexed")
+)
+
+// Record is a single embedded chunk, DocumentID groups the chunks of one source document
+type Record struct {
+	ID         string            `json:"id"`
+	DocumentID string            `json:"documentID"`
+	Content    string            `json:"content"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Vector     []float32         `json:"-"`
+}
+
+type SearchOptions struct {
+	// TopK is the max number of results (defaults to 5)
+	TopK int
+	// Filter keeps only records whose metadata has every key with the exact value
+	Filter map[string]string
+	// MinScore drops results scoring below it, nil keeps every score (cosine and dot
+	// product scores can be negative)
+	MinScore *float64
+	// Metric overrides the store metric on the brute force path
+	Metric Metric
+}
+
+// keep reports whether a score passes MinScore
+func (o *SearchOptions) keep(score float64) bool {
+	return o.MinScore == nil || score >= *o.MinScore
+}
+
+type SearchResult struct {
+	Record *Record
+	Score  float64
+}
+
+type Config struct {
+	// Namespace isolates the keys of one store from the others (defaults to "default")
+	Namespace string
+	// Dimensions is enforced on upsert and search when set, required for RediSearch
+	Dimensions int
+	Metric     Metric
+	// UseRediSearch searches through an FT index instead of scanning every record,
+	// the client must speak RESP2 (Protocol: 2) or enable UnstableResp3
+	UseRediSearch bool
+	// FilterFields are the metadata keys indexed as TAG fields on the RediSearch path
+	FilterFields []string
+}
+
+// VectorStoreInterface persists embedded records and runs top-k similarity searches
+type VectorStoreInterface interface {
+	EnsureIndex(ctx context.Context) error
+	Upsert(ctx context.Context, records ...*Record) error
+	Get(ctx context.Context, id string) (*Record, error)
+	Search(ctx context.Context, query []float32, options *SearchOptions) ([]*SearchResult, error)
+	// DocumentRecordIDs lists the ids of the records stored for the document
+	DocumentRecordIDs(ctx context.Context, documentID string) ([]string, error)
+	DeleteDocument(ctx context.Context, documentID string) error
+	Delete(ctx context.Context, ids ...string) error
+}
+
+type RedisVectorStore struct {
+	client *redis.Client
+	cfg    Config
+}
+
+// NewRedisVectorStore creates a store that keeps every record in a redis hash, searching
+// is brute force in Go unless cfg.UseRediSearch is set
+func NewRedisVectorStore(client *redis.Client, cfg Config) VectorStoreInterface {
+	if cfg.Namespace == "" {
+		cfg.Namespace = defaultNamespace
+	}
+	if cfg.Metric == "" {
+		cfg.Metric = MetricCosine
+	}
+	return &RedisVectorStore{client: client, cfg: cfg}
+}
+
+// -----------------------------------------------------------
+// Key Helpers
+// -----------------------------------------------------------
+
+func (s *RedisVectorStore) recordPrefix() string {
+	return fmt.Sprintf("vec:%s:rec:", s.cfg.Namespace)
+}
+func (s *RedisVectorStore) recordKey(id string) string {
+	return s.recordPrefix() + id
+}
+func (s *RedisVectorStore) idsKey() string {
+	return fmt.Sprintf("vec:%s:ids", s.cfg.Namespace)
+}
+func (s *RedisVectorStore) documentKey(documentID string) string {
+	return fmt.Sprintf("vec:%s:doc:%s", s.cfg.Namespace, documentID)
+}
+func (s *RedisVectorStore) indexName() string {
+	return fmt.Sprintf("vec:%s:idx", s.cfg.Namespace)
+}
+
+// -----------------------------------------------------------
+// Writes
+// -----------------------------------------------------------
+
+// EnsureIndex creates the RediSearch index when enabled, it is a no-op otherwise
+func (s *RedisVectorStore) EnsureIndex(ctx context.Context) error {
+	if !s.cfg.UseRediSearch {
+		return nil
+	}
+	if s.cfg.Dimensions <= 0 {
+		return fmt.Errorf("%w: dimensions are required for the RediSearch index", ErrDimensionMismatch)
+	}
+	if _, err := s.client.FTInfo(ctx, s.indexName()).Result(); err == nil {
+		return nil
+	}
+	distance := "COSINE"
+	if s.cfg.Metric == MetricDotProduct {
+		distance = "IP"
+	}
+	schema := []*redis.FieldSchema{
+		{
+			FieldName: "vector",
+			FieldType: redis.SearchFieldTypeVector,
+			VectorArgs: &redis.FTVectorArgs{FlatOptions: &redis.FTFlatOptions{
+				Type:           "FLOAT32",
+				Dim:            s.cfg.Dimensions,
+				DistanceMetric: distance,
+			}},
+		},
+		{FieldName: "documentID", FieldType: redis.SearchFieldTypeTag},
+	}
+	for _, field := range s.cfg.FilterFields {
+		schema = append(schema, &redis.FieldSchema{FieldName: metaFieldPrefix + field, FieldType: redis.SearchFieldTypeTag})
+	}
+	return s.client.FTCreate(ctx, s.indexName(), &redis.FTCreateOptions{
+		OnHash: true,
+		Prefix: []interface{}{s.recordPrefix()},
+	}, schema...).Err()
+}
+
+func (s *RedisVectorStore) checkVector(vector []float32) error {
+	if len(vector) == 0 {
+		return ErrEmptyVector
+	}
+	if s.cfg.Dimensions > 0 && len(vector) != s.cfg.Dimensions {
+		return fmt.Errorf("%w: got %d want %d", ErrDimensionMismatch, len(vector), s.cfg.Dimensions)
+	}
+	return nil
+}
+
+// storedDocumentIDs reads the document each existing record belongs to, keyed by record id
+func (s *RedisVectorStore) storedDocumentIDs(ctx context.Context, ids []string) (map[string]string, error) {
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(ids))
+	for index, id := range ids {
+		cmds[index] = pipe.HGet(ctx, s.recordKey(id), "documentID")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	documentIDs := make(map[string]string, len(ids))
+	for index, cmd := range cmds {
+		if documentID, err := cmd.Result(); err == nil && documentID != "" {
+			documentIDs[ids[index]] = documentID
+		}
+	}
+	return documentIDs, nil
+}
+
+func (s *RedisVectorStore) Upsert(ctx context.Context, records ...*Record) error {
+	ids := make([]string, len(records))
+	for index, record := range records {
+		if record.ID == "" {
+			return ErrMissingID
+		}
+		if err := s.checkVector(record.Vector); err != nil {
+			return fmt.Errorf("record %s: %w", record.ID, err)
+		}
+		ids[index] = record.ID
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	// a re-used id moving to another document must leave the old document set, otherwise
+	// deleting the old document would delete the record
+	previous, err := s.storedDocumentIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	pipe := s.client.TxPipeline()
+	for _, record := range records {
+		metadata, err := json.Marshal(record.Metadata)
+		if err != nil {
+			return fmt.Errorf("record %s: failed to marshal metadata: %w", record.ID, err)
+		}
+		fields := map[string]interface{}{
+			"id":         record.ID,
+			"documentID": record.DocumentID,
+			"content":    record.Content,
+			"metadata":   string(metadata),
+			"vector":     redisclient.EncodeVector(record.Vector),
+		}
+		for k, v := range record.Metadata {
+			fields[metaFieldPrefix+k] = v
+		}
+		if old, ok := previous[record.ID]; ok && old != record.DocumentID {
+			pipe.SRem(ctx, s.documentKey(old), record.ID)
+		}
+		pipe.Del(ctx, s.recordKey(record.ID))
+		pipe.HSet(ctx, s.recordKey(record.ID), fields)
+		pipe.SAdd(ctx, s.idsKey(), record.ID)
+		if record.DocumentID != "" {
+			pipe.SAdd(ctx, s.documentKey(record.DocumentID), record.ID)
+		}
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisVectorStore) DocumentRecordIDs(ctx context.Context, documentID string) ([]string, error) {
+	return s.client.SMembers(ctx, s.documentKey(documentID)).Result()
+}
+
+// DeleteDocument removes every record stored for the document
+func (s *RedisVectorStore) DeleteDocument(ctx context.Context, documentID string) error {
+	ids, err := s.DocumentRecordIDs(ctx, documentID)
+	if err != nil {
+		return err
+	}
+	if err := s.Delete(ctx, ids...); err != nil {
+		return err
+	}
+	return s.client.Del(ctx, s.documentKey(documentID)).Err()
+}
+
+// Delete removes single records by id, together with their document set entry
+func (s *RedisVectorStore) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	documentIDs, err := s.storedDocumentIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	pipe := s.client.TxPipeline()
+	for _, id := range ids {
+		pipe.Del(ctx, s.recordKey(id))
+		pipe.SRem(ctx, s.idsKey(), id)
+		if documentID, ok := documentIDs[id]; ok {
+			pipe.SRem(ctx, s.documentKey(documentID), id)
+		}
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// -----------------------------------------------------------
+// Reads
+// -----------------------------------------------------------
+
+func (s *RedisVectorStore) Get(ctx context.Context, id string) (*Record, error) {
+	fields, err := s.client.HGetAll(ctx, s.recordKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRecordNotFound, id)
+	}
+	return recordFromHash(fields)
+}
+
+func recordFromHash(fields map[string]string) (*Record, error) {
+	record := &Record{
+		ID:         fields["id"],
+		DocumentID: fields["documentID"],
+		Content:    fields["content"],
+	}
+	if raw := fields["metadata"]; raw != "" && raw != "null" {
+		if err := json.Unmarshal([]byte(raw), &record.Metadata); err != nil {
+			return nil, fmt.Errorf("record %s: invalid metadata: %w", record.ID, err)
+		}
+	}
+	if raw, ok := fields["vector"]; ok {
+		vector, err := redisclient.DecodeVector([]byte(raw))
+		if err != nil {
+			return nil, fmt.Errorf("record %s: %w", record.ID, err)
+		}
+		record.Vector = vector
+	}
+	return record, nil
+}
+
+func (s *RedisVectorStore) Search(ctx context.Context, query []float32, options *SearchOptions) ([]*SearchResult, error) {
+	if err := s.checkVector(query); err != nil {
+		return nil, err
+	}
+	opts := SearchOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.TopK <= 0 {
+		opts.TopK = defaultTopK
+	}
+	if opts.Metric == "" {
+		opts.Metric = s.cfg.Metric
+	}
+	if s.cfg.UseRediSearch {
+		return s.searchIndex(ctx, query, opts)
+	}
+	return s.searchScan(ctx, query, opts)
+}
+
+// searchScan loads every record of the namespace and scores it in Go
+func (s *RedisVectorStore) searchScan(ctx context.Context, query []float32, opts SearchOptions) ([]*SearchResult, error) {
+	ids, err := s.client.SMembers(ctx, s.idsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*SearchResult, 0, opts.TopK)
+	for start := 0; start < len(ids); start += scanBatchSize {
+		end := min(start+scanBatchSize, len(ids))
+		pipe := s.client.Pipeline()
+		cmds := make([]*redis.MapStringStringCmd, 0, end-start)
+		for _, id := range ids[start:end] {
+			cmds = append(cmds, pipe.HGetAll(ctx, s.recordKey(id)))
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, err
+		}
+		for _, cmd := range cmds {
+			fields, err := cmd.Result()
+			if err != nil || len(fields) == 0 {
+				continue
+			}
+			record, err := recordFromHash(fields)
+			if err != nil || !MatchesFilter(record.Metadata, opts.Filter) {
+				continue
+			}
+			score, ok := Score(opts.Metric, query, record.Vector)
+			if !ok || !opts.keep(score) {
+				continue
+			}
+			results = append(results, &SearchResult{Record: record, Score: score})
+		}
+		results = TopK(results, opts.TopK)
+	}
+	return results, nil
+}
+
+// searchIndex runs a KNN query against the RediSearch index
+func (s *RedisVectorStore) searchIndex(ctx context.Context, query []float32, opts SearchOptions) ([]*SearchResult, error) {
+	filter, err := s.indexFilter(opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	q := fmt.Sprintf("(%s)=>[KNN %d @vector $vec AS %s]", filter, opts.TopK, scoreField)
+	res, err := s.client.FTSearchWithArgs(ctx, s.indexName(), q, &redis.FTSearchOptions{
+		Params:         map[string]interface{}{"vec": redisclient.EncodeVector(query)},
+		Return:         []redis.FTSearchReturn{{FieldName: "id"}, {FieldName: "documentID"}, {FieldName: "content"}, {FieldName: "metadata"}, {FieldName: scoreField}},
+		SortBy:         []redis.FTSearchSortBy{{FieldName: scoreField, Asc: true}},
+		Limit:          opts.TopK,
+		DialectVersion: 2,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*SearchResult, 0, len(res.Docs))
+	for _, doc := range res.Docs {
+		record, err := recordFromHash(doc.Fields)
+		if err != nil {
+			continue
+		}
+		distance, err := strconv.ParseFloat(doc.Fields[scoreField], 64)
+		if err != nil {
+			continue
+		}
+		// both COSINE and IP distances are reported as 1 - similarity
+		score := 1 - distance
+		if !opts.keep(score) {
+			continue
+		}
+		results = append(results, &SearchResult{Record: record, Score: score})
+	}
+	return results, nil
+}
+
+func (s *RedisVectorStore) indexFilter(filter map[string]string) (string, error) {
+	if len(filter) == 0 {
+		return "*", nil
+	}
+	keys := make([]string, 0, len(filter))
+	for k := range filter {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		indexed := false
+		for _, field := range s.cfg.FilterFields {
+			if field == k {
+				indexed = true
+				break
+			}
+		}
+		if !indexed {
+			return "", fmt.Errorf("%w: %s", ErrFilterNotIndexed, k)
+		}
+		parts = append(parts, fmt.Sprintf("@%s%s:{%s}", metaFieldPrefix, k, escapeTag(filter[k])))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// escapeTag escapes the RediSearch tag separators and punctuation
+func escapeTag(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(",.<>{}[]\"':;!@#$%^&*()-+=~|/\\ ", r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
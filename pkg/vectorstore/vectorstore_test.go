@@ -0,0 +1,107 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSearchOptionsKeep(t *testing.T) {
+	zero := 0.0
+	half := 0.5
+	tests := []struct {
+		name     string
+		minScore *float64
+		score    float64
+		want     bool
+	}{
+		{name: "unset keeps negative scores", score: -0.7, want: true},
+		{name: "zero drops negative scores", minScore: &zero, score: -0.1, want: false},
+		{name: "zero keeps zero", minScore: &zero, score: 0, want: true},
+		{name: "below threshold", minScore: &half, score: 0.49, want: false},
+		{name: "at threshold", minScore: &half, score: 0.5, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &SearchOptions{MinScore: tt.minScore}
+			if got := opts.keep(tt.score); got != tt.want {
+				t.Errorf("keep(%v) = %v, want %v", tt.score, got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestRedisStore needs a disposable redis at REDIS_ADDR, every test gets its own namespace
+func newTestRedisStore(t *testing.T) (*RedisVectorStore, *redis.Client) {
+	t.Helper()
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	namespace := fmt.Sprintf("test-%s-%d", t.Name(), time.Now().UnixNano())
+	t.Cleanup(func() {
+		ctx := context.Background()
+		keys, _ := client.Keys(ctx, fmt.Sprintf("vec:%s:*", namespace)).Result()
+		if len(keys) > 0 {
+			client.Del(ctx, keys...)
+		}
+	})
+	store := NewRedisVectorStore(client, Config{Namespace: namespace}).(*RedisVectorStore)
+	return store, client
+}
+
+func TestRedisVectorStoreDocumentSets(t *testing.T) {
+	store, client := newTestRedisStore(t)
+	ctx := context.Background()
+	if err := store.Upsert(ctx,
+		&Record{ID: "shared", DocumentID: "old", Vector: []float32{1, 0}},
+		&Record{ID: "other", DocumentID: "old", Vector: []float32{0, 1}},
+	); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	// the id moves to another document
+	if err := store.Upsert(ctx, &Record{ID: "shared", DocumentID: "new", Vector: []float32{1, 1}}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if err := store.DeleteDocument(ctx, "old"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "shared"); err != nil {
+		t.Errorf("Get(shared) error = %v, deleting the old document removed a moved record", err)
+	}
+	if _, err := store.Get(ctx, "other"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get(other) error = %v, want ErrRecordNotFound", err)
+	}
+
+	if err := store.Delete(ctx, "shared"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	members, err := client.SMembers(ctx, store.documentKey("new")).Result()
+	if err != nil || len(members) != 0 {
+		t.Errorf("document set after Delete = %v, %v, want it empty", members, err)
+	}
+}
+
+func TestRedisVectorStoreNegativeScores(t *testing.T) {
+	store, _ := newTestRedisStore(t)
+	ctx := context.Background()
+	if err := store.Upsert(ctx, &Record{ID: "opposite", Vector: []float32{-1, 0}}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	results, err := store.Search(ctx, []float32{1, 0}, nil)
+	if err != nil || len(results) != 1 || results[0].Score >= 0 {
+		t.Errorf("Search() = %v, %v, want the negative match", results, err)
+	}
+	zero := 0.0
+	results, err = store.Search(ctx, []float32{1, 0}, &SearchOptions{MinScore: &zero})
+	if err != nil || len(results) != 0 {
+		t.Errorf("Search() with MinScore 0 = %v, %v, want no results", results, err)
+	}
+}
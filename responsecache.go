@@ -0,0 +1,225 @@
+package genaiclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// ResponseCacheStore persists encoded model responses by request hash, GetLLMResponse
+// must return nil without error on a miss. redisclient.RedisClient implements it.
+type ResponseCacheStore interface {
+	SetLLMResponse(ctx context.Context, hash string, response []byte, ttl time.Duration) error
+	GetLLMResponse(ctx context.Context, hash string) ([]byte, error)
+}
+
+// ResponseCacheStats are the cache counters since the cache was created
+type ResponseCacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Errors uint64
+}
+
+// GenAIResponseCacheInterface exposes the model callbacks of the exact match cache
+type GenAIResponseCacheInterface interface {
+	Callbacks() (llmagent.BeforeModelCallback, llmagent.AfterModelCallback)
+	Stats() ResponseCacheStats
+}
+
+// ResponseCache replays the stored response of a request identical to a previous one
+// (same model, contents, config and tools), so batch jobs can be rerun offline
+// with the exact same outputs.
+type ResponseCache struct {
+	store   ResponseCacheStore
+	ttl     time.Duration
+	pending *pendingModelCalls[string]
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	errors  atomic.Uint64
+}
+
+// NewResponseCache creates the exact match cache, ttl 0 keeps the responses forever
+func NewResponseCache(store ResponseCacheStore, ttl time.Duration) GenAIResponseCacheInterface {
+	return &ResponseCache{
+		store:   store,
+		ttl:     ttl,
+		pending: newPendingModelCalls[string](pendingModelCallTTL),
+	}
+}
+
+// ResponseCacheKey hashes everything sent to the model: the model name, the contents,
+// the generation config (system instruction, schema and tool declarations included)
+// and the names of the tools attached to the request
+func ResponseCacheKey(req *model.LLMRequest) (string, error) {
+	if req == nil {
+		return "", fmt.Errorf("nil llm request")
+	}
+	toolNames := make([]string, 0, len(req.Tools))
+	for name := range req.Tools {
+		toolNames = append(toolNames, name)
+	}
+	sort.Strings(toolNames)
+	encoded, err := json.Marshal(struct {
+		Model    string                       `json:"model"`
+		Contents []*genai.Content             `json:"contents"`
+		Config   *genai.GenerateContentConfig `json:"config"`
+		Tools    []string                     `json:"tools"`
+	}{req.Model, req.Contents, req.Config, toolNames})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Callbacks returns the before callback that replays hits and the after callback that
+// stores the final responses of misses
+func (c *ResponseCache) Callbacks() (llmagent.BeforeModelCallback, llmagent.AfterModelCallback) {
+	return c.before, c.after
+}
+
+func (c *ResponseCache) Stats() ResponseCacheStats {
+	return ResponseCacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.errors.Load(),
+	}
+}
+
+func (c *ResponseCache) before(ctx agent.CallbackContext, llmRequest *model.LLMRequest) (*model.LLMResponse, error) {
+	// drop the entry of a previous call whose after callback never ran
+	c.pending.take(ctx)
+	key, err := ResponseCacheKey(llmRequest)
+	if err != nil {
+		c.errors.Add(1)
+		log.Debug().Err(err).Msg("response cache key failed")
+		return nil, nil
+	}
+	data, err := c.store.GetLLMResponse(ctx, key)
+	if err != nil {
+		c.errors.Add(1)
+		log.Debug().Err(err).Str("key", key).Msg("response cache read failed")
+	}
+	if data != nil {
+		var resp model.LLMResponse
+		if err := json.Unmarshal(data, &resp); err == nil {
+			c.hits.Add(1)
+			return &resp, nil
+		}
+		c.errors.Add(1)
+	}
+	c.misses.Add(1)
+	c.pending.put(ctx, key)
+	return nil, nil
+}
+
+func (c *ResponseCache) after(ctx agent.CallbackContext, llmResponse *model.LLMResponse, llmErr error) (*model.LLMResponse, error) {
+	if llmResponse != nil && llmResponse.Partial && llmErr == nil {
+		return nil, nil
+	}
+	key, ok := c.pending.take(ctx)
+	if !ok || llmErr != nil || llmResponse == nil || llmResponse.Content == nil ||
+		llmResponse.ErrorCode != "" || llmResponse.Interrupted {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(llmResponse)
+	if err == nil {
+		err = c.store.SetLLMResponse(ctx, key, encoded, c.ttl)
+	}
+	if err != nil {
+		c.errors.Add(1)
+		log.Debug().Err(err).Str("key", key).Msg("response cache write failed")
+	}
+	return nil, nil
+}
+
+// -----------------------------------------------------------
+// Disk store
+// -----------------------------------------------------------
+
+type DiskResponseStore struct {
+	dir string
+}
+
+type diskResponseEntry struct {
+	ExpiresAt *time.Time      `json:"expiresAt,omitempty"`
+	Response  json.RawMessage `json:"response"`
+}
+
+// NewDiskResponseStore keeps one json file per request hash inside dir, keep the
+// directory next to a batch job to rerun it offline
+func NewDiskResponseStore(dir string) (ResponseCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("Failed to create response cache dir: %w", err)
+	}
+	return &DiskResponseStore{dir: dir}, nil
+}
+
+func (s *DiskResponseStore) path(hash string) string {
+	return filepath.Join(s.dir, hash+".json")
+}
+
+func (s *DiskResponseStore) SetLLMResponse(ctx context.Context, hash string, response []byte, ttl time.Duration) error {
+	entry := diskResponseEntry{Response: response}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl).UTC()
+		entry.ExpiresAt = &expiresAt
+	}
+	encoded, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	// write to a temp file first so concurrent readers never see a partial file
+	tmp, err := os.CreateTemp(s.dir, hash+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path(hash)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// GetLLMResponse returns nil for missing and expired entries, expired files are removed
+func (s *DiskResponseStore) GetLLMResponse(ctx context.Context, hash string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry diskResponseEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.ExpiresAt != nil && time.Now().After(*entry.ExpiresAt) {
+		os.Remove(s.path(hash))
+		return nil, nil
+	}
+	return entry.Response, nil
+}
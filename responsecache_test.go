@@ -0,0 +1,208 @@
+package genaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	base := func() *model.LLMRequest {
+		return &model.LLMRequest{
+			Model:    "gemini-2.5-flash",
+			Contents: []*genai.Content{genai.NewContentFromText("hello", genai.RoleUser)},
+			Config:   &genai.GenerateContentConfig{SystemInstruction: genai.NewContentFromText("be brief", genai.RoleUser)},
+			Tools:    map[string]any{"a": nil, "b": nil},
+		}
+	}
+	baseKey, err := ResponseCacheKey(base())
+	if err != nil {
+		t.Fatalf("ResponseCacheKey() error = %v", err)
+	}
+	if again, _ := ResponseCacheKey(base()); again != baseKey {
+		t.Errorf("ResponseCacheKey() is not deterministic")
+	}
+	tests := []struct {
+		name   string
+		mutate func(req *model.LLMRequest)
+	}{
+		{name: "model", mutate: func(req *model.LLMRequest) { req.Model = "gemini-2.5-pro" }},
+		{name: "contents", mutate: func(req *model.LLMRequest) {
+			req.Contents = append(req.Contents, genai.NewContentFromText("again", genai.RoleUser))
+		}},
+		{name: "system instruction", mutate: func(req *model.LLMRequest) {
+			req.Config.SystemInstruction = genai.NewContentFromText("be verbose", genai.RoleUser)
+		}},
+		{name: "generation config", mutate: func(req *model.LLMRequest) { req.Config.Temperature = genai.Ptr[float32](0.2) }},
+		{name: "tools", mutate: func(req *model.LLMRequest) { req.Tools["c"] = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := base()
+			tt.mutate(req)
+			key, err := ResponseCacheKey(req)
+			if err != nil {
+				t.Fatalf("ResponseCacheKey() error = %v", err)
+			}
+			if key == baseKey {
+				t.Errorf("ResponseCacheKey() ignored the %s", tt.name)
+			}
+		})
+	}
+	if _, err := ResponseCacheKey(nil); err == nil {
+		t.Errorf("ResponseCacheKey(nil) expected an error")
+	}
+}
+
+func TestDiskResponseStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	store, err := NewDiskResponseStore(dir)
+	if err != nil {
+		t.Fatalf("NewDiskResponseStore() error = %v", err)
+	}
+	ctx := context.Background()
+	if data, err := store.GetLLMResponse(ctx, "missing"); data != nil || err != nil {
+		t.Errorf("GetLLMResponse(missing) = %s, %v, want nil, nil", data, err)
+	}
+	if err := store.SetLLMResponse(ctx, "kept", []byte(`{"a":1}`), 0); err != nil {
+		t.Fatalf("SetLLMResponse() error = %v", err)
+	}
+	data, err := store.GetLLMResponse(ctx, "kept")
+	var kept map[string]int
+	if err != nil || json.Unmarshal(data, &kept) != nil || kept["a"] != 1 {
+		t.Errorf("GetLLMResponse(kept) = %s, %v", data, err)
+	}
+	if err := store.SetLLMResponse(ctx, "expiring", []byte(`{}`), time.Millisecond); err != nil {
+		t.Fatalf("SetLLMResponse() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if data, err := store.GetLLMResponse(ctx, "expiring"); data != nil || err != nil {
+		t.Errorf("GetLLMResponse(expiring) = %s, %v, want nil, nil", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "expiring.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expired file was not removed: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("cache dir has %d files, want no temp files left", len(entries))
+	}
+	os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{"), 0o644)
+	if _, err := store.GetLLMResponse(ctx, "corrupt"); err == nil {
+		t.Errorf("GetLLMResponse(corrupt) expected an error")
+	}
+}
+
+// memoryResponseStore is a ResponseCacheStore that can be made to fail
+type memoryResponseStore struct {
+	data    map[string][]byte
+	failGet error
+}
+
+func (m *memoryResponseStore) SetLLMResponse(ctx context.Context, hash string, response []byte, ttl time.Duration) error {
+	m.data[hash] = response
+	return nil
+}
+
+func (m *memoryResponseStore) GetLLMResponse(ctx context.Context, hash string) ([]byte, error) {
+	if m.failGet != nil {
+		return nil, m.failGet
+	}
+	return m.data[hash], nil
+}
+
+func newResponseCacheTestAgent(t *testing.T, llm model.LLM, cache GenAIResponseCacheInterface, extra ...llmagent.BeforeModelCallback) GenAIAgentInterface {
+	t.Helper()
+	before, after := cache.Callbacks()
+	return newFakeAgent(t, "batch", llm, llmagent.Config{
+		BeforeModelCallbacks: append([]llmagent.BeforeModelCallback{before}, extra...),
+		AfterModelCallbacks:  []llmagent.AfterModelCallback{after},
+	})
+}
+
+func TestResponseCacheReplaysIdenticalRequests(t *testing.T) {
+	store, err := NewDiskResponseStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskResponseStore() error = %v", err)
+	}
+	llm := textLLM("42")
+	cache := NewResponseCache(store, 0)
+	batch := newResponseCacheTestAgent(t, llm, cache)
+	ctx := context.Background()
+	for _, prompt := range []string{"answer", "answer", "other"} {
+		sendText(t, ctx, batch, prompt)
+	}
+	if llm.calls() != 2 {
+		t.Errorf("model calls = %d, want the repeated request replayed", llm.calls())
+	}
+	if got := sendText(t, ctx, batch, "answer"); got != "42" {
+		t.Errorf("replayed answer = %q", got)
+	}
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Errors != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestResponseCacheStoreErrors(t *testing.T) {
+	llm := textLLM("42")
+	cache := NewResponseCache(&memoryResponseStore{data: map[string][]byte{}, failGet: errors.New("down")}, 0)
+	sendText(t, context.Background(), newResponseCacheTestAgent(t, llm, cache), "answer")
+	if llm.calls() != 1 {
+		t.Errorf("model calls = %d, want the request sent when the store fails", llm.calls())
+	}
+	if stats := cache.Stats(); stats.Errors != 1 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestResponseCacheSkipsFailedResponses(t *testing.T) {
+	store := &memoryResponseStore{data: map[string][]byte{}}
+	llm := &fakeLLM{respond: func(*model.LLMRequest) (*model.LLMResponse, error) {
+		return &model.LLMResponse{ErrorCode: "SAFETY"}, nil
+	}}
+	sendText(t, context.Background(), newResponseCacheTestAgent(t, llm, NewResponseCache(store, 0)), "answer")
+	if len(store.data) != 0 {
+		t.Errorf("stored %d failed responses", len(store.data))
+	}
+}
+
+func TestResponseCacheDropsPendingEntries(t *testing.T) {
+	shortCircuit := func(ctx agent.CallbackContext, req *model.LLMRequest) (*model.LLMResponse, error) {
+		return textResponse("from another callback"), nil
+	}
+	failing := &fakeLLM{respond: func(*model.LLMRequest) (*model.LLMResponse, error) {
+		return nil, errors.New("model down")
+	}}
+	tests := []struct {
+		name        string
+		llm         model.LLM
+		extra       []llmagent.BeforeModelCallback
+		wantPending int
+	}{
+		{name: "model error", llm: failing},
+		{name: "later callback short-circuits", llm: textLLM("x"), extra: []llmagent.BeforeModelCallback{shortCircuit}, wantPending: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewResponseCache(&memoryResponseStore{data: map[string][]byte{}}, 0).(*ResponseCache)
+			cache.pending.ttl = 0
+			batch := newResponseCacheTestAgent(t, tt.llm, cache, tt.extra...)
+			for range 3 {
+				for range batch.NewInMemorySession(context.Background(), "user").Send(context.Background(), "answer") {
+				}
+			}
+			if n := cache.pending.len(); n != tt.wantPending {
+				t.Errorf("pending entries = %d, want %d", n, tt.wantPending)
+			}
+		})
+	}
+}
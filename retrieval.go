@@ -0,0 +1,219 @@
+package genaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darwishdev/genaiclient/pkg/vectorstore"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+const (
+	DefaultRetrievalToolName = "retrieve_documents"
+	// RetrievalCitationsStateKey holds the json encoded citations of the latest retrieval
+	RetrievalCitationsStateKey = "retrieval_citations"
+)
+
+var (
+	ErrRetrievalEmptyQuery = errors.New("retrieval query is empty")
+	ErrRetrievalFailed     = errors.New("retrieval failed")
+)
+
+// Citation points to the stored chunk an answer was grounded on
+type Citation struct {
+	DocumentID string  `json:"documentID"`
+	ChunkID    string  `json:"chunkID"`
+	Chunk      string  `json:"chunk"`
+	Source     string  `json:"source,omitempty"`
+	Start      string  `json:"start,omitempty"`
+	End        string  `json:"end,omitempty"`
+	Score      float64 `json:"score"`
+}
+
+// RetrievalToolConfig configures the retrieval tool and callback, zero values fall back to defaults
+type RetrievalToolConfig struct {
+	Name        string
+	Description string
+	TopK        int
+	// MinScore drops chunks scoring below it, nil keeps every chunk
+	MinScore *float64
+	Filter   map[string]string
+	// Embed options for the query, TaskType defaults to RETRIEVAL_QUERY
+	Embed *EmbedOptions
+}
+
+type retrievalArgs struct {
+	Query string `json:"query"`
+}
+
+type retrievalResult struct {
+	Chunks []Citation `json:"chunks"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// NewRetrievalTool builds a function tool that embeds the model's query, searches the
+// vector store and returns the top chunks so the model can ground its answer. The
+// citations are also written to the session state under RetrievalCitationsStateKey
+// so structured sessions can return them next to the response.
+//
+// Gemini does not allow function calling together with a response schema, so
+// structured agents must use NewRetrievalCallback instead.
+func NewRetrievalTool(embedder GenAIEmbedderInterface, store vectorstore.VectorStoreInterface, cfg RetrievalToolConfig) (tool.Tool, error) {
+	if cfg.Name == "" {
+		cfg.Name = DefaultRetrievalToolName
+	}
+	if cfg.Description == "" {
+		cfg.Description = "Searches the knowledge base and returns the most relevant document chunks for the query. " +
+			"Use it before answering questions about the documents and cite the returned chunks."
+	}
+	embedOpts := queryEmbedOptions(cfg)
+	handler := func(ctx tool.Context, args retrievalArgs) retrievalResult {
+		citations, err := retrieve(ctx, embedder, store, embedOpts, cfg, args.Query)
+		if err != nil {
+			// chunks must stay an array to pass the tool output schema
+			return retrievalResult{Chunks: []Citation{}, Error: err.Error()}
+		}
+		encoded, err := json.Marshal(citations)
+		if err == nil {
+			ctx.Actions().StateDelta[RetrievalCitationsStateKey] = string(encoded)
+		}
+		return retrievalResult{Chunks: citations}
+	}
+	retrievalTool, err := functiontool.New(functiontool.Config{
+		Name:        cfg.Name,
+		Description: cfg.Description,
+	}, handler)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create retrieval tool: %w", err)
+	}
+	return retrievalTool, nil
+}
+
+// NewRetrievalCallback returns a before model callback that searches the vector store
+// with the latest user message and adds the top chunks to the system instruction,
+// so agents with an output schema can be grounded without tools. The citations are
+// written to the session state under RetrievalCitationsStateKey like the tool does.
+// Register it after any cache callbacks, they key on the system instruction.
+func NewRetrievalCallback(embedder GenAIEmbedderInterface, store vectorstore.VectorStoreInterface, cfg RetrievalToolConfig) llmagent.BeforeModelCallback {
+	embedOpts := queryEmbedOptions(cfg)
+	return func(ctx agent.CallbackContext, llmRequest *model.LLMRequest) (*model.LLMResponse, error) {
+		query := lastUserText(llmRequest)
+		if query == "" {
+			return nil, nil
+		}
+		citations, err := retrieve(ctx, embedder, store, embedOpts, cfg, query)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRetrievalFailed, err)
+		}
+		encoded, err := json.Marshal(citations)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRetrievalFailed, err)
+		}
+		if err := ctx.State().Set(RetrievalCitationsStateKey, string(encoded)); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRetrievalFailed, err)
+		}
+		if len(citations) == 0 {
+			return nil, nil
+		}
+		if llmRequest.Config == nil {
+			llmRequest.Config = &genai.GenerateContentConfig{}
+		}
+		if llmRequest.Config.SystemInstruction == nil {
+			llmRequest.Config.SystemInstruction = &genai.Content{Role: genai.RoleUser}
+		}
+		llmRequest.Config.SystemInstruction.Parts = append(llmRequest.Config.SystemInstruction.Parts,
+			genai.NewPartFromText(retrievalContext(citations)))
+		return nil, nil
+	}
+}
+
+// retrievalContext renders the chunks the model should ground its answer on
+func retrievalContext(citations []Citation) string {
+	var b strings.Builder
+	b.WriteString("Answer using the following document chunks and cite them by chunk id.\n")
+	for _, c := range citations {
+		fmt.Fprintf(&b, "\n[chunk %s from %s]\n%s\n", c.ChunkID, c.DocumentID, c.Chunk)
+	}
+	return b.String()
+}
+
+func queryEmbedOptions(cfg RetrievalToolConfig) *EmbedOptions {
+	var embedOpts EmbedOptions
+	if cfg.Embed != nil {
+		embedOpts = *cfg.Embed
+	}
+	if embedOpts.TaskType == "" {
+		embedOpts.TaskType = "RETRIEVAL_QUERY"
+	}
+	return &embedOpts
+}
+
+func retrieve(
+	ctx context.Context,
+	embedder GenAIEmbedderInterface,
+	store vectorstore.VectorStoreInterface,
+	embedOpts *EmbedOptions,
+	cfg RetrievalToolConfig,
+	query string,
+) ([]Citation, error) {
+	if query == "" {
+		return nil, ErrRetrievalEmptyQuery
+	}
+	vectors, err := embedder.Embed(ctx, query, embedOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no embedding returned for the query")
+	}
+	hits, err := store.Search(ctx, vectors[0], &vectorstore.SearchOptions{
+		TopK:     cfg.TopK,
+		MinScore: cfg.MinScore,
+		Filter:   cfg.Filter,
+	})
+	if err != nil {
+		return nil, err
+	}
+	citations := make([]Citation, 0, len(hits))
+	for _, hit := range hits {
+		citations = append(citations, Citation{
+			DocumentID: hit.Record.DocumentID,
+			ChunkID:    hit.Record.ID,
+			Chunk:      hit.Record.Content,
+			Source:     hit.Record.Metadata[MetadataSource],
+			Start:      hit.Record.Metadata[MetadataStart],
+			End:        hit.Record.Metadata[MetadataEnd],
+			Score:      hit.Score,
+		})
+	}
+	return citations, nil
+}
+
+// citationsFromEvent decodes the citations a retrieval tool or callback attached to the event
+func citationsFromEvent(event *session.Event) []Citation {
+	if event == nil {
+		return nil
+	}
+	raw, ok := event.Actions.StateDelta[RetrievalCitationsStateKey]
+	if !ok {
+		return nil
+	}
+	encoded, ok := raw.(string)
+	if !ok {
+		return nil
+	}
+	var citations []Citation
+	if err := json.Unmarshal([]byte(encoded), &citations); err != nil {
+		return nil
+	}
+	return citations
+}
@@ -0,0 +1,186 @@
+package genaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+	"github.com/darwishdev/genaiclient/pkg/vectorstore"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+type groundedAnswer struct {
+	Answer string `json:"answer"`
+}
+
+func newGroundedTestSession(t *testing.T, llm model.LLM, callback llmagent.BeforeModelCallback) GenAIStructuredSessionInterface[string, groundedAnswer] {
+	t.Helper()
+	schema, err := adapter.BuildSchemaFromStructStrict(groundedAnswer{})
+	if err != nil {
+		t.Fatalf("BuildSchemaFromStructStrict() error = %v", err)
+	}
+	base := newFakeAgent(t, "qa", llm, llmagent.Config{
+		OutputSchema:         schema,
+		BeforeModelCallbacks: []llmagent.BeforeModelCallback{callback},
+	})
+	structured := &GenAIStructuredAgent[string, groundedAnswer]{base: base, outputKey: "result"}
+	return structured.NewInMemorySession(context.Background(), "user")
+}
+
+func TestRetrievalCallbackGroundsStructuredAgent(t *testing.T) {
+	embedder := newFakeEmbedder(map[string][]float32{"how do retries work?": {1, 0}})
+	store := newMemoryVectorStore()
+	store.Upsert(context.Background(),
+		&vectorstore.Record{ID: "doc#0", DocumentID: "doc", Content: "retries back off exponentially", Vector: []float32{1, 0},
+			Metadata: map[string]string{MetadataSource: "guide.md"}},
+		&vectorstore.Record{ID: "other#0", DocumentID: "other", Content: "unrelated", Vector: []float32{0, 1}},
+	)
+	minScore := 0.5
+	llm := textLLM(`{"answer":"exponential backoff"}`)
+	sess := newGroundedTestSession(t, llm, NewRetrievalCallback(embedder, store, RetrievalToolConfig{TopK: 2, MinScore: &minScore}))
+
+	res, err := sess.SendGrounded(context.Background(), "how do retries work?")
+	if err != nil {
+		t.Fatalf("SendGrounded() error = %v", err)
+	}
+	if res.Result.Answer != "exponential backoff" {
+		t.Errorf("Result = %+v", res.Result)
+	}
+	if len(res.Citations) != 1 || res.Citations[0].ChunkID != "doc#0" || res.Citations[0].Source != "guide.md" {
+		t.Fatalf("Citations = %+v, want doc#0", res.Citations)
+	}
+	req := llm.lastRequest()
+	if len(req.Config.Tools) != 0 {
+		t.Errorf("request has %d tools, want none next to the response schema", len(req.Config.Tools))
+	}
+	if req.Config.ResponseSchema == nil {
+		t.Errorf("request lost its response schema")
+	}
+	if instruction := contentText(req.Config.SystemInstruction); !strings.Contains(instruction, "retries back off exponentially") {
+		t.Errorf("system instruction = %q, want the retrieved chunk", instruction)
+	}
+}
+
+func TestRetrievalCallbackError(t *testing.T) {
+	llm := textLLM(`{"answer":"x"}`)
+	sess := newGroundedTestSession(t, llm, NewRetrievalCallback(newFakeEmbedder(nil), newMemoryVectorStore(), RetrievalToolConfig{}))
+	if _, err := sess.SendGrounded(context.Background(), "unknown"); !errors.Is(err, ErrRetrievalFailed) {
+		t.Errorf("SendGrounded() error = %v, want ErrRetrievalFailed", err)
+	}
+	if llm.calls() != 0 {
+		t.Errorf("model called %d times after a failed retrieval", llm.calls())
+	}
+}
+
+func TestRetrievalTool(t *testing.T) {
+	embedder := newFakeEmbedder(map[string][]float32{"retries": {1, 0}, "nothing": {-1, 0}})
+	store := newMemoryVectorStore()
+	store.Upsert(context.Background(),
+		&vectorstore.Record{ID: "doc#0", DocumentID: "doc", Content: "retries back off exponentially", Vector: []float32{1, 0},
+			Metadata: map[string]string{MetadataSource: "guide.md"}},
+		&vectorstore.Record{ID: "other#0", DocumentID: "other", Content: "unrelated", Vector: []float32{0, 1}},
+	)
+	minScore := 0.5
+	retrievalTool, err := NewRetrievalTool(embedder, store, RetrievalToolConfig{TopK: 2, MinScore: &minScore})
+	if err != nil {
+		t.Fatalf("NewRetrievalTool() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		wantChunks   []string
+		wantError    bool
+		wantStateSet bool
+	}{
+		{name: "matching chunks", query: "retries", wantChunks: []string{"doc#0"}, wantStateSet: true},
+		{name: "no match", query: "nothing", wantStateSet: true},
+		{name: "embed error", query: "unknown", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &fakeLLM{respond: func(req *model.LLMRequest) (*model.LLMResponse, error) {
+				last := req.Contents[len(req.Contents)-1]
+				if len(last.Parts) > 0 && last.Parts[0].FunctionResponse != nil {
+					return textResponse("exponential backoff"), nil
+				}
+				return &model.LLMResponse{
+					Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+						genai.NewPartFromFunctionCall(DefaultRetrievalToolName, map[string]any{"query": tt.query}),
+					}},
+					TurnComplete: true,
+				}, nil
+			}}
+			a := newFakeAgent(t, "qa", llm, llmagent.Config{Tools: []tool.Tool{retrievalTool}})
+			events := collectEvents(t, a.NewInMemorySession(context.Background(), "user").Send(context.Background(), "how do retries work?"))
+
+			var response *genai.FunctionResponse
+			var state any
+			var stateSet bool
+			for _, event := range events {
+				if event.Content == nil {
+					continue
+				}
+				for _, part := range event.Content.Parts {
+					if part.FunctionResponse != nil {
+						response = part.FunctionResponse
+						state, stateSet = event.Actions.StateDelta[RetrievalCitationsStateKey]
+					}
+				}
+			}
+			if response == nil {
+				t.Fatal("no function response event")
+			}
+			encoded, err := json.Marshal(response.Response)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			var result retrievalResult
+			if err := json.Unmarshal(encoded, &result); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if (result.Error != "") != tt.wantError {
+				t.Errorf("tool error = %q, wantError %v", result.Error, tt.wantError)
+			}
+			var chunkIDs []string
+			for _, chunk := range result.Chunks {
+				chunkIDs = append(chunkIDs, chunk.ChunkID)
+			}
+			if !slices.Equal(chunkIDs, tt.wantChunks) {
+				t.Errorf("chunks = %v, want %v", chunkIDs, tt.wantChunks)
+			}
+			if stateSet != tt.wantStateSet {
+				t.Fatalf("state delta set = %v, want %v", stateSet, tt.wantStateSet)
+			}
+			if !stateSet {
+				return
+			}
+			var citations []Citation
+			if err := json.Unmarshal([]byte(state.(string)), &citations); err != nil {
+				t.Fatalf("citations state = %v: %v", state, err)
+			}
+			if len(citations) != len(tt.wantChunks) {
+				t.Fatalf("citations = %+v, want %v", citations, tt.wantChunks)
+			}
+			for i, citation := range citations {
+				if citation.ChunkID != tt.wantChunks[i] || citation.Source != "guide.md" || citation.Chunk != "retries back off exponentially" {
+					t.Errorf("citation = %+v, want %s from guide.md", citation, tt.wantChunks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetrievalContext(t *testing.T) {
+	got := retrievalContext([]Citation{{DocumentID: "doc", ChunkID: "doc#1", Chunk: "text"}})
+	if !strings.Contains(got, "[chunk doc#1 from doc]\ntext") {
+		t.Errorf("retrievalContext() = %q", got)
+	}
+}
@@ -0,0 +1,326 @@
+package genaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+	"github.com/darwishdev/genaiclient/pkg/similarity"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+var (
+	ErrRouterNoRoutes        = errors.New("router agent requires at least one route")
+	ErrRouterDuplicateRoute  = errors.New("router route names must be unique")
+	ErrRouterUnknownRoute    = errors.New("router selected an unknown route")
+	ErrRouterClassifyFailed  = errors.New("router failed to classify the prompt")
+	ErrRouterEmptyPrompt     = errors.New("router received an empty prompt")
+	ErrRouterFallbackMissing = errors.New("router fallback route is not registered")
+)
+
+// session state keys the router writes its decision to
+const (
+	RouterStateRouteKey  = "router_route"
+	RouterStateReasonKey = "router_reason"
+	RouterStateScoreKey  = "router_score"
+)
+
+// RouteTarget is a sub agent the router can delegate to, the description is what
+// the classifier matches the prompt against
+type RouteTarget struct {
+	Name        string
+	Description string
+	Agent       GenAIAgentNode
+}
+
+// RouteDecision is the result of classifying a single prompt
+type RouteDecision struct {
+	Route  string  `json:"route"`
+	Reason string  `json:"reason,omitempty"`
+	Score  float64 `json:"score,omitempty"`
+}
+
+// RouteClassifier picks the route that should handle the prompt
+type RouteClassifier interface {
+	Classify(ctx context.Context, prompt string, routes []RouteTarget) (*RouteDecision, error)
+}
+
+// NewRouterAgent creates a front door agent that classifies every incoming message,
+// records the decision in the session state and transfers control to the selected route.
+// When classification fails the message is sent to fallbackRoute (if set).
+func NewRouterAgent(
+	appName string,
+	agentName string,
+	agentDescription string,
+	classifier RouteClassifier,
+	fallbackRoute string,
+	routes ...RouteTarget,
+) (GenAIAgentInterface, error) {
+	if len(routes) == 0 {
+		return nil, ErrRouterNoRoutes
+	}
+	// the defaults below are filled in on our own copy, not the caller's slice
+	routes = append([]RouteTarget(nil), routes...)
+	byName := make(map[string]agent.Agent, len(routes))
+	subAgents := make([]agent.Agent, len(routes))
+	for index := range routes {
+		route := &routes[index]
+		if route.Agent == nil || route.Agent.Agent() == nil {
+			return nil, fmt.Errorf("route at index %d has no agent", index)
+		}
+		if route.Name == "" {
+			route.Name = route.Agent.Agent().Name()
+		}
+		if route.Description == "" {
+			route.Description = route.Agent.Agent().Description()
+		}
+		if _, ok := byName[route.Name]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrRouterDuplicateRoute, route.Name)
+		}
+		byName[route.Name] = route.Agent.Agent()
+		subAgents[index] = route.Agent.Agent()
+	}
+	if fallbackRoute != "" {
+		if _, ok := byName[fallbackRoute]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrRouterFallbackMissing, fallbackRoute)
+		}
+	}
+	r := &routerAgent{
+		classifier:    classifier,
+		fallbackRoute: fallbackRoute,
+		routes:        routes,
+		byName:        byName,
+	}
+	router, err := agent.New(agent.Config{
+		Name:        agentName,
+		Description: agentDescription,
+		SubAgents:   subAgents,
+		Run:         r.run,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create router agent: %w", err)
+	}
+	return &GenAIAgent{
+		appName: appName,
+		agent:   router,
+	}, nil
+}
+
+type routerAgent struct {
+	classifier    RouteClassifier
+	fallbackRoute string
+	routes        []RouteTarget
+	byName        map[string]agent.Agent
+}
+
+func (r *routerAgent) run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		ev := session.NewEvent(ctx.InvocationID())
+		ev.Author = ctx.Agent().Name()
+		ev.Branch = ctx.Branch()
+		decision, err := r.decide(ctx, contentText(ctx.UserContent()))
+		if err != nil {
+			// workflow parents read the event next to an error, so never yield a nil one
+			ev.ErrorMessage = err.Error()
+			yield(ev, err)
+			return
+		}
+		target := r.byName[decision.Route]
+		log.Debug().
+			Str("route", decision.Route).
+			Float64("score", decision.Score).
+			Str("reason", decision.Reason).
+			Msg("router decision")
+
+		ev.Actions.TransferToAgent = target.Name()
+		ev.Actions.StateDelta[RouterStateRouteKey] = decision.Route
+		ev.Actions.StateDelta[RouterStateReasonKey] = decision.Reason
+		ev.Actions.StateDelta[RouterStateScoreKey] = decision.Score
+		if !yield(ev, nil) {
+			return
+		}
+		for event, err := range target.Run(ctx) {
+			if !yield(event, err) {
+				return
+			}
+		}
+	}
+}
+
+func (r *routerAgent) decide(ctx context.Context, prompt string) (*RouteDecision, error) {
+	if prompt == "" {
+		return r.fallback(ErrRouterEmptyPrompt)
+	}
+	if r.classifier == nil {
+		return r.fallback(fmt.Errorf("%w: no classifier configured", ErrRouterClassifyFailed))
+	}
+	decision, err := r.classifier.Classify(ctx, prompt, r.routes)
+	if err != nil {
+		return r.fallback(fmt.Errorf("%w: %w", ErrRouterClassifyFailed, err))
+	}
+	if decision == nil || decision.Route == "" {
+		return r.fallback(fmt.Errorf("%w: empty decision", ErrRouterClassifyFailed))
+	}
+	if _, ok := r.byName[decision.Route]; !ok {
+		return r.fallback(fmt.Errorf("%w: %s", ErrRouterUnknownRoute, decision.Route))
+	}
+	return decision, nil
+}
+
+func (r *routerAgent) fallback(cause error) (*RouteDecision, error) {
+	if r.fallbackRoute == "" {
+		return nil, cause
+	}
+	log.Debug().Err(cause).Str("route", r.fallbackRoute).Msg("router falling back")
+	return &RouteDecision{
+		Route:  r.fallbackRoute,
+		Reason: fmt.Sprintf("fallback: %s", cause.Error()),
+	}, nil
+}
+
+// -----------------------------------------------------------
+// LLM classifier
+// -----------------------------------------------------------
+
+type llmRouteClassification struct {
+	Route      string  `json:"route" description:"name of the route that should handle the message"`
+	Reason     string  `json:"reason" description:"short justification for the selected route"`
+	Confidence float64 `json:"confidence" description:"confidence between 0 and 1"`
+}
+
+type llmRouteClassifier struct {
+	client    *genai.Client
+	modelName string
+}
+
+// NewLLMRouteClassifier classifies prompts with a structured generate content call
+// constrained to the registered route names
+func NewLLMRouteClassifier(apiKey string, modelName string) (RouteClassifier, error) {
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{APIKey: apiKey})
+	if err != nil {
+		return nil, err
+	}
+	return &llmRouteClassifier{client: client, modelName: modelName}, nil
+}
+
+func (c *llmRouteClassifier) Classify(ctx context.Context, prompt string, routes []RouteTarget) (*RouteDecision, error) {
+	names := make([]string, len(routes))
+	var sb strings.Builder
+	sb.WriteString("You are a router. Pick the single route best suited to handle the user message.\nRoutes:\n")
+	for index, route := range routes {
+		names[index] = route.Name
+		fmt.Fprintf(&sb, "- %s: %s\n", route.Name, route.Description)
+	}
+	schema := adapter.BuildSchemaFromStruct(llmRouteClassification{})
+	schema.Properties["route"].Enum = names
+	zero := float32(0)
+	resp, err := c.client.Models.GenerateContent(ctx, c.modelName,
+		[]*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)},
+		&genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(sb.String(), genai.RoleUser),
+			Temperature:       &zero,
+			ResponseMIMEType:  "application/json",
+			ResponseSchema:    schema,
+		})
+	if err != nil {
+		return nil, err
+	}
+	var out llmRouteClassification
+	if err := json.Unmarshal([]byte(resp.Text()), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse router classification: %w", err)
+	}
+	return &RouteDecision{
+		Route:  out.Route,
+		Reason: out.Reason,
+		Score:  out.Confidence,
+	}, nil
+}
+
+// -----------------------------------------------------------
+// Embedding classifier
+// -----------------------------------------------------------
+
+type embeddingRouteClassifier struct {
+	embedder  GenAIEmbedderInterface
+	threshold float64
+	options   *EmbedOptions
+	mu        sync.Mutex
+	vectors   map[string][]float32
+}
+
+// NewEmbeddingRouteClassifier classifies prompts by the cosine similarity between the
+// prompt embedding and each route description embedding, decisions scoring below
+// threshold are rejected so the router falls back
+func NewEmbeddingRouteClassifier(embedder GenAIEmbedderInterface, threshold float64, options ...*EmbedOptions) RouteClassifier {
+	var opts *EmbedOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return &embeddingRouteClassifier{
+		embedder:  embedder,
+		threshold: threshold,
+		options:   opts,
+		vectors:   make(map[string][]float32),
+	}
+}
+
+func (c *embeddingRouteClassifier) Classify(ctx context.Context, prompt string, routes []RouteTarget) (*RouteDecision, error) {
+	query, err := c.embed(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	best := &RouteDecision{Score: math.Inf(-1)}
+	for _, route := range routes {
+		vector, err := c.routeVector(ctx, route)
+		if err != nil {
+			return nil, err
+		}
+		score, _ := similarity.Cosine(query, vector)
+		if score > best.Score {
+			best.Route = route.Name
+			best.Score = score
+		}
+	}
+	if best.Score < c.threshold {
+		return nil, fmt.Errorf("best route %s scored %.3f below threshold %.3f", best.Route, best.Score, c.threshold)
+	}
+	best.Reason = "embedding similarity"
+	return best, nil
+}
+
+func (c *embeddingRouteClassifier) routeVector(ctx context.Context, route RouteTarget) ([]float32, error) {
+	c.mu.Lock()
+	vector, ok := c.vectors[route.Name]
+	c.mu.Unlock()
+	if ok {
+		return vector, nil
+	}
+	vector, err := c.embed(ctx, route.Description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed route %s: %w", route.Name, err)
+	}
+	c.mu.Lock()
+	c.vectors[route.Name] = vector
+	c.mu.Unlock()
+	return vector, nil
+}
+
+func (c *embeddingRouteClassifier) embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := c.embedder.Embed(ctx, text, c.options)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+	return vectors[0], nil
+}
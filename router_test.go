@@ -0,0 +1,165 @@
+package genaiclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/adk/agent"
+)
+
+type fixedClassifier struct {
+	decision *RouteDecision
+	err      error
+}
+
+func (c fixedClassifier) Classify(ctx context.Context, prompt string, routes []RouteTarget) (*RouteDecision, error) {
+	return c.decision, c.err
+}
+
+func TestNewRouterAgentRoutes(t *testing.T) {
+	billing := newFakeAgent(t, "billing", textLLM("billing answer"))
+	support := newFakeAgent(t, "support", textLLM("support answer"))
+	tests := []struct {
+		name     string
+		fallback string
+		routes   []RouteTarget
+		wantErr  error
+	}{
+		{name: "no routes", wantErr: ErrRouterNoRoutes},
+		{
+			name:    "duplicate names",
+			routes:  []RouteTarget{{Name: "a", Agent: billing}, {Name: "a", Agent: support}},
+			wantErr: ErrRouterDuplicateRoute,
+		},
+		{
+			name:    "duplicate default names",
+			routes:  []RouteTarget{{Agent: billing}, {Agent: billing}},
+			wantErr: ErrRouterDuplicateRoute,
+		},
+		{
+			name:     "unknown fallback",
+			fallback: "missing",
+			routes:   []RouteTarget{{Agent: billing}},
+			wantErr:  ErrRouterFallbackMissing,
+		},
+		{
+			name:     "fallback by default name",
+			fallback: "support",
+			routes:   []RouteTarget{{Agent: billing}, {Agent: support}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRouterAgent("test_app", "router", "", nil, tt.fallback, tt.routes...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewRouterAgent() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := NewRouterAgent("test_app", "router", "", nil, "", RouteTarget{Name: "x"}); err == nil {
+		t.Errorf("NewRouterAgent() expected an error for a route without agent")
+	}
+}
+
+func TestNewRouterAgentDoesNotMutateRoutes(t *testing.T) {
+	routes := []RouteTarget{{Agent: newFakeAgent(t, "billing", textLLM("x"))}}
+	if _, err := NewRouterAgent("test_app", "router", "", nil, "", routes...); err != nil {
+		t.Fatalf("NewRouterAgent() error = %v", err)
+	}
+	if routes[0].Name != "" || routes[0].Description != "" {
+		t.Errorf("caller routes were modified: %+v", routes[0])
+	}
+}
+
+func TestRouterDecide(t *testing.T) {
+	routes := []RouteTarget{{Name: "billing"}, {Name: "support"}}
+	tests := []struct {
+		name       string
+		classifier RouteClassifier
+		fallback   string
+		prompt     string
+		wantRoute  string
+		wantErr    error
+	}{
+		{name: "classified", classifier: fixedClassifier{decision: &RouteDecision{Route: "billing"}}, prompt: "invoice", wantRoute: "billing"},
+		{name: "empty prompt falls back", classifier: fixedClassifier{}, fallback: "support", wantRoute: "support"},
+		{name: "empty prompt without fallback", classifier: fixedClassifier{}, wantErr: ErrRouterEmptyPrompt},
+		{name: "unknown route falls back", classifier: fixedClassifier{decision: &RouteDecision{Route: "sales"}}, fallback: "support", prompt: "x", wantRoute: "support"},
+		{name: "unknown route", classifier: fixedClassifier{decision: &RouteDecision{Route: "sales"}}, prompt: "x", wantErr: ErrRouterUnknownRoute},
+		{name: "classifier error", classifier: fixedClassifier{err: errors.New("down")}, prompt: "x", wantErr: ErrRouterClassifyFailed},
+		{name: "empty decision", classifier: fixedClassifier{decision: &RouteDecision{}}, prompt: "x", wantErr: ErrRouterClassifyFailed},
+		{name: "no classifier", prompt: "x", wantErr: ErrRouterClassifyFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &routerAgent{
+				classifier:    tt.classifier,
+				fallbackRoute: tt.fallback,
+				routes:        routes,
+				byName:        map[string]agent.Agent{"billing": nil, "support": nil},
+			}
+			decision, err := r.decide(context.Background(), tt.prompt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decide() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && decision.Route != tt.wantRoute {
+				t.Errorf("decide() route = %s, want %s", decision.Route, tt.wantRoute)
+			}
+		})
+	}
+}
+
+func TestRouterTransfersToRoute(t *testing.T) {
+	billing := newFakeAgent(t, "billing", textLLM("billing answer"))
+	support := newFakeAgent(t, "support", textLLM("support answer"))
+	router, err := NewRouterAgent("test_app", "router", "", fixedClassifier{decision: &RouteDecision{Route: "support", Score: 0.9}}, "",
+		RouteTarget{Agent: billing}, RouteTarget{Agent: support})
+	if err != nil {
+		t.Fatalf("NewRouterAgent() error = %v", err)
+	}
+	events := collectEvents(t, router.NewInMemorySession(context.Background(), "user").Send(context.Background(), "help"))
+	var route any
+	var answers []string
+	for _, ev := range events {
+		if v, ok := ev.Actions.StateDelta[RouterStateRouteKey]; ok {
+			route = v
+		}
+		if text := contentText(ev.Content); text != "" {
+			answers = append(answers, ev.Author+":"+text)
+		}
+	}
+	if route != "support" {
+		t.Errorf("routed to %v, want support", route)
+	}
+	if len(answers) != 1 || answers[0] != "support:support answer" {
+		t.Errorf("answers = %v", answers)
+	}
+}
+
+func TestEmbeddingRouteClassifier(t *testing.T) {
+	embedder := newFakeEmbedder(map[string][]float32{
+		"invoices and payments": {1, 0},
+		"technical problems":    {0, 1},
+		"my invoice is wrong":   {0.9, 0.1},
+		"hello":                 {-1, -1},
+	})
+	routes := []RouteTarget{
+		{Name: "billing", Description: "invoices and payments"},
+		{Name: "support", Description: "technical problems"},
+	}
+	classifier := NewEmbeddingRouteClassifier(embedder, 0.5)
+	decision, err := classifier.Classify(context.Background(), "my invoice is wrong", routes)
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if decision.Route != "billing" || decision.Score < 0.9 {
+		t.Errorf("Classify() = %+v, want billing", decision)
+	}
+	if _, err := classifier.Classify(context.Background(), "hello", routes); err == nil {
+		t.Errorf("Classify() expected an error below the threshold")
+	}
+	if got := embedder.callCount("invoices and payments"); got != 1 {
+		t.Errorf("route description embedded %d times, want it cached", got)
+	}
+}
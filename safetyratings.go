@@ -0,0 +1,207 @@
+package genaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// SafetyRatingsMetadataKey holds the []genaiconfig.SafetyRating of a blocked response
+// in LLMResponse.CustomMetadata
+const SafetyRatingsMetadataKey = "safety_ratings"
+
+// geminiModel is the adk gemini model keeping the safety ratings adk drops when it
+// converts the responses. It calls the genai client as is, so vertex clients and
+// custom http clients keep their own transport.
+type geminiModel struct {
+	client *genai.Client
+	name   string
+}
+
+func newGeminiModel(modelName string, client *genai.Client) model.LLM {
+	return &geminiModel{client: client, name: modelName}
+}
+
+func (m *geminiModel) Name() string {
+	return m.name
+}
+
+func (m *geminiModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	appendUserContent(req)
+	if stream {
+		return m.generateStream(ctx, req)
+	}
+	return func(yield func(*model.LLMResponse, error) bool) {
+		res, err := m.client.Models.GenerateContent(ctx, m.name, req.Contents, req.Config)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to call model: %w", err))
+			return
+		}
+		yield(llmResponseFromGemini(res))
+	}
+}
+
+func (m *geminiModel) generateStream(ctx context.Context, req *model.LLMRequest) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		aggregator := &streamAggregator{}
+		for res, err := range m.client.Models.GenerateContentStream(ctx, m.name, req.Contents, req.Config) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			resp, err := llmResponseFromGemini(res)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if len(res.Candidates) == 0 {
+				// a blocked prompt ends the stream
+				yield(resp, nil)
+				return
+			}
+			resp.TurnComplete = res.Candidates[0].FinishReason != ""
+			if aggregated := aggregator.add(resp); aggregated != nil {
+				if !yield(aggregated, nil) {
+					return
+				}
+			}
+			if !yield(resp, nil) {
+				return
+			}
+		}
+		if aggregated := aggregator.flush(); aggregated != nil {
+			yield(aggregated, nil)
+		}
+	}
+}
+
+// appendUserContent makes the request end with a user turn so the model keeps going,
+// like the adk gemini model does
+func appendUserContent(req *model.LLMRequest) {
+	if len(req.Contents) == 0 {
+		req.Contents = append(req.Contents, genai.NewContentFromText("Handle the requests as specified in the System Instruction.", genai.RoleUser))
+	}
+	if last := req.Contents[len(req.Contents)-1]; last != nil && last.Role != genai.RoleUser {
+		req.Contents = append(req.Contents, genai.NewContentFromText("Continue processing previous requests as instructed. Exit or provide a summary if no more outputs are needed.", genai.RoleUser))
+	}
+}
+
+// llmResponseFromGemini converts the first candidate the way adk does and attaches the
+// ratings of a blocked response under SafetyRatingsMetadataKey. A blocked prompt turns
+// into a response carrying the block reason so sessions return a BlockedError.
+func llmResponseFromGemini(res *genai.GenerateContentResponse) (*model.LLMResponse, error) {
+	var resp *model.LLMResponse
+	switch {
+	case len(res.Candidates) > 0 && res.Candidates[0] != nil:
+		candidate := res.Candidates[0]
+		resp = &model.LLMResponse{
+			Content:           candidate.Content,
+			GroundingMetadata: candidate.GroundingMetadata,
+			FinishReason:      candidate.FinishReason,
+			CitationMetadata:  candidate.CitationMetadata,
+			AvgLogprobs:       candidate.AvgLogprobs,
+			LogprobsResult:    candidate.LogprobsResult,
+			UsageMetadata:     res.UsageMetadata,
+		}
+		if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+			resp.Content = nil
+			resp.ErrorCode = string(candidate.FinishReason)
+			resp.ErrorMessage = candidate.FinishMessage
+		}
+	case res.PromptFeedback != nil && res.PromptFeedback.BlockReason != "":
+		resp = &model.LLMResponse{
+			ErrorCode:     string(res.PromptFeedback.BlockReason),
+			ErrorMessage:  res.PromptFeedback.BlockReasonMessage,
+			UsageMetadata: res.UsageMetadata,
+		}
+	default:
+		return nil, errors.New("empty response")
+	}
+	if blocked := adapter.BlockedErrorFromGemini(res); blocked != nil && len(blocked.Ratings) > 0 {
+		resp.CustomMetadata = map[string]any{SafetyRatingsMetadataKey: blocked.Ratings}
+	}
+	return resp, nil
+}
+
+// streamAggregator merges the partial text of a stream into one response, yielded
+// before the next non text response or when the stream ends, like the adk aggregator
+type streamAggregator struct {
+	text     string
+	thought  string
+	role     string
+	response *model.LLMResponse
+}
+
+func (s *streamAggregator) add(resp *model.LLMResponse) *model.LLMResponse {
+	s.response = resp
+	var first *genai.Part
+	if resp.Content != nil && len(resp.Content.Parts) > 0 {
+		first = resp.Content.Parts[0]
+		s.role = resp.Content.Role
+	}
+	if first != nil && first.Text != "" {
+		if first.Thought {
+			s.thought += first.Text
+		} else {
+			s.text += first.Text
+		}
+		resp.Partial = true
+		return nil
+	}
+	// audio chunks keep streaming without a merged response
+	if first != nil && first.InlineData != nil {
+		return nil
+	}
+	return s.flush()
+}
+
+func (s *streamAggregator) flush() *model.LLMResponse {
+	defer func() { *s = streamAggregator{} }()
+	if s.response == nil || s.text == "" && s.thought == "" {
+		return nil
+	}
+	var parts []*genai.Part
+	if s.thought != "" {
+		parts = append(parts, &genai.Part{Text: s.thought, Thought: true})
+	}
+	if s.text != "" {
+		parts = append(parts, &genai.Part{Text: s.text})
+	}
+	return &model.LLMResponse{
+		Content:           &genai.Content{Parts: parts, Role: s.role},
+		ErrorCode:         s.response.ErrorCode,
+		ErrorMessage:      s.response.ErrorMessage,
+		UsageMetadata:     s.response.UsageMetadata,
+		GroundingMetadata: s.response.GroundingMetadata,
+		FinishReason:      s.response.FinishReason,
+		CustomMetadata:    s.response.CustomMetadata,
+	}
+}
+
+// safetyRatingsFromMetadata reads the ratings geminiModel attached, events loaded
+// from a session store carry them as decoded json
+func safetyRatingsFromMetadata(metadata map[string]any) []genaiconfig.SafetyRating {
+	switch ratings := metadata[SafetyRatingsMetadataKey].(type) {
+	case nil:
+		return nil
+	case []genaiconfig.SafetyRating:
+		return ratings
+	default:
+		data, err := json.Marshal(ratings)
+		if err != nil {
+			return nil
+		}
+		var decoded []genaiconfig.SafetyRating
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return nil
+		}
+		return decoded
+	}
+}
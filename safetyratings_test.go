@@ -0,0 +1,207 @@
+package genaiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+const harassmentRating = `{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}`
+
+// newSafetyTestServer serves the given bodies for unary and streamed calls
+func newSafetyTestServer(t *testing.T, unary string, stream []string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ":streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range stream {
+				fmt.Fprintf(w, "data: %s\r\n\r\n", event)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, unary)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newSafetyTestModel(t *testing.T, unary string, stream []string) model.LLM {
+	t.Helper()
+	return newTestGeminiModel(t, &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: newSafetyTestServer(t, unary, stream).URL},
+	})
+}
+
+func newTestGeminiModel(t *testing.T, cfg *genai.ClientConfig) model.LLM {
+	t.Helper()
+	client, err := genai.NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("genai.NewClient() error = %v", err)
+	}
+	return newGeminiModel("test-model", client)
+}
+
+// countingTransport counts the requests sent through a caller http client
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func sendBlocked(t *testing.T, m model.LLM) *BlockedError {
+	t.Helper()
+	sess := newFakeAgent(t, "qa", m).NewInMemorySession(context.Background(), "user")
+	for _, err := range sess.SendStream(context.Background(), "hi") {
+		var blocked *BlockedError
+		if errors.As(err, &blocked) {
+			return blocked
+		}
+		if err != nil {
+			t.Fatalf("stream error = %v, want a BlockedError", err)
+		}
+	}
+	t.Fatalf("stream ended without a BlockedError")
+	return nil
+}
+
+func TestSafetyRatingsStreamedBlock(t *testing.T) {
+	m := newSafetyTestModel(t, "", []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}]}`,
+		`{"candidates":[{"finishReason":"SAFETY","safetyRatings":[` + harassmentRating + `]}]}`,
+	})
+	blocked := sendBlocked(t, m)
+	if blocked.Prompt || blocked.Reason != "SAFETY" {
+		t.Errorf("blocked = %+v, want a blocked answer", blocked)
+	}
+	if len(blocked.Ratings) != 1 || blocked.Ratings[0].Category != "HARM_CATEGORY_HARASSMENT" || !blocked.Ratings[0].Blocked {
+		t.Errorf("Ratings = %+v", blocked.Ratings)
+	}
+}
+
+func TestSafetyRatingsBlockedPrompt(t *testing.T) {
+	m := newSafetyTestModel(t, "", []string{
+		`{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[` + harassmentRating + `]}}`,
+	})
+	blocked := sendBlocked(t, m)
+	if !blocked.Prompt || blocked.Reason != "SAFETY" || len(blocked.Ratings) != 1 {
+		t.Errorf("blocked = %+v, want the blocked prompt with its ratings", blocked)
+	}
+}
+
+func TestSafetyRatingsUnary(t *testing.T) {
+	m := newSafetyTestModel(t, "{\n  \"candidates\": [{\"finishReason\": \"SAFETY\",\n  \"safetyRatings\": ["+harassmentRating+"]}]\n}", nil)
+	for resp, err := range m.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+		if err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+		ratings := safetyRatingsFromMetadata(resp.CustomMetadata)
+		if len(ratings) != 1 || ratings[0].Probability != "HIGH" {
+			t.Errorf("ratings = %+v", ratings)
+		}
+	}
+}
+
+func TestSafetyRatingsKeepCallerClient(t *testing.T) {
+	srv := newSafetyTestServer(t, "", []string{
+		`{"candidates":[{"finishReason":"SAFETY","safetyRatings":[` + harassmentRating + `]}]}`,
+	})
+	tests := []struct {
+		name    string
+		backend genai.Backend
+	}{
+		{name: "gemini api", backend: genai.BackendGeminiAPI},
+		{name: "vertex", backend: genai.BackendVertexAI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &countingTransport{}
+			httpClient := &http.Client{Transport: transport}
+			m := newTestGeminiModel(t, &genai.ClientConfig{
+				APIKey:      "test",
+				Backend:     tt.backend,
+				HTTPClient:  httpClient,
+				HTTPOptions: genai.HTTPOptions{BaseURL: srv.URL},
+			})
+			blocked := sendBlocked(t, m)
+			if len(blocked.Ratings) != 1 {
+				t.Errorf("Ratings = %+v", blocked.Ratings)
+			}
+			if transport.requests.Load() != 1 {
+				t.Errorf("caller transport saw %d requests, want 1", transport.requests.Load())
+			}
+			if httpClient.Transport != transport {
+				t.Errorf("caller http client transport was replaced")
+			}
+		})
+	}
+}
+
+func TestGeminiModelAggregatesStream(t *testing.T) {
+	m := newSafetyTestModel(t, "", []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[]},"finishReason":"STOP"}]}`,
+	})
+	var partial []string
+	var final *model.LLMResponse
+	for resp, err := range m.GenerateContent(context.Background(), &model.LLMRequest{}, true) {
+		if err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+		if resp.Partial {
+			partial = append(partial, contentText(resp.Content))
+		} else if resp.Content != nil && final == nil {
+			final = resp
+		}
+	}
+	if strings.Join(partial, "|") != "Hel|lo" {
+		t.Errorf("partial = %q", partial)
+	}
+	if final == nil || contentText(final.Content) != "Hello" || final.FinishReason != genai.FinishReasonStop {
+		t.Errorf("aggregated = %+v, want Hello", final)
+	}
+}
+
+func TestSafetyRatingsFromStoredMetadata(t *testing.T) {
+	stored := map[string]any{SafetyRatingsMetadataKey: []any{
+		map[string]any{"category": "HARM_CATEGORY_HATE_SPEECH", "probability": "MEDIUM"},
+	}}
+	ratings := safetyRatingsFromMetadata(stored)
+	if len(ratings) != 1 || ratings[0].Category != "HARM_CATEGORY_HATE_SPEECH" {
+		t.Errorf("safetyRatingsFromMetadata() = %+v", ratings)
+	}
+	if safetyRatingsFromMetadata(nil) != nil {
+		t.Errorf("safetyRatingsFromMetadata(nil) should be nil")
+	}
+}
+
+func TestSafetyRatingsHandleGrounded(t *testing.T) {
+	m := newSafetyTestModel(t, "", []string{
+		`{"candidates":[{"finishReason":"SAFETY","safetyRatings":[` + harassmentRating + `]}]}`,
+	})
+	noop := func(agent.CallbackContext, *model.LLMRequest) (*model.LLMResponse, error) { return nil, nil }
+	sess := newGroundedTestSession(t, m, noop)
+	_, err := sess.SendGrounded(context.Background(), "hi")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("SendGrounded() error = %v, want a BlockedError", err)
+	}
+	if len(blocked.Ratings) != 1 || blocked.Ratings[0].Category != "HARM_CATEGORY_HARASSMENT" {
+		t.Errorf("Ratings = %+v", blocked.Ratings)
+	}
+}
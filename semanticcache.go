@@ -0,0 +1,259 @@
+package genaiclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/darwishdev/genaiclient/pkg/vectorstore"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+const (
+	DefaultSemanticCacheThreshold = 0.95
+	// SemanticCacheBypassStateKey skips the cache for the session when set to true
+	SemanticCacheBypassStateKey = "semantic_cache_bypass"
+
+	semanticCacheAgentKey       = "agent"
+	semanticCacheInstructionKey = "instruction"
+	semanticCacheResponseKey    = "response"
+	semanticCacheCreatedAtKey   = "createdAt"
+)
+
+type semanticCacheBypassKey struct{}
+
+// BypassSemanticCache returns a context whose requests skip the semantic cache
+func BypassSemanticCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, semanticCacheBypassKey{}, true)
+}
+
+type SemanticCacheConfig struct {
+	// Threshold is the min cosine similarity for a hit (defaults to 0.95)
+	Threshold float64
+	// TTL expires cached responses, 0 keeps them until invalidated
+	TTL time.Duration
+	// Embed options for the request text, TaskType defaults to SEMANTIC_SIMILARITY
+	Embed *EmbedOptions
+}
+
+// GenAISemanticCacheInterface exposes the model callbacks and the per agent invalidation
+type GenAISemanticCacheInterface interface {
+	Callbacks() (llmagent.BeforeModelCallback, llmagent.AfterModelCallback)
+	Invalidate(ctx context.Context, agentName string) error
+}
+
+// SemanticCache short-circuits model calls whose request is semantically close to a
+// previous request of the same agent with the same instruction. Entries live in a
+// vector store where the document id is the agent name, so invalidation is per agent.
+//
+// Requests are matched on the last user message only, earlier turns of the
+// conversation are not part of the key. Keep it for agents whose answers do not
+// depend on the history, or bypass it for multi-turn sessions.
+type SemanticCache struct {
+	embedder GenAIEmbedderInterface
+	store    vectorstore.VectorStoreInterface
+	cfg      SemanticCacheConfig
+	embed    EmbedOptions
+	pending  *pendingModelCalls[*semanticCacheEntry]
+}
+
+type semanticCacheEntry struct {
+	request     string
+	instruction string
+	vector      []float32
+}
+
+// NewSemanticCache creates the cache, use Callbacks to plug it into an agent config.
+// The store should use its own namespace so cached requests are not mixed with documents.
+func NewSemanticCache(embedder GenAIEmbedderInterface, store vectorstore.VectorStoreInterface, cfg SemanticCacheConfig) GenAISemanticCacheInterface {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultSemanticCacheThreshold
+	}
+	var embed EmbedOptions
+	if cfg.Embed != nil {
+		embed = *cfg.Embed
+	}
+	if embed.TaskType == "" {
+		embed.TaskType = "SEMANTIC_SIMILARITY"
+	}
+	return &SemanticCache{
+		embedder: embedder,
+		store:    store,
+		cfg:      cfg,
+		embed:    embed,
+		pending:  newPendingModelCalls[*semanticCacheEntry](pendingModelCallTTL),
+	}
+}
+
+// Callbacks returns the before callback that serves hits and the after callback that
+// stores the final responses of misses
+func (c *SemanticCache) Callbacks() (llmagent.BeforeModelCallback, llmagent.AfterModelCallback) {
+	return c.before, c.after
+}
+
+// Invalidate drops every cached response of the agent
+func (c *SemanticCache) Invalidate(ctx context.Context, agentName string) error {
+	return c.store.DeleteDocument(ctx, agentName)
+}
+
+func (c *SemanticCache) bypassed(ctx agent.CallbackContext) bool {
+	if bypass, ok := ctx.Value(semanticCacheBypassKey{}).(bool); ok && bypass {
+		return true
+	}
+	v, err := ctx.State().Get(SemanticCacheBypassStateKey)
+	if err != nil {
+		return false
+	}
+	switch value := v.(type) {
+	case bool:
+		return value
+	case string:
+		bypass, _ := strconv.ParseBool(value)
+		return bypass
+	}
+	return false
+}
+
+func (c *SemanticCache) before(ctx agent.CallbackContext, llmRequest *model.LLMRequest) (*model.LLMResponse, error) {
+	// drop the entry of a previous call whose after callback never ran
+	c.pending.take(ctx)
+	if c.bypassed(ctx) {
+		return nil, nil
+	}
+	request := lastUserText(llmRequest)
+	if request == "" {
+		return nil, nil
+	}
+	vectors, err := c.embedder.Embed(ctx, request, &c.embed)
+	if err != nil || len(vectors) == 0 {
+		log.Debug().Err(err).Msg("semantic cache embed failed")
+		return nil, nil
+	}
+	instruction := instructionHash(llmRequest)
+	threshold := c.cfg.Threshold
+	hits, err := c.store.Search(ctx, vectors[0], &vectorstore.SearchOptions{
+		TopK:     1,
+		MinScore: &threshold,
+		Filter: map[string]string{
+			semanticCacheAgentKey:       ctx.AgentName(),
+			semanticCacheInstructionKey: instruction,
+		},
+	})
+	if err != nil {
+		log.Debug().Err(err).Msg("semantic cache search failed")
+	}
+	for _, hit := range hits {
+		resp, ok := c.decodeHit(hit.Record)
+		if !ok {
+			continue
+		}
+		log.Debug().Str("agent", ctx.AgentName()).Float64("score", hit.Score).Msg("semantic cache hit")
+		return resp, nil
+	}
+	c.pending.put(ctx, &semanticCacheEntry{
+		request:     request,
+		instruction: instruction,
+		vector:      vectors[0],
+	})
+	return nil, nil
+}
+
+func (c *SemanticCache) decodeHit(record *vectorstore.Record) (*model.LLMResponse, bool) {
+	if c.cfg.TTL > 0 {
+		createdAt, err := time.Parse(time.RFC3339Nano, record.Metadata[semanticCacheCreatedAtKey])
+		if err != nil || time.Since(createdAt) > c.cfg.TTL {
+			return nil, false
+		}
+	}
+	var resp model.LLMResponse
+	if err := json.Unmarshal([]byte(record.Metadata[semanticCacheResponseKey]), &resp); err != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+func (c *SemanticCache) after(ctx agent.CallbackContext, llmResponse *model.LLMResponse, llmErr error) (*model.LLMResponse, error) {
+	if llmResponse != nil && llmResponse.Partial && llmErr == nil {
+		return nil, nil
+	}
+	entry, ok := c.pending.take(ctx)
+	if !ok || llmErr != nil || !cacheableResponse(llmResponse) {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(llmResponse)
+	if err != nil {
+		return nil, nil
+	}
+	id := sha256.Sum256([]byte(ctx.AgentName() + "\x00" + entry.instruction + "\x00" + entry.request))
+	err = c.store.Upsert(ctx, &vectorstore.Record{
+		ID:         hex.EncodeToString(id[:]),
+		DocumentID: ctx.AgentName(),
+		Content:    entry.request,
+		Metadata: map[string]string{
+			semanticCacheAgentKey:       ctx.AgentName(),
+			semanticCacheInstructionKey: entry.instruction,
+			semanticCacheResponseKey:    string(encoded),
+			semanticCacheCreatedAtKey:   time.Now().UTC().Format(time.RFC3339Nano),
+		},
+		Vector: entry.vector,
+	})
+	if err != nil {
+		log.Debug().Err(err).Msg("semantic cache store failed")
+	}
+	return nil, nil
+}
+
+// cacheableResponse accepts complete text answers, tool calls are never cached
+func cacheableResponse(resp *model.LLMResponse) bool {
+	if resp == nil || resp.Content == nil || resp.ErrorCode != "" || resp.Interrupted {
+		return false
+	}
+	hasText := false
+	for _, p := range resp.Content.Parts {
+		if p == nil {
+			continue
+		}
+		if p.FunctionCall != nil || p.FunctionResponse != nil {
+			return false
+		}
+		if p.Text != "" && !p.Thought {
+			hasText = true
+		}
+	}
+	return hasText
+}
+
+// lastUserText returns the text of the request's last content when it is a user message
+func lastUserText(req *model.LLMRequest) string {
+	if req == nil || len(req.Contents) == 0 {
+		return ""
+	}
+	last := req.Contents[len(req.Contents)-1]
+	if last == nil || last.Role != genai.RoleUser {
+		return ""
+	}
+	for _, p := range last.Parts {
+		if p != nil && p.FunctionResponse != nil {
+			return ""
+		}
+	}
+	return contentText(last)
+}
+
+// instructionHash identifies the model and system instruction the request was made with
+func instructionHash(req *model.LLMRequest) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00", req.Model)
+	if req.Config != nil {
+		fmt.Fprint(h, contentText(req.Config.SystemInstruction))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,132 @@
+package genaiclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func newSemanticCacheTestAgent(t *testing.T, llm model.LLM, cache GenAISemanticCacheInterface, extra ...llmagent.BeforeModelCallback) GenAIAgentInterface {
+	t.Helper()
+	before, after := cache.Callbacks()
+	return newFakeAgent(t, "qa", llm, llmagent.Config{
+		Instruction:          "answer briefly",
+		BeforeModelCallbacks: append([]llmagent.BeforeModelCallback{before}, extra...),
+		AfterModelCallbacks:  []llmagent.AfterModelCallback{after},
+	})
+}
+
+func sendText(t *testing.T, ctx context.Context, a GenAIAgentInterface, prompt string) string {
+	t.Helper()
+	var answer string
+	for _, ev := range collectEvents(t, a.NewInMemorySession(ctx, "user").Send(ctx, prompt)) {
+		if text := contentText(ev.Content); text != "" && ev.Author != string(genai.RoleUser) {
+			answer = text
+		}
+	}
+	return answer
+}
+
+func semanticCacheEmbedder() *fakeEmbedder {
+	return newFakeEmbedder(map[string][]float32{
+		"what is go?":      {1, 0},
+		"what's go?":       {0.99, 0.01},
+		"what is haskell?": {0, 1},
+	})
+}
+
+func TestSemanticCacheServesSimilarRequests(t *testing.T) {
+	llm := textLLM("a language")
+	store := newMemoryVectorStore()
+	cache := NewSemanticCache(semanticCacheEmbedder(), store, SemanticCacheConfig{})
+	qa := newSemanticCacheTestAgent(t, llm, cache)
+	ctx := context.Background()
+
+	if got := sendText(t, ctx, qa, "what is go?"); got != "a language" {
+		t.Fatalf("first answer = %q", got)
+	}
+	if got := sendText(t, ctx, qa, "what's go?"); got != "a language" {
+		t.Errorf("cached answer = %q", got)
+	}
+	if llm.calls() != 1 {
+		t.Errorf("model calls = %d, want the similar request served from the cache", llm.calls())
+	}
+	sendText(t, ctx, qa, "what is haskell?")
+	if llm.calls() != 2 {
+		t.Errorf("model calls = %d, want a miss below the threshold", llm.calls())
+	}
+	sendText(t, BypassSemanticCache(ctx), qa, "what is go?")
+	if llm.calls() != 3 {
+		t.Errorf("model calls = %d, want the bypassed request sent to the model", llm.calls())
+	}
+	if err := cache.Invalidate(ctx, "qa"); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	sendText(t, ctx, qa, "what is go?")
+	if llm.calls() != 4 {
+		t.Errorf("model calls = %d, want a miss after invalidation", llm.calls())
+	}
+}
+
+func TestSemanticCacheTTL(t *testing.T) {
+	llm := textLLM("a language")
+	cache := NewSemanticCache(semanticCacheEmbedder(), newMemoryVectorStore(), SemanticCacheConfig{TTL: time.Millisecond})
+	qa := newSemanticCacheTestAgent(t, llm, cache)
+	sendText(t, context.Background(), qa, "what is go?")
+	time.Sleep(5 * time.Millisecond)
+	sendText(t, context.Background(), qa, "what is go?")
+	if llm.calls() != 2 {
+		t.Errorf("model calls = %d, want the expired entry ignored", llm.calls())
+	}
+}
+
+func TestSemanticCacheSkipsUncacheableResponses(t *testing.T) {
+	llm := &fakeLLM{respond: func(*model.LLMRequest) (*model.LLMResponse, error) {
+		return &model.LLMResponse{Content: genai.NewContentFromText("partial", genai.RoleModel), ErrorCode: "MAX_TOKENS"}, nil
+	}}
+	store := newMemoryVectorStore()
+	qa := newSemanticCacheTestAgent(t, llm, NewSemanticCache(semanticCacheEmbedder(), store, SemanticCacheConfig{}))
+	sendText(t, context.Background(), qa, "what is go?")
+	if len(store.records) != 0 {
+		t.Errorf("stored %d entries for an error response", len(store.records))
+	}
+}
+
+func TestSemanticCacheDropsPendingEntries(t *testing.T) {
+	shortCircuit := func(ctx agent.CallbackContext, req *model.LLMRequest) (*model.LLMResponse, error) {
+		return textResponse("from another callback"), nil
+	}
+	failing := &fakeLLM{respond: func(*model.LLMRequest) (*model.LLMResponse, error) {
+		return nil, errors.New("model down")
+	}}
+	tests := []struct {
+		name  string
+		llm   model.LLM
+		extra []llmagent.BeforeModelCallback
+		// the after callback never runs on a short-circuit, only the latest entry waits for the sweep
+		wantPending int
+	}{
+		{name: "model error", llm: failing},
+		{name: "later callback short-circuits", llm: textLLM("x"), extra: []llmagent.BeforeModelCallback{shortCircuit}, wantPending: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewSemanticCache(semanticCacheEmbedder(), newMemoryVectorStore(), SemanticCacheConfig{}).(*SemanticCache)
+			cache.pending.ttl = 0
+			qa := newSemanticCacheTestAgent(t, tt.llm, cache, tt.extra...)
+			for range 3 {
+				for range qa.NewInMemorySession(context.Background(), "user").Send(context.Background(), "what is go?") {
+				}
+			}
+			if n := cache.pending.len(); n != tt.wantPending {
+				t.Errorf("pending entries = %d, want %d", n, tt.wantPending)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package genaiclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+	"google.golang.org/genai"
+)
+
+// recordingUploader is an adapter.FileUploader keeping the uploaded bytes, files are ACTIVE right away
+type recordingUploader struct {
+	mu      sync.Mutex
+	uploads [][]byte
+}
+
+func (r *recordingUploader) Upload(ctx context.Context, reader io.Reader, config *genai.UploadFileConfig) (*genai.File, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.uploads = append(r.uploads, data)
+	return &genai.File{Name: "files/1", URI: "memory://files/1", MIMEType: config.MIMEType, State: genai.FileStateActive}, nil
+}
+
+func (r *recordingUploader) Get(ctx context.Context, name string, config *genai.GetFileConfig) (*genai.File, error) {
+	return nil, errors.New("not expected")
+}
+
+func TestSendPromptUploadsLargeLocalFiles(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "note.txt")
+	large := filepath.Join(dir, "recording.mp3")
+	if err := os.WriteFile(small, []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(large, bytes.Repeat([]byte("a"), 64), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	llm := textLLM("done")
+	a := newFakeAgent(t, "qa", llm)
+	uploader := &recordingUploader{}
+	a.(*GenAIAgent).fileOptions = &adapter.FileOptions{Uploader: uploader, InlineLimit: 16}
+
+	sess := a.NewInMemorySession(context.Background(), "user")
+	collectEvents(t, sess.SendPrompt(context.Background(), &genaiconfig.Prompt{
+		Text:  "summarize",
+		Files: []genaiconfig.FileConfig{{Path: small}, {Path: large}},
+	}))
+
+	if len(uploader.uploads) != 1 || len(uploader.uploads[0]) != 64 {
+		t.Fatalf("uploads = %d, want only the large file", len(uploader.uploads))
+	}
+	req := llm.lastRequest()
+	parts := req.Contents[len(req.Contents)-1].Parts
+	if len(parts) != 3 || parts[0].Text != "summarize" {
+		t.Fatalf("parts = %+v, want the text and both files", parts)
+	}
+	if parts[1].InlineData == nil || string(parts[1].InlineData.Data) != "hi" {
+		t.Errorf("small file part = %+v, want it inlined", parts[1])
+	}
+	if parts[2].FileData == nil || parts[2].FileData.FileURI != "memory://files/1" || parts[2].FileData.MIMEType != "audio/mpeg" {
+		t.Errorf("large file part = %+v, want the uploaded file uri", parts[2])
+	}
+}
+
+func TestSendPromptConversionError(t *testing.T) {
+	sess := newFakeAgent(t, "qa", textLLM("done")).NewInMemorySession(context.Background(), "user")
+	for _, err := range sess.SendPrompt(context.Background(), &genaiconfig.Prompt{}) {
+		if !errors.Is(err, ErrContentConversionFailed) {
+			t.Errorf("SendPrompt() error = %v, want ErrContentConversionFailed", err)
+		}
+	}
+}
+
+func TestNewGeminiAgentUploadsThroughGenAIClient(t *testing.T) {
+	a, err := NewGeminiAgent("test_app", "test-key", "gemini-2.5-flash", "qa", "", "", nil, nil, false)
+	if err != nil {
+		t.Fatalf("NewGeminiAgent() error = %v", err)
+	}
+	agent := a.(*GenAIAgent)
+	if agent.fileOptions == nil || agent.fileOptions.Uploader != agent.genaiClient.Files {
+		t.Fatalf("fileOptions = %+v, want the genai client Files service", agent.fileOptions)
+	}
+	sess := a.NewInMemorySession(context.Background(), "user").(*GenAISession)
+	if sess.fileOptions != agent.fileOptions {
+		t.Errorf("session fileOptions = %+v, want the agent ones", sess.fileOptions)
+	}
+}
@@ -2,8 +2,12 @@ package genaiclient
 
 import (
 	"context"
+	"fmt"
 	"iter"
 
+	"github.com/darwishdev/genaiclient/pkg/adapter"
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/runner"
 	"google.golang.org/adk/session"
@@ -12,12 +16,30 @@ import (
 
 type GenAISessionInterface interface {
 	Send(ctx context.Context, prompt string) iter.Seq2[*session.Event, error]
+	// SetGenerationConfig overrides the agent generation config for the rest of the
+	// session, WithGenerationConfig on the Send context overrides it per message
+	SetGenerationConfig(cfg *genaiconfig.GenerationConfig) error
+	// SendStream is Send with the answer text and the thought summaries split apart
+	SendStream(ctx context.Context, prompt string) iter.Seq2[*StreamChunk, error]
+	// SendPrompt sends text together with files, large local files are uploaded through
+	// the Files API of the agent's genai client instead of being inlined
+	SendPrompt(ctx context.Context, prompt *genaiconfig.Prompt) iter.Seq2[*session.Event, error]
 }
 
 type GenAISession struct {
-	session   session.Session
-	outputKey string
-	runner    *runner.Runner
+	session          session.Session
+	outputKey        string
+	runner           *runner.Runner
+	generationConfig *genaiconfig.GenerationConfig
+	fileOptions      *adapter.FileOptions
+}
+
+func (s *GenAISession) SetGenerationConfig(cfg *genaiconfig.GenerationConfig) error {
+	if _, err := GenerateContentConfig(cfg); err != nil {
+		return err
+	}
+	s.generationConfig = cfg
+	return nil
 }
 
 func (s *GenAISession) Send(ctx context.Context, prompt string) iter.Seq2[*session.Event, error] {
@@ -27,9 +49,99 @@ func (s *GenAISession) Send(ctx context.Context, prompt string) iter.Seq2[*sessi
 		},
 		Role: string(genai.RoleUser),
 	}
+	return s.run(ctx, msg)
+}
+
+func (s *GenAISession) SendPrompt(ctx context.Context, prompt *genaiconfig.Prompt) iter.Seq2[*session.Event, error] {
+	if prompt == nil {
+		prompt = &genaiconfig.Prompt{}
+	}
+	contents, err := adapter.GeminiContentFromPromptWithFiles(ctx, prompt, s.fileOptions)
+	if err != nil {
+		return func(yield func(*session.Event, error) bool) {
+			yield(nil, fmt.Errorf("%w: %w", ErrContentConversionFailed, err))
+		}
+	}
+	return s.run(ctx, contents[0])
+}
+
+func (s *GenAISession) run(ctx context.Context, msg *genai.Content) iter.Seq2[*session.Event, error] {
 	cfg := agent.RunConfig{
 		StreamingMode: agent.StreamingModeSSE,
 	}
+	ctx = withBaseGenerationConfig(ctx, s.generationConfig)
 	itr := s.runner.Run(ctx, s.session.UserID(), s.session.ID(), msg, cfg)
 	return itr
 }
+
+func (s *GenAISession) SendStream(ctx context.Context, prompt string) iter.Seq2[*StreamChunk, error] {
+	return SplitThoughts(s.Send(ctx, prompt))
+}
+
+// StreamChunk is one event of a session stream with its new text split into the answer
+// and the thought summary, both are empty for events repeating text already streamed
+type StreamChunk struct {
+	Event   *session.Event
+	Text    string
+	Thought string
+}
+
+// SplitThoughts separates thought parts from answer text. Streamed partial events carry
+// the text, the complete event closing them is yielded without text unless nothing was
+// streamed before it (e.g. a cached response)
+func SplitThoughts(seq iter.Seq2[*session.Event, error]) iter.Seq2[*StreamChunk, error] {
+	return func(yield func(*StreamChunk, error) bool) {
+		streamed := false
+		for event, err := range seq {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if blocked := blockedError(event); blocked != nil {
+				if !yield(nil, blocked) {
+					return
+				}
+				continue
+			}
+			chunk := &StreamChunk{Event: event}
+			if event.Content != nil && (event.Partial || !streamed) {
+				chunk.Text, chunk.Thought = splitParts(event.Content.Parts)
+			}
+			streamed = event.Partial
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+	}
+}
+
+func splitParts(parts []*genai.Part) (text string, thought string) {
+	for _, p := range parts {
+		if p == nil || p.Text == "" {
+			continue
+		}
+		if p.Thought {
+			thought += p.Text
+		} else {
+			text += p.Text
+		}
+	}
+	return text, thought
+}
+
+// blockedError returns the BlockedError of an event, adk keeps the finish or block
+// reason of the response and the gemini models of this package add the safety ratings
+func blockedError(event *session.Event) *BlockedError {
+	resp := event.LLMResponse
+	if adapter.IsBlockedFinishReason(resp.FinishReason) {
+		return &BlockedError{Reason: string(resp.FinishReason), Message: resp.ErrorMessage,
+			Ratings: safetyRatingsFromMetadata(resp.CustomMetadata)}
+	}
+	if resp.FinishReason == "" && resp.Content == nil && adapter.IsBlockedPromptReason(resp.ErrorCode) {
+		return &BlockedError{Reason: resp.ErrorCode, Message: resp.ErrorMessage, Prompt: true,
+			Ratings: safetyRatingsFromMetadata(resp.CustomMetadata)}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package genaiclient
+
+import (
+	"errors"
+	"iter"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func modelEvent(partial bool, parts ...*genai.Part) *session.Event {
+	return &session.Event{
+		Author: "qa",
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{Role: genai.RoleModel, Parts: parts},
+			Partial: partial,
+		},
+	}
+}
+
+func eventSeq(events []*session.Event, errs ...error) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		for _, ev := range events {
+			if !yield(ev, nil) {
+				return
+			}
+		}
+		for _, err := range errs {
+			if !yield(nil, err) {
+				return
+			}
+		}
+	}
+}
+
+func TestSplitThoughts(t *testing.T) {
+	thought := &genai.Part{Text: "planning", Thought: true}
+	events := []*session.Event{
+		modelEvent(true, thought),
+		modelEvent(true, genai.NewPartFromText("Hel")),
+		modelEvent(true, genai.NewPartFromText("lo")),
+		// the aggregated event repeats everything streamed before it
+		modelEvent(false, thought, genai.NewPartFromText("Hello")),
+		// a complete response that was never streamed, e.g. served from a cache
+		modelEvent(false, &genai.Part{Text: "recalled", Thought: true}, genai.NewPartFromText("cached")),
+		{LLMResponse: model.LLMResponse{FinishReason: genai.FinishReasonSafety}},
+	}
+	streamErr := errors.New("stream failed")
+	type got struct{ text, thought string }
+	var chunks []got
+	var errs []error
+	for chunk, err := range SplitThoughts(eventSeq(events, streamErr)) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		chunks = append(chunks, got{chunk.Text, chunk.Thought})
+	}
+	want := []got{
+		{"", "planning"},
+		{"Hel", ""},
+		{"lo", ""},
+		{"", ""},
+		{"cached", "recalled"},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("chunks = %+v, want %+v", chunks, want)
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Errorf("chunk %d = %+v, want %+v", i, chunks[i], want[i])
+		}
+	}
+	var blocked *BlockedError
+	if len(errs) != 2 || !errors.As(errs[0], &blocked) || blocked.Reason != string(genai.FinishReasonSafety) || !errors.Is(errs[1], streamErr) {
+		t.Errorf("errors = %v, want the blocked error then the stream error", errs)
+	}
+}
+
+func TestHandleGroundedStreamedAndCached(t *testing.T) {
+	sess := &GenAIStructuredSession[string, groundedAnswer]{}
+	var thoughts []string
+	sess.OnThought(func(thought string) { thoughts = append(thoughts, thought) })
+	streamed := []*session.Event{
+		modelEvent(true, &genai.Part{Text: "thinking", Thought: true}),
+		modelEvent(true, genai.NewPartFromText(`{"answer":`)),
+		modelEvent(true, genai.NewPartFromText(`"streamed"}`)),
+		modelEvent(false, &genai.Part{Text: "thinking", Thought: true}, genai.NewPartFromText(`{"answer":"streamed"}`)),
+	}
+	res, err := sess.HandleGrounded(eventSeq(streamed))
+	if err != nil {
+		t.Fatalf("HandleGrounded() error = %v", err)
+	}
+	if res.Result.Answer != "streamed" || res.Thoughts != "thinking" || len(thoughts) != 1 {
+		t.Errorf("HandleGrounded() = %+v, thoughts %v", res, thoughts)
+	}
+	res, err = sess.HandleGrounded(eventSeq([]*session.Event{modelEvent(false, genai.NewPartFromText(`{"answer":"cached"}`))}))
+	if err != nil || res.Result.Answer != "cached" {
+		t.Errorf("HandleGrounded() cached = %+v, %v", res, err)
+	}
+}
@@ -6,9 +6,10 @@ import (
 	"reflect"
 
 	"github.com/darwishdev/genaiclient/pkg/adapter"
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
-	"google.golang.org/adk/model/gemini"
 	"google.golang.org/genai"
 )
 
@@ -24,6 +25,7 @@ type GenAIStructuredAgentInterface[TReq any, TRes any] interface {
 		sessionID string,
 		rdb *redis.Client,
 	) (GenAIStructuredSessionInterface[TReq, TRes], error)
+	Agent() agent.Agent
 }
 
 type GenAIStructuredAgent[TReq any, TRes any] struct {
@@ -56,23 +58,32 @@ func NewStructuredAgent[TReq any, TRes any](
 	cfg.Instruction = agentInstructions
 	if !isEmptyStruct[TRes]() {
 		var tr TRes
-		cfg.OutputSchema = adapter.BuildSchemaFromStruct(tr)
+		schema, err := adapter.BuildSchemaFromStructStrict(tr)
+		if err != nil {
+			return nil, fmt.Errorf("output schema error: %w", err)
+		}
+		cfg.OutputSchema = schema
 	}
 	if !isEmptyStruct[TReq]() {
 		var tq TReq
-		cfg.InputSchema = adapter.BuildSchemaFromStruct(tq)
+		schema, err := adapter.BuildSchemaFromStructStrict(tq)
+		if err != nil {
+			return nil, fmt.Errorf("input schema error: %w", err)
+		}
+		cfg.InputSchema = schema
 	}
 	if enableTracer {
 		before, after := EnableTracer()
 		cfg.BeforeModelCallbacks = append(cfg.BeforeModelCallbacks, before)
 		cfg.AfterModelCallbacks = append(cfg.AfterModelCallbacks, after)
 	}
+	cfg.BeforeModelCallbacks = withGenerationOverrides(cfg.BeforeModelCallbacks)
 	ctx := context.Background()
-	m, err := gemini.NewModel(ctx, modelName, &genai.ClientConfig{APIKey: apiKey})
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey})
 	if err != nil {
 		return nil, fmt.Errorf("model error: %w", err)
 	}
-	cfg.Model = m
+	cfg.Model = newGeminiModel(modelName, client)
 	baseAgent, err := llmagent.New(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("agent build error: %w", err)
@@ -86,6 +97,14 @@ func NewStructuredAgent[TReq any, TRes any](
 		outputKey: outputKey,
 	}, nil
 }
+
+// Agent returns the underlying adk agent so it can be composed into workflows
+func (a *GenAIStructuredAgent[TReq, TRes]) Agent() agent.Agent {
+	if a.base == nil {
+		return nil
+	}
+	return a.base.Agent()
+}
 func (a *GenAIStructuredAgent[TReq, TRes]) NewInMemorySession(
 	ctx context.Context,
 	userID string,
@@ -126,3 +145,24 @@ func (a *GenAIStructuredAgent[TReq, TRes]) NewRedisSession(
 		outputKey: a.outputKey,
 	}, nil
 }
+
+// NewStructuredChatSession is NewChatSession for structured agents, the chat generation
+// config may not carry a response schema
+func NewStructuredChatSession[TReq any, TRes any](
+	ctx context.Context,
+	a GenAIStructuredAgentInterface[TReq, TRes],
+	chat *genaiconfig.ChatConfig,
+	rdb *redis.Client,
+) (GenAIStructuredSessionInterface[TReq, TRes], error) {
+	if chat == nil {
+		return nil, fmt.Errorf("chat config is nil")
+	}
+	sess, err := a.NewRedisSession(ctx, chat.UserID, chat.ID, rdb)
+	if err != nil {
+		return nil, err
+	}
+	if err := sess.SetGenerationConfig(chat.GenerationConfig); err != nil {
+		return nil, fmt.Errorf("chat %s: %w", chat.ID, err)
+	}
+	return sess, nil
+}
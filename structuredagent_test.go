@@ -0,0 +1,22 @@
+package genaiclient
+
+import (
+	"testing"
+)
+
+func TestStructuredAgentsAcceptMapFields(t *testing.T) {
+	type lookup struct {
+		Filters map[string]string `json:"filters"`
+	}
+	type report struct {
+		Summary string             `json:"summary"`
+		Scores  map[string]float64 `json:"scores"`
+	}
+	if _, err := NewStructuredAgent[lookup, report]("test_app", "test-key", "gemini-2.5-flash", "reporter", "", "", false); err != nil {
+		t.Errorf("NewStructuredAgent() error = %v, map fields should keep working", err)
+	}
+	loader := NewAgentLoader("test_app", "test-key", false)
+	if err := RegisterOutputType[report](loader, "report"); err != nil {
+		t.Errorf("RegisterOutputType() error = %v, map fields should keep working", err)
+	}
+}
@@ -3,64 +3,151 @@ package genaiclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
 
+	"github.com/darwishdev/genaiclient/pkg/genaiconfig"
+
 	"google.golang.org/adk/session"
+	"google.golang.org/genai"
 )
 
 type GenAIStructuredSessionInterface[TReq any, TRes any] interface {
 	Send(ctx context.Context, req TReq) (TRes, error)
 	Handle(seq iter.Seq2[*session.Event, error]) (TRes, error)
+	SendGrounded(ctx context.Context, req TReq) (*GroundedResponse[TRes], error)
+	HandleGrounded(seq iter.Seq2[*session.Event, error]) (*GroundedResponse[TRes], error)
+	SetGenerationConfig(cfg *genaiconfig.GenerationConfig) error
+	// OnThought registers a handler receiving thought summaries while they stream
+	OnThought(handler func(thought string))
+}
+
+// GroundedResponse is the structured response with the citations of every
+// retrieval made while producing it
+type GroundedResponse[TRes any] struct {
+	Result    TRes
+	Citations []Citation
+	// Thoughts is the thought summary, set when IncludeThoughts is on
+	Thoughts string
 }
 type GenAIStructuredSession[TReq any, TRes any] struct {
 	base      GenAISessionInterface
 	outputKey string
+	onThought func(thought string)
+}
+
+func (s *GenAIStructuredSession[TReq, TRes]) OnThought(handler func(thought string)) {
+	s.onThought = handler
+}
+
+// SetGenerationConfig rejects response schemas, they come from the output type
+func (s *GenAIStructuredSession[TReq, TRes]) SetGenerationConfig(cfg *genaiconfig.GenerationConfig) error {
+	if cfg != nil && cfg.ResponseSchemaConfig != nil {
+		return fmt.Errorf("%w: structured agents take their response schema from the output type", ErrInvalidGenerationConfig)
+	}
+	return s.base.SetGenerationConfig(cfg)
 }
 
 func (s *GenAIStructuredSession[TReq, TRes]) Send(
 	ctx context.Context,
 	req TReq, // user passes structured request or string
 ) (TRes, error) {
+	seq, err := s.send(ctx, req)
+	if err != nil {
+		var zero TRes
+		return zero, err
+	}
+	return s.Handle(seq)
+}
+
+// SendGrounded sends the request and returns the response with its citations
+func (s *GenAIStructuredSession[TReq, TRes]) SendGrounded(
+	ctx context.Context,
+	req TReq,
+) (*GroundedResponse[TRes], error) {
+	seq, err := s.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.HandleGrounded(seq)
+}
+
+func (s *GenAIStructuredSession[TReq, TRes]) send(
+	ctx context.Context,
+	req TReq,
+) (iter.Seq2[*session.Event, error], error) {
 	var prompt string
 	if str, ok := any(req).(string); ok {
 		prompt = str
 	} else {
 		b, err := json.Marshal(req)
 		if err != nil {
-			var zero TRes
-			return zero, err
+			return nil, err
 		}
 		prompt = string(b)
 	}
-	seq := s.base.Send(ctx, prompt)
-	return s.Handle(seq)
+	return s.base.Send(ctx, prompt), nil
 }
 
 func (s *GenAIStructuredSession[TReq, TRes]) Handle(
 	seq iter.Seq2[*session.Event, error],
 ) (TRes, error) {
+	res, err := s.HandleGrounded(seq)
+	if err != nil {
+		var zero TRes
+		return zero, err
+	}
+	return res.Result, nil
+}
+
+func (s *GenAIStructuredSession[TReq, TRes]) HandleGrounded(
+	seq iter.Seq2[*session.Event, error],
+) (*GroundedResponse[TRes], error) {
 	var out TRes
 	var accumulated string
-	for event, err := range seq {
+	var final string
+	var thoughts string
+	var citations []Citation
+	seen := make(map[string]bool)
+	for chunk, err := range SplitThoughts(seq) {
+		if errors.Is(err, ErrBlocked) {
+			return nil, err
+		}
 		if err != nil {
-			return out, fmt.Errorf("agent stream error: %w", err)
+			return nil, fmt.Errorf("agent stream error: %w", err)
+		}
+		event := chunk.Event
+		for _, c := range citationsFromEvent(event) {
+			if seen[c.ChunkID] {
+				continue
+			}
+			seen[c.ChunkID] = true
+			citations = append(citations, c)
+		}
+		if chunk.Thought != "" {
+			thoughts += chunk.Thought
+			if s.onThought != nil {
+				s.onThought(chunk.Thought)
+			}
 		}
 		if event.Partial {
-			for _, p := range event.Content.Parts {
-				if p.Text != "" {
-					accumulated += p.Text
-					// Optionally print streaming text:
-					fmt.Print(p.Text)
-				}
+			accumulated += chunk.Text
+		} else if event.Content != nil && event.Content.Role == genai.RoleModel {
+			// complete responses that were not streamed (e.g. served from a cache)
+			if text := contentText(event.Content); text != "" {
+				final = text
 			}
 		}
 	}
 	if accumulated == "" {
-		return out, fmt.Errorf("no response received")
+		accumulated = final
+	}
+	if accumulated == "" {
+		return nil, fmt.Errorf("no response received")
 	}
 	if err := json.Unmarshal([]byte(accumulated), &out); err != nil {
-		return out, fmt.Errorf("failed to parse structured response: %w", err)
+		return nil, fmt.Errorf("failed to parse structured response: %w", err)
 	}
-	return out, nil
+	return &GroundedResponse[TRes]{Result: out, Citations: citations, Thoughts: thoughts}, nil
 }
@@ -0,0 +1,274 @@
+package genaiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/workflowagents/loopagent"
+	"google.golang.org/adk/agent/workflowagents/parallelagent"
+	"google.golang.org/adk/agent/workflowagents/sequentialagent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+var (
+	ErrWorkflowNoSubAgents = errors.New("workflow agent requires at least one sub agent")
+	ErrWorkflowMergeFailed = errors.New("failed to merge parallel agent results")
+	ErrWorkflowNilSubAgent = errors.New("workflow sub agent is nil")
+)
+
+// GenAIAgentNode is implemented by every agent wrapper that can be used as a
+// child of a workflow agent (GenAIAgentInterface and the structured agents)
+type GenAIAgentNode interface {
+	Agent() agent.Agent
+}
+
+// ParallelMergeFunc receives the final text of every parallel sub agent keyed
+// by the sub agent name and returns the value stored in the session state
+type ParallelMergeFunc func(results map[string]string) (any, error)
+
+// LoopCondition is evaluated after every loop iteration, returning true stops the loop
+type LoopCondition func(state session.State) bool
+
+// MergeResultsAsJSON is the default ParallelMergeFunc, it stores the results as a
+// json object string so it survives every session backend (including redis hashes)
+func MergeResultsAsJSON(results map[string]string) (any, error) {
+	b, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func workflowSubAgents(nodes []GenAIAgentNode) ([]agent.Agent, error) {
+	if len(nodes) == 0 {
+		return nil, ErrWorkflowNoSubAgents
+	}
+	subAgents := make([]agent.Agent, len(nodes))
+	for index, node := range nodes {
+		// a typed nil wrapper is not == nil and panics on Agent()
+		if value := reflect.ValueOf(node); node == nil || value.Kind() == reflect.Pointer && value.IsNil() || node.Agent() == nil {
+			return nil, fmt.Errorf("%w at index %d", ErrWorkflowNilSubAgent, index)
+		}
+		subAgents[index] = node.Agent()
+	}
+	return subAgents, nil
+}
+
+// NewSequentialAgent runs the sub agents one after another in the same invocation
+func NewSequentialAgent(
+	appName string,
+	agentName string,
+	agentDescription string,
+	subAgents ...GenAIAgentNode,
+) (GenAIAgentInterface, error) {
+	children, err := workflowSubAgents(subAgents)
+	if err != nil {
+		return nil, err
+	}
+	workflow, err := sequentialagent.New(sequentialagent.Config{
+		AgentConfig: agent.Config{
+			Name:        agentName,
+			Description: agentDescription,
+			SubAgents:   children,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create sequential agent: %w", err)
+	}
+	return &GenAIAgent{
+		appName: appName,
+		agent:   workflow,
+	}, nil
+}
+
+// NewParallelAgent fans the prompt out to all sub agents concurrently.
+// When mergeKey is set the final answers of the sub agents are merged with merge
+// (MergeResultsAsJSON when nil) and written to the session state under mergeKey.
+func NewParallelAgent(
+	appName string,
+	agentName string,
+	agentDescription string,
+	mergeKey string,
+	merge ParallelMergeFunc,
+	subAgents ...GenAIAgentNode,
+) (GenAIAgentInterface, error) {
+	children, err := workflowSubAgents(subAgents)
+	if err != nil {
+		return nil, err
+	}
+	if mergeKey == "" {
+		workflow, err := parallelagent.New(parallelagent.Config{
+			AgentConfig: agent.Config{
+				Name:        agentName,
+				Description: agentDescription,
+				SubAgents:   children,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create parallel agent: %w", err)
+		}
+		return &GenAIAgent{
+			appName: appName,
+			agent:   workflow,
+		}, nil
+	}
+
+	if merge == nil {
+		merge = MergeResultsAsJSON
+	}
+	fanoutName := agentName + "_fanout"
+	fanout, err := parallelagent.New(parallelagent.Config{
+		AgentConfig: agent.Config{
+			Name:        fanoutName,
+			Description: agentDescription,
+			SubAgents:   children,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create parallel agent: %w", err)
+	}
+	merger, err := agent.New(agent.Config{
+		Name:        agentName + "_merger",
+		Description: "Merges the parallel results into the session state",
+		Run:         mergeParallelResults(fanoutName, mergeKey, merge),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create merger agent: %w", err)
+	}
+	workflow, err := sequentialagent.New(sequentialagent.Config{
+		AgentConfig: agent.Config{
+			Name:        agentName,
+			Description: agentDescription,
+			SubAgents:   []agent.Agent{fanout, merger},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create parallel agent: %w", err)
+	}
+	return &GenAIAgent{
+		appName: appName,
+		agent:   workflow,
+	}, nil
+}
+
+// mergeParallelResults collects the last final text each fan-out branch produced since
+// the latest user message and emits a single state delta event. llm agents give their
+// events their own invocation id so the user message marks the current turn instead
+func mergeParallelResults(fanoutName string, mergeKey string, merge ParallelMergeFunc) func(agent.InvocationContext) iter.Seq2[*session.Event, error] {
+	return func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+		return func(yield func(*session.Event, error) bool) {
+			results := make(map[string]string)
+			for ev := range ctx.Session().Events().All() {
+				if ev == nil || ev.Partial {
+					continue
+				}
+				if ev.Author == string(genai.RoleUser) {
+					results = make(map[string]string)
+					continue
+				}
+				if !strings.Contains(ev.Branch, fanoutName+".") || ev.Content == nil {
+					continue
+				}
+				text := contentText(ev.Content)
+				if text == "" {
+					continue
+				}
+				results[ev.Author] = text
+			}
+			ev := session.NewEvent(ctx.InvocationID())
+			ev.Author = ctx.Agent().Name()
+			ev.Branch = ctx.Branch()
+			merged, err := merge(results)
+			if err != nil {
+				// adk workflow agents read the event next to an error, so never yield a nil one
+				err = fmt.Errorf("%w: %w", ErrWorkflowMergeFailed, err)
+				ev.ErrorMessage = err.Error()
+				yield(ev, err)
+				return
+			}
+			ev.Actions.StateDelta[mergeKey] = merged
+			yield(ev, nil)
+		}
+	}
+}
+
+// NewLoopAgent runs the sub agents in sequence repeatedly until until returns true,
+// a sub agent escalates or maxIterations is reached (0 means no limit)
+func NewLoopAgent(
+	appName string,
+	agentName string,
+	agentDescription string,
+	maxIterations uint,
+	until LoopCondition,
+	subAgents ...GenAIAgentNode,
+) (GenAIAgentInterface, error) {
+	children, err := workflowSubAgents(subAgents)
+	if err != nil {
+		return nil, err
+	}
+	if until != nil {
+		checker, err := agent.New(agent.Config{
+			Name:        agentName + "_condition",
+			Description: "Stops the loop once the exit condition is met",
+			Run:         checkLoopCondition(until),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create loop condition agent: %w", err)
+		}
+		children = append(children, checker)
+	}
+	workflow, err := loopagent.New(loopagent.Config{
+		AgentConfig: agent.Config{
+			Name:        agentName,
+			Description: agentDescription,
+			SubAgents:   children,
+		},
+		MaxIterations: maxIterations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create loop agent: %w", err)
+	}
+	return &GenAIAgent{
+		appName: appName,
+		agent:   workflow,
+	}, nil
+}
+
+func checkLoopCondition(until LoopCondition) func(agent.InvocationContext) iter.Seq2[*session.Event, error] {
+	return func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+		return func(yield func(*session.Event, error) bool) {
+			if !until(ctx.Session().State()) {
+				return
+			}
+			ev := session.NewEvent(ctx.InvocationID())
+			ev.Author = ctx.Agent().Name()
+			ev.Branch = ctx.Branch()
+			ev.Actions.Escalate = true
+			yield(ev, nil)
+		}
+	}
+}
+
+// contentText joins the text parts of a content skipping thoughts
+func contentText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, p := range content.Parts {
+		if p == nil || p.Thought || p.Text == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(p.Text)
+	}
+	return sb.String()
+}
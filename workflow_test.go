@@ -0,0 +1,150 @@
+package genaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestNewSequentialAgent(t *testing.T) {
+	writer := newFakeAgent(t, "writer", textLLM("draft"))
+	reviewer := newFakeAgent(t, "reviewer", textLLM("approved"))
+	pipeline, err := NewSequentialAgent("test_app", "pipeline", "Draft then review", writer, reviewer)
+	if err != nil {
+		t.Fatalf("NewSequentialAgent() error = %v", err)
+	}
+	events := collectEvents(t, pipeline.NewInMemorySession(context.Background(), "user").Send(context.Background(), "write"))
+	var authors []string
+	for _, ev := range events {
+		if text := contentText(ev.Content); text != "" {
+			authors = append(authors, ev.Author+":"+text)
+		}
+	}
+	if len(authors) != 2 || authors[0] != "writer:draft" || authors[1] != "reviewer:approved" {
+		t.Errorf("events = %v, want writer then reviewer", authors)
+	}
+}
+
+func TestNewParallelAgentMergesResults(t *testing.T) {
+	style := newFakeAgent(t, "style", textLLM("looks good"))
+	security := newFakeAgent(t, "security", textLLM("no issues"))
+	panel, err := NewParallelAgent("test_app", "panel", "Parallel reviewers", "reviews", nil, style, security)
+	if err != nil {
+		t.Fatalf("NewParallelAgent() error = %v", err)
+	}
+	events := collectEvents(t, panel.NewInMemorySession(context.Background(), "user").Send(context.Background(), "review"))
+	var merged any
+	for _, ev := range events {
+		if v, ok := ev.Actions.StateDelta["reviews"]; ok {
+			merged = v
+		}
+	}
+	raw, ok := merged.(string)
+	if !ok {
+		t.Fatalf("reviews state = %#v, want a json string", merged)
+	}
+	var results map[string]string
+	if err := json.Unmarshal([]byte(raw), &results); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if results["style"] != "looks good" || results["security"] != "no issues" || len(results) != 2 {
+		t.Errorf("merged results = %v", results)
+	}
+}
+
+func TestNewParallelAgentMergeError(t *testing.T) {
+	a := newFakeAgent(t, "a", textLLM("x"))
+	failing := func(map[string]string) (any, error) { return nil, errors.New("boom") }
+	panel, err := NewParallelAgent("test_app", "panel", "", "out", failing, a)
+	if err != nil {
+		t.Fatalf("NewParallelAgent() error = %v", err)
+	}
+	var gotErr error
+	for _, err := range panel.NewInMemorySession(context.Background(), "user").Send(context.Background(), "go") {
+		if err != nil {
+			gotErr = err
+		}
+	}
+	if !errors.Is(gotErr, ErrWorkflowMergeFailed) {
+		t.Errorf("error = %v, want ErrWorkflowMergeFailed", gotErr)
+	}
+}
+
+func TestNewLoopAgentStopsOnCondition(t *testing.T) {
+	calls := 0
+	llm := &fakeLLM{respond: func(*model.LLMRequest) (*model.LLMResponse, error) {
+		calls++
+		if calls == 3 {
+			return textResponse("done"), nil
+		}
+		return textResponse("again"), nil
+	}}
+	worker := newFakeAgent(t, "worker", llm, llmagent.Config{OutputKey: "status"})
+	loop, err := NewLoopAgent("test_app", "refine", "", 10, func(state session.State) bool {
+		v, err := state.Get("status")
+		return err == nil && v == "done"
+	}, worker)
+	if err != nil {
+		t.Fatalf("NewLoopAgent() error = %v", err)
+	}
+	collectEvents(t, loop.NewInMemorySession(context.Background(), "user").Send(context.Background(), "go"))
+	if llm.calls() != 3 {
+		t.Errorf("model calls = %d, want 3", llm.calls())
+	}
+}
+
+func TestWorkflowRequiresSubAgents(t *testing.T) {
+	if _, err := NewSequentialAgent("test_app", "empty", ""); !errors.Is(err, ErrWorkflowNoSubAgents) {
+		t.Errorf("NewSequentialAgent() error = %v, want ErrWorkflowNoSubAgents", err)
+	}
+	if _, err := NewLoopAgent("test_app", "empty", "", 1, nil); !errors.Is(err, ErrWorkflowNoSubAgents) {
+		t.Errorf("NewLoopAgent() error = %v, want ErrWorkflowNoSubAgents", err)
+	}
+}
+
+func TestWorkflowRejectsNilSubAgents(t *testing.T) {
+	valid := newFakeAgent(t, "valid", textLLM("ok"))
+	var nilAgent *GenAIAgent
+	var nilStructured *GenAIStructuredAgent[string, groundedAnswer]
+	tests := []struct {
+		name  string
+		nodes []GenAIAgentNode
+	}{
+		{name: "nil interface", nodes: []GenAIAgentNode{valid, nil}},
+		{name: "typed nil agent", nodes: []GenAIAgentNode{valid, nilAgent}},
+		{name: "typed nil structured agent", nodes: []GenAIAgentNode{nilStructured}},
+		{name: "agent without adk agent", nodes: []GenAIAgentNode{&GenAIAgent{}}},
+		{name: "structured agent without base", nodes: []GenAIAgentNode{&GenAIStructuredAgent[string, groundedAnswer]{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSequentialAgent("test_app", "seq", "", tt.nodes...); !errors.Is(err, ErrWorkflowNilSubAgent) {
+				t.Errorf("NewSequentialAgent() error = %v, want ErrWorkflowNilSubAgent", err)
+			}
+			if _, err := NewParallelAgent("test_app", "par", "", "", nil, tt.nodes...); !errors.Is(err, ErrWorkflowNilSubAgent) {
+				t.Errorf("NewParallelAgent() error = %v, want ErrWorkflowNilSubAgent", err)
+			}
+		})
+	}
+}
+
+func TestContentText(t *testing.T) {
+	content := &genai.Content{Parts: []*genai.Part{
+		{Text: "thinking", Thought: true},
+		{Text: "first"},
+		nil,
+		{Text: "second"},
+	}}
+	if got := contentText(content); got != "first\nsecond" {
+		t.Errorf("contentText() = %q", got)
+	}
+	if got := contentText(nil); got != "" {
+		t.Errorf("contentText(nil) = %q", got)
+	}
+}